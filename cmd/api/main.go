@@ -1,52 +1,160 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/olebedev/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/rromero96/stori/cmd/api/server"
 	"github.com/rromero96/stori/cmd/api/system"
+	"github.com/rromero96/stori/conf"
+	storisql "github.com/rromero96/stori/sql"
 )
 
 const (
-	systemGetHtml string = "/system/html/v1"
+	systemGetHtml        string = "/system/html/v1"
+	systemGetInfo        string = "/system/info"
+	systemGetBalances    string = "/system/accounts/:id/balances/v1"
+	systemGetRecurring   string = "/system/accounts/:id/recurring/v1"
+	systemGetAnomalies   string = "/system/accounts/:id/anomalies/v1"
+	systemGetForecast    string = "/system/accounts/:id/forecast/v1"
+	systemBudgets        string = "/system/accounts/:id/budgets/v1"
+	systemBudget         string = "/system/accounts/:id/budgets/v1/:budget_id"
+	systemBudgetReport   string = "/system/accounts/:id/budgets/report/v1"
+	systemGetStatements  string = "/system/accounts/:id/statements/v1"
+	systemGetStatement   string = "/system/accounts/:id/statements/v1/:statement_id"
+	healthGetLive        string = "/health/live"
+	healthGetReady       string = "/health/ready"
+	metricsGet           string = "/metrics"
+	defaultHealthTimeout string = "2s"
+	defaultAlertTimeout  string = "5s"
+	defaultLogLevel      string = "info"
+	defaultLogFormat     string = "json"
+	defaultTracing       string = "none"
+
+	envConfigFile   string = "STORI_CONFIG"
+	envDataDir      string = "STORI_DATA_DIR"
+	envWatchDir     string = "STORI_WATCH_DIR"
+	envTemplatesDir string = "STORI_TEMPLATES_DIR"
 
 	connectionStringFormat string = "%s:%s@tcp(%s)/%s?charset=utf8&parseTime=true"
 	mysqlDriver            string = "mysql"
 	storiDB                string = "stori"
 )
 
+// options are the command line flags
+type options struct {
+	configFile   string
+	dataDir      string
+	watchDir     string
+	templatesDir string
+	schema       bool
+}
+
+// Build information, injected at link time with
+// -ldflags "-X main.version=<version> -X main.commit=<commit> -X main.buildTime=<time>"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
 func main() {
 	if err := run(); err != nil {
-		log.Fatal(err)
+		slog.Error("server failed", slog.Any("error", err))
+		os.Exit(1)
 	}
 }
 
 func run() error {
 	/*
-		Server Configuration
+		Flags
 	*/
-	app := gin.Default()
-
-	port := "8080"
-	address := ":" + port
+	opts, err := parseFlags(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if opts.schema {
+		return storisql.WriteSchema(os.Stdout)
+	}
 
 	/*
 	   YML Configuration
 	*/
-	file, err := ioutil.ReadFile(system.GetFileName("../conf", "production.yml"))
+	yamlString := string(conf.Production)
+	if opts.configFile != "" {
+		file, err := os.ReadFile(opts.configFile)
+		if err != nil {
+			return err
+		}
+		yamlString = string(file)
+	}
+
+	cfg, err := config.ParseYaml(yamlString)
+	if err != nil {
+		return err
+	}
+
+	serverConfig, err := getServerConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	/*
+		Logging
+	*/
+	logger, err := system.NewLogger(os.Stdout, cfg.UString("log.level", defaultLogLevel), cfg.UString("log.format", defaultLogFormat))
 	if err != nil {
-		panic(err)
+		return err
+	}
+	slog.SetDefault(logger)
+
+	/*
+		Tracing
+	*/
+	tracerProvider, err := system.NewTracerProvider(context.Background(), system.TracingConfig{
+		Exporter:    cfg.UString("tracing.exporter", defaultTracing),
+		Endpoint:    cfg.UString("tracing.endpoint"),
+		Insecure:    cfg.UBool("tracing.insecure"),
+		SampleRatio: cfg.UFloat64("tracing.sample_ratio", 1),
+		Version:     version,
+	})
+	if err != nil {
+		return err
 	}
-	yamlString := string(file)
+	defer func() {
+		if err := tracerProvider.Shutdown(context.Background()); err != nil {
+			logger.Error("can't shut down tracer provider", slog.Any("error", err))
+		}
+	}()
 
-	cfg, _ := config.ParseYaml(yamlString)
+	/*
+		Server Configuration
+	*/
+	app := gin.New()
+	app.ContextWithFallback = true
+	app.Use(gin.Recovery(), system.TracingMiddleware(), system.RequestIDMiddleware(logger), system.RequestLoggerMiddleware())
 
 	/*
 	   MYSQL client
@@ -55,25 +163,179 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	defer storiDBClient.Close()
+
+	/*
+		Metrics
+	*/
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	metrics := system.NewMetrics(registry)
+	system.RegisterDBStats(registry, storiDBClient, storiDB)
+	app.Use(system.HTTPMetricsMiddleware(metrics))
 
 	/*
 		Injections
 	*/
-	mysqlIDFinder := system.MakeMySQLFind(storiDBClient)
-	mysqlCreateTransactions := system.MakeMySQLCreate(storiDBClient, mysqlIDFinder)
-	readCSV := system.MakeReadCSV()
-	htmlProcessTransactions := system.MakeHTMLProcessTransactions(readCSV, mysqlCreateTransactions)
+	mysqlCreateTransactions := system.MakeMySQLCreate(storiDBClient, metrics)
+	timezones, err := getTimezones(cfg)
+	if err != nil {
+		return err
+	}
+	importProfiles, err := getImportProfiles(cfg)
+	if err != nil {
+		return err
+	}
+	archiveConfig, err := getArchiveConfig(cfg)
+	if err != nil {
+		return err
+	}
+	sourceEncoding, err := system.ParseEncoding(cfg.UString("imports.encoding", system.EncodingAuto))
+	if err != nil {
+		return fmt.Errorf("invalid imports.encoding: %w", err)
+	}
+	categorizer, err := getCategorizer(cfg)
+	if err != nil {
+		return err
+	}
+	grouping, err := system.ParseGrouping(cfg.UString("summary.grouping", system.DefaultGrouping))
+	if err != nil {
+		return fmt.Errorf("invalid summary.grouping: %w", err)
+	}
+	streamCSV := system.MakeStreamCSV(metrics, importProfiles)
+	readOFX := system.MakeReadOFX()
+	readCamt053 := system.MakeReadCamt053()
+	readStatement := system.MakeReadStatement(streamCSV, readOFX, readCamt053, archiveConfig, timezones, sourceEncoding)
+	openingBalances := getOpeningBalances(cfg)
+	getBalances := system.MakeGetBalances(system.MakeMySQLFindBalances(storiDBClient, openingBalances))
+	mysqlFindTransactions := system.MakeMySQLFindTransactions(storiDBClient)
+	recurringConfig := getRecurringConfig(cfg)
+	getRecurring := system.MakeGetRecurring(mysqlFindTransactions, recurringConfig)
+	getAnomalies := system.MakeGetAnomalies(mysqlFindTransactions, getAnomalyConfig(cfg))
+	getForecast := system.MakeGetForecast(mysqlFindTransactions, getBalances, getForecastConfig(cfg, recurringConfig))
+	currencies, err := getCurrencies(cfg)
+	if err != nil {
+		return err
+	}
+	getExchangeRates := system.MakeMySQLGetExchangeRates(storiDBClient)
+	if ratesFile := cfg.UString("currencies.rates_file"); ratesFile != "" {
+		getExchangeRates = system.MakeReadExchangeRates(ratesFile)
+	}
+	mysqlSnapshotBalances := system.MakeMySQLSnapshotBalances(storiDBClient, openingBalances, currencies)
+	importStatement := system.MakeImportStatement(readStatement, mysqlCreateTransactions, mysqlSnapshotBalances, categorizer, currencies, getExchangeRates, grouping, cfg.UInt("imports.batch_size", system.DefaultBatchSize))
+	mysqlFindBudget := system.MakeMySQLFindBudget(storiDBClient)
+	mysqlListBudgets := system.MakeMySQLListBudgets(storiDBClient)
+	getBudgetReport := system.MakeGetBudgetReport(mysqlListBudgets, mysqlFindTransactions, getBudgetAlerts(cfg))
+	notifyBudgetAlert, err := getNotifyBudgetAlert(cfg)
+	if err != nil {
+		return err
+	}
+	alertBudgets := system.MakeAlertBudgets(system.MakeMySQLBudgetAlertSent(storiDBClient), system.MakeMySQLSaveBudgetAlert(storiDBClient), notifyBudgetAlert)
+	localizer, err := getLocalizer(cfg, categorizer.Categories())
+	if err != nil {
+		return err
+	}
+	templates, err := getTemplateRegistry(cfg, opts.templatesDir, localizer.Catalogs)
+	if err != nil {
+		return err
+	}
+	dataFiles := system.SampleData()
+	if opts.dataDir != "" {
+		dataFiles = os.DirFS(opts.dataDir)
+	}
+	cycle, err := system.NewStatementCycle(cfg.UInt("statements.cycle_day", system.DefaultCycleDay))
+	if err != nil {
+		return fmt.Errorf("invalid statements.cycle_day: %w", err)
+	}
+	getStatementSummary := system.MakeGetStatementSummary(mysqlFindTransactions, getExchangeRates, currencies, grouping)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, getStatementSummary, getBalances, getRecurring, getAnomalies, getForecast, getBudgetReport, alertBudgets, system.MakeMySQLIssueStatement(storiDBClient), metrics, timezones, cycle, localizer, templates, dataFiles)
+
+	healthTimeout, err := time.ParseDuration(cfg.UString("health.timeout", defaultHealthTimeout))
+	if err != nil {
+		return fmt.Errorf("invalid health.timeout: %w", err)
+	}
+	checks := map[string]system.HealthCheck{
+		"database": system.MakeMySQLPing(storiDBClient),
+	}
+	if opts.dataDir != "" {
+		checks["data"] = system.MakeFileCheck(filepath.Join(opts.dataDir, system.DataFile))
+	}
+	readiness := system.NewReadiness(healthTimeout, checks)
+	buildInfo := system.BuildInfo{Version: version, Commit: commit, BuildTime: buildTime}
 
 	/*
 		Endpoints
 	*/
 	app.GET(systemGetHtml, system.GetHTMLInfoV1(htmlProcessTransactions))
+	app.GET(systemGetInfo, system.GetSystemInfoV1(buildInfo))
+	app.GET(systemGetBalances, system.GetAccountBalancesV1(getBalances))
+	app.GET(systemGetRecurring, system.GetAccountRecurringV1(getRecurring))
+	app.GET(systemGetAnomalies, system.GetAccountAnomaliesV1(getAnomalies))
+	app.GET(systemGetForecast, system.GetAccountForecastV1(getForecast))
+	app.GET(systemBudgets, system.GetAccountBudgetsV1(mysqlListBudgets))
+	app.POST(systemBudgets, system.CreateAccountBudgetV1(system.MakeMySQLCreateBudget(storiDBClient)))
+	app.GET(systemBudget, system.GetAccountBudgetV1(mysqlFindBudget))
+	app.PUT(systemBudget, system.UpdateAccountBudgetV1(mysqlFindBudget, system.MakeMySQLUpdateBudget(storiDBClient)))
+	app.DELETE(systemBudget, system.DeleteAccountBudgetV1(system.MakeMySQLDeleteBudget(storiDBClient)))
+	app.GET(systemBudgetReport, system.GetAccountBudgetReportV1(getBudgetReport))
+	app.GET(systemGetStatements, system.GetAccountStatementsV1(system.MakeMySQLListStatements(storiDBClient)))
+	app.GET(systemGetStatement, system.GetAccountStatementV1(system.MakeMySQLFindStatement(storiDBClient)))
+	app.GET(healthGetLive, system.GetHealthLiveV1())
+	app.GET(healthGetReady, system.GetHealthReadyV1(readiness))
+	app.GET(metricsGet, gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+
+	/*
+		Graceful shutdown
+	*/
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	/*
+		Drop folder
+	*/
+	watcherDone := make(chan struct{})
+	if cfg.UBool("watcher.enabled", false) || opts.watchDir != "" {
+		watcherConfig, err := getWatcherConfig(cfg, opts.watchDir)
+		if err != nil {
+			return err
+		}
+		ingestFile := system.MakeIngestFile(importStatement, system.MakeMySQLFindImport(storiDBClient), system.MakeMySQLSaveImport(storiDBClient), metrics, timezones)
+		watchDirectory := system.MakeWatchDirectory(watcherConfig, ingestFile)
+		go func() {
+			defer close(watcherDone)
+			if err := watchDirectory(system.ContextWithLogger(ctx, logger)); err != nil {
+				logger.Error("drop folder watcher stopped", slog.Any("error", err))
+			}
+		}()
+	} else {
+		close(watcherDone)
+	}
+
+	srv := server.New(app, serverConfig)
+
+	logger.Info("server up and running", slog.Int("port", serverConfig.Port))
+	if err := server.Run(ctx, srv, serverConfig, readiness.SetShuttingDown); err != nil {
+		return err
+	}
+	<-watcherDone
+	logger.Info("server stopped")
 
-	log.Printf("server up and running in port %s", port)
-	app.Run(address)
 	return nil
 }
 
+// parseFlags reads the command line. The paths can also be set with environment variables, which the flags override
+func parseFlags(args []string) (options, error) {
+	var opts options
+	flags := flag.NewFlagSet("stori", flag.ContinueOnError)
+	flags.StringVar(&opts.configFile, "config", os.Getenv(envConfigFile), "yml configuration file, the built-in production configuration when empty (env "+envConfigFile+")")
+	flags.StringVar(&opts.dataDir, "data-dir", os.Getenv(envDataDir), "folder with the "+system.DataFile+" statement of the email, the built-in sample when empty (env "+envDataDir+")")
+	flags.StringVar(&opts.watchDir, "watch-dir", os.Getenv(envWatchDir), "drop folder, enables the watcher and overrides watcher.dir (env "+envWatchDir+")")
+	flags.StringVar(&opts.templatesDir, "templates-dir", os.Getenv(envTemplatesDir), "folder the templates are reloaded from with templates.reload, overrides templates.dir (env "+envTemplatesDir+")")
+	flags.BoolVar(&opts.schema, "schema", false, "print the database schema and its migrations and exit")
+
+	return opts, flags.Parse(args)
+}
+
 func createDBClient(connectionString string) (*sql.DB, error) {
 	db, err := sql.Open(mysqlDriver, connectionString)
 	if err != nil {
@@ -94,3 +356,322 @@ func getDBConnectionStringRoutes(database string, yml *config.Config) string {
 	dbName, _ := yml.String(fmt.Sprintf("databases.mysql.%s.db_name", database))
 	return fmt.Sprintf(connectionStringFormat, dbUserName, dbPassword, dbHost, dbName)
 }
+
+func getServerConfig(yml *config.Config) (server.Config, error) {
+	serverConfig := server.DefaultConfig()
+	serverConfig.Port = yml.UInt("server.port", serverConfig.Port)
+	serverConfig.MaxHeaderBytes = yml.UInt("server.max_header_bytes", serverConfig.MaxHeaderBytes)
+
+	durations := map[string]*time.Duration{
+		"server.read_timeout":        &serverConfig.ReadTimeout,
+		"server.read_header_timeout": &serverConfig.ReadHeaderTimeout,
+		"server.write_timeout":       &serverConfig.WriteTimeout,
+		"server.idle_timeout":        &serverConfig.IdleTimeout,
+		"server.shutdown_timeout":    &serverConfig.ShutdownTimeout,
+		"server.drain_delay":         &serverConfig.DrainDelay,
+	}
+	for key, duration := range durations {
+		value, err := yml.String(key)
+		if err != nil {
+			continue
+		}
+
+		*duration, err = time.ParseDuration(value)
+		if err != nil {
+			return server.Config{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	return serverConfig, nil
+}
+
+func getTimezones(yml *config.Config) (system.Timezones, error) {
+	defaultLocation, err := time.LoadLocation(yml.UString("dates.timezone", "UTC"))
+	if err != nil {
+		return system.Timezones{}, fmt.Errorf("invalid dates.timezone: %w", err)
+	}
+
+	timezones := system.Timezones{Default: defaultLocation, Accounts: map[string]*time.Location{}}
+	for accountID := range yml.UMap("dates.accounts") {
+		name, err := yml.String(fmt.Sprintf("dates.accounts.%s.timezone", accountID))
+		if err != nil {
+			continue
+		}
+
+		timezones.Accounts[accountID], err = time.LoadLocation(name)
+		if err != nil {
+			return system.Timezones{}, fmt.Errorf("invalid timezone of account %s: %w", accountID, err)
+		}
+	}
+
+	return timezones, nil
+}
+
+func getCurrencies(yml *config.Config) (system.Currencies, error) {
+	base, err := system.ParseCurrency(yml.UString("currencies.base", system.DefaultCurrency))
+	if err != nil {
+		return system.Currencies{}, fmt.Errorf("invalid currencies.base: %w", err)
+	}
+
+	currencies := system.Currencies{Default: base, Accounts: map[string]string{}}
+	for accountID := range yml.UMap("currencies.accounts") {
+		currencies.Accounts[accountID], err = system.ParseCurrency(yml.UString(fmt.Sprintf("currencies.accounts.%s.currency", accountID)))
+		if err != nil {
+			return system.Currencies{}, fmt.Errorf("invalid currency of account %s: %w", accountID, err)
+		}
+	}
+
+	return currencies, nil
+}
+
+func getLocalizer(yml *config.Config, categories []string) (system.Localizer, error) {
+	catalogs, err := system.LoadCatalogs(system.EmbeddedCatalogs())
+	if err != nil {
+		return system.Localizer{}, err
+	}
+	if err := system.CheckCatalogs(catalogs, categories...); err != nil {
+		return system.Localizer{}, fmt.Errorf("invalid locales: %w", err)
+	}
+
+	localizer := system.Localizer{Catalogs: catalogs, Default: yml.UString("locales.default", system.DefaultLocale), Accounts: map[string]string{}}
+	for accountID := range yml.UMap("locales.accounts") {
+		localizer.Accounts[accountID] = yml.UString(fmt.Sprintf("locales.accounts.%s.locale", accountID))
+	}
+	if err := localizer.Validate(); err != nil {
+		return system.Localizer{}, fmt.Errorf("invalid locales: %w", err)
+	}
+
+	return localizer, nil
+}
+
+func getTemplateRegistry(yml *config.Config, dir string, catalogs map[string]system.Catalog) (*system.TemplateRegistry, error) {
+	if !yml.UBool("templates.reload", false) {
+		return system.NewTemplateRegistry(system.EmbeddedTemplates(), catalogs, false)
+	}
+
+	if dir == "" {
+		dir = yml.UString("templates.dir")
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("-templates-dir or templates.dir is required when templates.reload is enabled")
+	}
+	return system.NewTemplateRegistry(os.DirFS(dir), catalogs, true)
+}
+
+func getOpeningBalances(yml *config.Config) system.OpeningBalances {
+	openings := system.OpeningBalances{}
+	for accountID := range yml.UMap("balances.accounts") {
+		openings[accountID] = yml.UFloat64(fmt.Sprintf("balances.accounts.%s.opening", accountID))
+	}
+
+	return openings
+}
+
+func getRecurringConfig(yml *config.Config) system.RecurringConfig {
+	return system.RecurringConfig{
+		MinOccurrences:    yml.UInt("recurring.min_occurrences", system.DefaultRecurringMinOccurrences),
+		AmountTolerance:   yml.UFloat64("recurring.amount_tolerance", system.DefaultRecurringAmountTolerance),
+		MinRegularity:     yml.UFloat64("recurring.min_regularity", system.DefaultRecurringMinRegularity),
+		MinNameSimilarity: yml.UFloat64("recurring.min_name_similarity", system.DefaultRecurringMinNameSimilarity),
+	}
+}
+
+func getAnomalyConfig(yml *config.Config) system.AnomalyConfig {
+	return system.AnomalyConfig{
+		Threshold:           yml.UFloat64("anomalies.threshold", system.DefaultAnomalyThreshold),
+		CountThreshold:      yml.UFloat64("anomalies.count_threshold", system.DefaultAnomalyCountThreshold),
+		MinHistory:          yml.UInt("anomalies.min_history", system.DefaultAnomalyMinHistory),
+		NewMerchantMultiple: yml.UFloat64("anomalies.new_merchant_multiple", system.DefaultAnomalyNewMerchantMultiple),
+		LookbackDays:        yml.UInt("anomalies.lookback_days", system.DefaultAnomalyLookbackDays),
+	}
+}
+
+func getForecastConfig(yml *config.Config, recurring system.RecurringConfig) system.ForecastConfig {
+	return system.ForecastConfig{
+		Days:          yml.UInt("forecast.days", system.DefaultForecastDays),
+		LookbackDays:  yml.UInt("forecast.lookback_days", system.DefaultForecastLookbackDays),
+		SeasonalWeeks: yml.UInt("forecast.seasonal_weeks", system.DefaultForecastSeasonalWeeks),
+		Recurring:     recurring,
+	}
+}
+
+func getBudgetAlerts(yml *config.Config) []float64 {
+	var alerts []float64
+	for i := range yml.UList("budgets.alerts") {
+		alerts = append(alerts, yml.UFloat64(fmt.Sprintf("budgets.alerts.%d", i)))
+	}
+	if len(alerts) == 0 {
+		return system.DefaultBudgetAlerts
+	}
+
+	return alerts
+}
+
+func getNotifyBudgetAlert(yml *config.Config) (system.NotifyBudgetAlert, error) {
+	url := yml.UString("budgets.webhook_url")
+	if url == "" {
+		return system.MakeLogNotifyBudgetAlert(), nil
+	}
+
+	timeout, err := time.ParseDuration(yml.UString("budgets.webhook_timeout", defaultAlertTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("invalid budgets.webhook_timeout: %w", err)
+	}
+
+	return system.MakeWebhookNotifyBudgetAlert(&http.Client{Timeout: timeout}, url), nil
+}
+
+func getImportProfiles(yml *config.Config) ([]system.ImportProfile, error) {
+	names := make([]string, 0)
+	for name := range yml.UMap("imports.profiles") {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return []system.ImportProfile{system.DefaultImportProfile()}, nil
+	}
+	sort.Strings(names)
+
+	profiles := make([]system.ImportProfile, 0, len(names))
+	for _, name := range names {
+		key := "imports.profiles." + name
+		profile := system.ImportProfile{
+			Name:             name,
+			Delimiter:        firstRune(yml.UString(key+".delimiter", ",")),
+			DecimalSeparator: firstRune(yml.UString(key+".decimal_separator", ".")),
+			Sign:             yml.UString(key+".sign", system.SignNegativeDebit),
+			Columns: system.ColumnMapping{
+				ID:          yml.UString(key + ".columns.id"),
+				Date:        yml.UString(key + ".columns.date"),
+				Amount:      yml.UString(key + ".columns.amount"),
+				Debit:       yml.UString(key + ".columns.debit"),
+				Credit:      yml.UString(key + ".columns.credit"),
+				Description: yml.UString(key + ".columns.description"),
+				Merchant:    yml.UString(key + ".columns.merchant"),
+				Currency:    yml.UString(key + ".columns.currency"),
+			},
+			Dates: system.DefaultDateParser(),
+		}
+
+		var formats []string
+		for _, format := range yml.UList(key + ".date_formats") {
+			formats = append(formats, fmt.Sprint(format))
+		}
+		if len(formats) > 0 {
+			var err error
+			profile.Dates, err = system.NewDateParser(formats...)
+			if err != nil {
+				return nil, fmt.Errorf("profile %s: %w", name, err)
+			}
+		}
+
+		if err := profile.Validate(); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, nil
+}
+
+func getArchiveConfig(yml *config.Config) (system.ArchiveConfig, error) {
+	archiveConfig := system.ArchiveConfig{
+		MaxUncompressedSize: int64(yml.UInt("imports.archives.max_uncompressed_size", int(system.DefaultMaxUncompressedSize))),
+		MaxRatio:            yml.UFloat64("imports.archives.max_ratio", system.DefaultMaxCompressionRatio),
+		MaxMembers:          yml.UInt("imports.archives.max_members", system.DefaultMaxArchiveMembers),
+	}
+	if archiveConfig.MaxUncompressedSize <= 0 || archiveConfig.MaxRatio <= 0 || archiveConfig.MaxMembers <= 0 {
+		return system.ArchiveConfig{}, fmt.Errorf("invalid imports.archives limits")
+	}
+
+	for i := range yml.UList("imports.archives.accounts") {
+		key := fmt.Sprintf("imports.archives.accounts.%d", i)
+		pattern, err := system.NewAccountPattern(yml.UString(key+".pattern"), yml.UString(key+".account"))
+		if err != nil {
+			return system.ArchiveConfig{}, fmt.Errorf("%s: %w", key, err)
+		}
+		archiveConfig.Accounts = append(archiveConfig.Accounts, pattern)
+	}
+
+	return archiveConfig, nil
+}
+
+func getCategorizer(yml *config.Config) (system.Categorizer, error) {
+	categorizer := system.Categorizer{Default: yml.UString("categories.default", system.DefaultCategory)}
+
+	var err error
+	categorizer.Rules, err = getCategoryRules(yml, "categories.rules")
+	if err != nil {
+		return system.Categorizer{}, err
+	}
+
+	for accountID := range yml.UMap("categories.accounts") {
+		rules, err := getCategoryRules(yml, "categories.accounts."+accountID)
+		if err != nil {
+			return system.Categorizer{}, err
+		}
+		if categorizer.Accounts == nil {
+			categorizer.Accounts = make(map[string][]system.CategoryRule)
+		}
+		categorizer.Accounts[accountID] = rules
+	}
+
+	return categorizer, nil
+}
+
+func getCategoryRules(yml *config.Config, key string) ([]system.CategoryRule, error) {
+	var rules []system.CategoryRule
+	for i := range yml.UList(key) {
+		ruleKey := fmt.Sprintf("%s.%d", key, i)
+		var keywords []string
+		for _, keyword := range yml.UList(ruleKey + ".keywords") {
+			keywords = append(keywords, fmt.Sprint(keyword))
+		}
+		rule, err := system.NewCategoryRule(yml.UString(ruleKey+".category"), yml.UString(ruleKey+".pattern"), keywords, yml.UString(ruleKey+".type"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ruleKey, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func getWatcherConfig(yml *config.Config, dir string) (system.WatcherConfig, error) {
+	if dir == "" {
+		dir = yml.UString("watcher.dir")
+	}
+	watcherConfig := system.WatcherConfig{
+		Dir:          dir,
+		PollInterval: system.DefaultPollInterval,
+		SettleTime:   system.DefaultSettleTime,
+		Polling:      yml.UBool("watcher.polling", false),
+	}
+	if watcherConfig.Dir == "" {
+		return system.WatcherConfig{}, fmt.Errorf("-watch-dir or watcher.dir is required when the watcher is enabled")
+	}
+
+	durations := map[string]*time.Duration{
+		"watcher.poll_interval": &watcherConfig.PollInterval,
+		"watcher.settle_time":   &watcherConfig.SettleTime,
+	}
+	for key, duration := range durations {
+		value, err := yml.String(key)
+		if err != nil {
+			continue
+		}
+
+		*duration, err = time.ParseDuration(value)
+		if err != nil {
+			return system.WatcherConfig{}, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	return watcherConfig, nil
+}
+
+func firstRune(value string) rune {
+	for _, r := range value {
+		return r
+	}
+	return 0
+}
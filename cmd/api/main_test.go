@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/olebedev/config"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+	"github.com/rromero96/stori/conf"
+)
+
+func TestGetImportProfiles_mapsEveryColumnOfTheDefaultProfile(t *testing.T) {
+	yml, err := config.ParseYaml(string(conf.Production))
+	assert.Nil(t, err)
+
+	profiles, err := getImportProfiles(yml)
+
+	assert.Nil(t, err)
+	for _, profile := range profiles {
+		if profile.Name == system.DefaultImportProfile().Name {
+			assert.Equal(t, system.DefaultImportProfile().Columns, profile.Columns)
+			return
+		}
+	}
+	t.Fatal("the production configuration has no default profile")
+}
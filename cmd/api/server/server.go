@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPort              int           = 8080
+	defaultReadTimeout       time.Duration = 15 * time.Second
+	defaultReadHeaderTimeout time.Duration = 5 * time.Second
+	defaultWriteTimeout      time.Duration = 30 * time.Second
+	defaultIdleTimeout       time.Duration = 60 * time.Second
+	defaultShutdownTimeout   time.Duration = 20 * time.Second
+	defaultDrainDelay        time.Duration = 5 * time.Second
+	defaultMaxHeaderBytes    int           = 1 << 20
+)
+
+type (
+	// Config holds the settings of the HTTP server
+	Config struct {
+		Port              int
+		ReadTimeout       time.Duration
+		ReadHeaderTimeout time.Duration
+		WriteTimeout      time.Duration
+		IdleTimeout       time.Duration
+		ShutdownTimeout   time.Duration
+		DrainDelay        time.Duration
+		MaxHeaderBytes    int
+	}
+)
+
+// DefaultConfig returns the configuration used when the YAML file doesn't define a value
+func DefaultConfig() Config {
+	return Config{
+		Port:              defaultPort,
+		ReadTimeout:       defaultReadTimeout,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+		ShutdownTimeout:   defaultShutdownTimeout,
+		DrainDelay:        defaultDrainDelay,
+		MaxHeaderBytes:    defaultMaxHeaderBytes,
+	}
+}
+
+// Address returns the address the server listens on
+func (c Config) Address() string {
+	return ":" + strconv.Itoa(c.Port)
+}
+
+// New creates an *http.Server that serves the given handler with the given configuration
+func New(handler http.Handler, cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Address(),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Run listens on the server address and serves requests until ctx is done, then shuts the server down gracefully
+func Run(ctx context.Context, srv *http.Server, cfg Config, draining func()) error {
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	return Serve(ctx, srv, listener, cfg, draining)
+}
+
+// Serve serves requests on the given listener until ctx is done. When that happens it calls draining, so the
+// readiness probe starts failing, and keeps serving for cfg.DrainDelay while the load balancer stops routing to it.
+// Then it stops accepting new connections and waits up to cfg.ShutdownTimeout for the in-flight requests to finish
+func Serve(ctx context.Context, srv *http.Server, listener net.Listener, cfg Config, draining func()) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	if draining != nil {
+		draining()
+	}
+	select {
+	case err := <-serveErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-time.After(cfg.DrainDelay):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/server"
+)
+
+func TestNew_success(t *testing.T) {
+	cfg := server.DefaultConfig()
+	cfg.Port = 9090
+
+	got := server.New(http.NewServeMux(), cfg)
+
+	assert.Equal(t, ":9090", got.Addr)
+	assert.Equal(t, cfg.ReadTimeout, got.ReadTimeout)
+	assert.Equal(t, cfg.WriteTimeout, got.WriteTimeout)
+	assert.Equal(t, cfg.IdleTimeout, got.IdleTimeout)
+	assert.Equal(t, cfg.MaxHeaderBytes, got.MaxHeaderBytes)
+}
+
+func TestServe_drainsInFlightRequestOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	cfg := server.DefaultConfig()
+	cfg.ShutdownTimeout = 5 * time.Second
+	cfg.DrainDelay = 0
+	srv := server.New(handler, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, srv, listener, cfg, nil)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{body: string(body), err: err}
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-serveErr:
+		t.Fatalf("server stopped before the in-flight request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	got := <-response
+	assert.Nil(t, got.err)
+	assert.Equal(t, "done", got.body)
+	assert.Nil(t, <-serveErr)
+}
+
+func TestServe_failsReadinessBeforeItStopsAcceptingConnections(t *testing.T) {
+	var shuttingDown atomic.Bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	cfg := server.DefaultConfig()
+	cfg.DrainDelay = 200 * time.Millisecond
+	srv := server.New(handler, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+	draining := make(chan struct{})
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, srv, listener, cfg, func() {
+			shuttingDown.Store(true)
+			close(draining)
+		})
+	}()
+
+	cancel()
+	<-draining
+	// a probe during the drain delay still reaches the server and sees it isn't ready
+	resp, err := http.Get("http://" + listener.Addr().String())
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	resp.Body.Close()
+	assert.Nil(t, <-serveErr)
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.NotNil(t, err)
+}
+
+func TestServe_failsWhenShutdownTimeoutExpires(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	cfg := server.DefaultConfig()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	cfg.DrainDelay = 0
+	srv := server.New(handler, cfg)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(ctx, srv, listener, cfg, nil)
+	}()
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	<-started
+	cancel()
+
+	want := context.DeadlineExceeded
+	got := <-serveErr
+
+	assert.Equal(t, want, got)
+}
+
+func TestRun_failsWhenAddressIsInUse(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer listener.Close()
+	srv := server.New(http.NewServeMux(), server.DefaultConfig())
+	srv.Addr = listener.Addr().String()
+
+	got := server.Run(context.Background(), srv, server.DefaultConfig(), nil)
+
+	assert.NotNil(t, got)
+}
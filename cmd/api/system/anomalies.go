@@ -0,0 +1,287 @@
+package system
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	AnomalyLargeDebit  string = "large_debit"
+	AnomalyNewMerchant string = "new_merchant"
+
+	DefaultAnomalyThreshold           float64 = 3.5
+	DefaultAnomalyCountThreshold      float64 = 3.5
+	DefaultAnomalyMinHistory          int     = 5
+	DefaultAnomalyNewMerchantMultiple float64 = 3
+	DefaultAnomalyLookbackDays        int     = 365
+
+	// madScale turns the median absolute deviation of a normal distribution into its standard deviation
+	madScale float64 = 1.4826
+	// meanADScale turns the mean absolute deviation of a normal distribution into its standard deviation
+	meanADScale float64 = 1.2533
+)
+
+type (
+	// AnomalyConfig tunes the detection of unusual activity. A debit is large when its robust z-score, measured
+	// with the median and the median absolute deviation of the debits of its category (or of the account when the
+	// category has less than MinHistory debits), is above Threshold. A month has a spike when the z-score of its
+	// number of transactions among the other months is above CountThreshold. A debit of a merchant never seen before
+	// is flagged when it is at least NewMerchantMultiple times the median debit of the account. The history starts
+	// LookbackDays before the analyzed dates
+	AnomalyConfig struct {
+		Threshold           float64
+		CountThreshold      float64
+		MinHistory          int
+		NewMerchantMultiple float64
+		LookbackDays        int
+	}
+
+	// Anomaly is an unusual transaction with the reasons it was flagged. Typical is the median debit it was compared
+	// with and Score its robust z-score
+	Anomaly struct {
+		ID       int64     `json:"id"`
+		Date     time.Time `json:"date"`
+		Name     string    `json:"name"`
+		Category string    `json:"category,omitempty"`
+		Amount   float64   `json:"amount"`
+		Typical  float64   `json:"typical"`
+		Score    float64   `json:"score"`
+		Reasons  []string  `json:"reasons"`
+	}
+
+	// CountSpike is a month with many more transactions than usual. Typical is the median number of transactions
+	// of the other months and Score the robust z-score of Count
+	CountSpike struct {
+		Key     string    `json:"key"`
+		Start   time.Time `json:"start"`
+		End     time.Time `json:"end"`
+		Count   int       `json:"count"`
+		Typical float64   `json:"typical"`
+		Score   float64   `json:"score"`
+	}
+
+	// Anomalies holds the unusual transactions and months of an account between two dates
+	Anomalies struct {
+		Account      string       `json:"account"`
+		From         time.Time    `json:"from"`
+		To           time.Time    `json:"to"`
+		Transactions []Anomaly    `json:"transactions"`
+		CountSpikes  []CountSpike `json:"count_spikes"`
+	}
+
+	// GetAnomalies is a function that detects the unusual activity of an account between two dates
+	GetAnomalies func(ctx context.Context, account string, from time.Time, to time.Time) (Anomalies, error)
+
+	// robustStats are the median and the spread of a sample, estimated from its median absolute deviation
+	robustStats struct {
+		median float64
+		spread float64
+		count  int
+	}
+)
+
+// DefaultAnomalyConfig returns the default AnomalyConfig
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Threshold:           DefaultAnomalyThreshold,
+		CountThreshold:      DefaultAnomalyCountThreshold,
+		MinHistory:          DefaultAnomalyMinHistory,
+		NewMerchantMultiple: DefaultAnomalyNewMerchantMultiple,
+		LookbackDays:        DefaultAnomalyLookbackDays,
+	}
+}
+
+// DetectAnomalies flags the debits between from and to that are far larger than the usual spending, or that are the
+// first large debit of a merchant, and the months between from and to with a spike in the number of transactions.
+// The history is the baseline: the transactions before from are needed to know the usual spending and merchants
+func DetectAnomalies(history []Transaction, from time.Time, to time.Time, config AnomalyConfig) Anomalies {
+	from, to = calendarDate(from), calendarDate(to)
+	anomalies := Anomalies{From: from, To: to, Transactions: make([]Anomaly, 0), CountSpikes: make([]CountSpike, 0)}
+
+	sorted := append([]Transaction(nil), history...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	var debits []float64
+	categoryDebits := make(map[string][]float64)
+	for _, t := range sorted {
+		if t.Type == "debit" {
+			debits = append(debits, -t.Transaction)
+			categoryDebits[t.Category] = append(categoryDebits[t.Category], -t.Transaction)
+		}
+	}
+	account := newRobustStats(debits)
+	categories := make(map[string]robustStats, len(categoryDebits))
+	for category, amounts := range categoryDebits {
+		categories[category] = newRobustStats(amounts)
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range sorted {
+		merchant := merchantKey(t)
+		firstTime := merchant != "" && !seen[merchant]
+		seen[merchant] = true
+
+		date := calendarDate(t.Date)
+		if t.Type != "debit" || date.Before(from) || date.After(to) {
+			continue
+		}
+
+		stats := categories[t.Category]
+		if stats.count < config.MinHistory {
+			stats = account
+		}
+		if stats.count < config.MinHistory {
+			continue
+		}
+
+		amount := -t.Transaction
+		anomaly := Anomaly{
+			ID:       t.ID,
+			Date:     date,
+			Name:     recurringName(t),
+			Category: t.Category,
+			Amount:   t.Transaction,
+			Typical:  -stats.median,
+			Score:    stats.score(amount),
+		}
+		if anomaly.Score > config.Threshold {
+			anomaly.Reasons = append(anomaly.Reasons, AnomalyLargeDebit)
+		}
+		if firstTime && account.median > 0 && amount >= config.NewMerchantMultiple*account.median {
+			anomaly.Reasons = append(anomaly.Reasons, AnomalyNewMerchant)
+		}
+		if len(anomaly.Reasons) > 0 {
+			anomalies.Transactions = append(anomalies.Transactions, anomaly)
+		}
+	}
+
+	anomalies.CountSpikes = countSpikes(sorted, from, to, config)
+	return anomalies
+}
+
+// MakeGetAnomalies creates a GetAnomalies function that analyzes the transactions stored for the account
+func MakeGetAnomalies(mySQLFindTransactions MySQLFindTransactions, config AnomalyConfig) GetAnomalies {
+	return func(ctx context.Context, account string, from time.Time, to time.Time) (Anomalies, error) {
+		ctx, span := startSpan(ctx, "GetAnomalies", attribute.String("anomalies.account", account))
+		defer span.End()
+
+		from, to = calendarDate(from), calendarDate(to)
+		if account == "" || to.Before(from) {
+			return Anomalies{}, ErrInvalidDateRange
+		}
+
+		history, err := mySQLFindTransactions(ctx, account, from.AddDate(0, 0, -config.LookbackDays), to)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find transactions", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return Anomalies{}, ErrCantGetAnomalies
+		}
+
+		anomalies := DetectAnomalies(history, from, to, config)
+		anomalies.Account = account
+		span.SetAttributes(
+			attribute.Int("anomalies.transactions", len(anomalies.Transactions)),
+			attribute.Int("anomalies.count_spikes", len(anomalies.CountSpikes)),
+		)
+		return anomalies, nil
+	}
+}
+
+// countSpikes compares the number of transactions of every month between from and to with the other months since
+// the first transaction of the history, months without transactions included
+func countSpikes(sorted []Transaction, from time.Time, to time.Time, config AnomalyConfig) []CountSpike {
+	spikes := make([]CountSpike, 0)
+	if len(sorted) == 0 {
+		return spikes
+	}
+
+	counts := make(map[string]int)
+	for _, t := range sorted {
+		counts[newPeriod(t.Date, GroupByMonth).Key]++
+	}
+	var months []Period
+	for month := newPeriod(sorted[0].Date, GroupByMonth); !month.Start.After(to); month = newPeriod(month.Start.AddDate(0, 1, 0), GroupByMonth) {
+		month.Count = counts[month.Key]
+		months = append(months, month)
+	}
+
+	for i, month := range months {
+		if month.End.Before(from) {
+			continue
+		}
+
+		others := make([]float64, 0, len(months)-1)
+		for j, other := range months {
+			if j != i {
+				others = append(others, float64(other.Count))
+			}
+		}
+		stats := newRobustStats(others)
+		if stats.count < config.MinHistory {
+			continue
+		}
+
+		if score := stats.score(float64(month.Count)); score > config.CountThreshold {
+			spikes = append(spikes, CountSpike{
+				Key:     month.Key,
+				Start:   month.Start,
+				End:     month.End,
+				Count:   month.Count,
+				Typical: stats.median,
+				Score:   score,
+			})
+		}
+	}
+
+	return spikes
+}
+
+// newRobustStats returns the median of the values and their spread. When more than half of the values are equal
+// the median absolute deviation is zero, and the spread is estimated from the mean absolute deviation instead
+func newRobustStats(values []float64) robustStats {
+	stats := robustStats{median: median(values), count: len(values)}
+
+	deviations := make([]float64, 0, len(values))
+	var sum float64
+	for _, value := range values {
+		deviation := math.Abs(value - stats.median)
+		deviations = append(deviations, deviation)
+		sum += deviation
+	}
+
+	stats.spread = madScale * median(deviations)
+	if stats.spread == 0 && len(values) > 0 {
+		stats.spread = meanADScale * sum / float64(len(values))
+	}
+
+	return stats
+}
+
+// score returns how many spreads value is above the median. Values are never unusual when all of the sample is equal
+func (s robustStats) score(value float64) float64 {
+	if s.spread == 0 {
+		return 0
+	}
+	return (value - s.median) / s.spread
+}
+
+// merchantKey identifies the merchant of a transaction by the words of its merchant, or of its description when it
+// doesn't have one
+func merchantKey(t Transaction) string {
+	words := nameWords(recurringName(t))
+	keys := make([]string, 0, len(words))
+	for word := range words {
+		keys = append(keys, word)
+	}
+	sort.Strings(keys)
+
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,214 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+// spendingHistory returns a year of usual spending: groceries every few days and transport almost daily, with
+// amounts that vary around their typical value
+func spendingHistory() []system.Transaction {
+	r := rand.New(rand.NewSource(7))
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var history []system.Transaction
+	for day := 0; day < 365; day++ {
+		date := start.AddDate(0, 0, day)
+		if day%4 == 0 {
+			t := system.MockTransaction(int64(len(history)+1), date, "debit", -float64(600+r.Intn(400)))
+			t.Merchant, t.Category = []string{"Soriana", "Walmart", "Chedraui"}[r.Intn(3)], "groceries"
+			history = append(history, t)
+		}
+		if day%3 == 0 {
+			t := system.MockTransaction(int64(len(history)+1), date, "debit", -float64(40+r.Intn(80)))
+			t.Merchant, t.Category = "Uber", "transport"
+			history = append(history, t)
+		}
+	}
+
+	return history
+}
+
+func anomalyFor(anomalies system.Anomalies, id int64) (system.Anomaly, bool) {
+	for _, anomaly := range anomalies.Transactions {
+		if anomaly.ID == id {
+			return anomaly, true
+		}
+	}
+	return system.Anomaly{}, false
+}
+
+func TestDetectAnomalies_ignoresUsualSpending(t *testing.T) {
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(spendingHistory(), from, to, system.DefaultAnomalyConfig())
+
+	assert.Empty(t, got.Transactions)
+	assert.Empty(t, got.CountSpikes)
+}
+
+func TestDetectAnomalies_flagsLargeDebit(t *testing.T) {
+	history := spendingHistory()
+	large := system.MockTransaction(1000, time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), "debit", -4500)
+	large.Merchant, large.Category = "Walmart", "groceries"
+	history = append(history, large)
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	assert.Len(t, got.Transactions, 1)
+	anomaly, ok := anomalyFor(got, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, []string{system.AnomalyLargeDebit}, anomaly.Reasons)
+	assert.Equal(t, -4500.0, anomaly.Amount)
+	assert.InDelta(t, -800, anomaly.Typical, 100)
+	assert.Greater(t, anomaly.Score, system.DefaultAnomalyThreshold)
+}
+
+func TestDetectAnomalies_comparesDebitsWithTheirCategory(t *testing.T) {
+	history := spendingHistory()
+	// Usual for groceries, but ten times the usual ride
+	ride := system.MockTransaction(1000, time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), "debit", -800)
+	ride.Merchant, ride.Category = "Uber", "transport"
+	history = append(history, ride)
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	anomaly, ok := anomalyFor(got, 1000)
+	assert.True(t, ok)
+	assert.Equal(t, []string{system.AnomalyLargeDebit}, anomaly.Reasons)
+	assert.Equal(t, "transport", anomaly.Category)
+}
+
+func TestDetectAnomalies_flagsFirstLargeDebitOfMerchant(t *testing.T) {
+	history := spendingHistory()
+	first := system.MockTransaction(1000, time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC), "debit", -2500)
+	first.Merchant, first.Category = "Liverpool", "shopping"
+	again := system.MockTransaction(1001, time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC), "debit", -2500)
+	again.Merchant, again.Category = "Liverpool", "shopping"
+	small := system.MockTransaction(1002, time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC), "debit", -90)
+	small.Merchant, small.Category = "Oxxo", "shopping"
+	history = append(history, again, first, small)
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	anomaly, ok := anomalyFor(got, 1000)
+	assert.True(t, ok)
+	assert.Contains(t, anomaly.Reasons, system.AnomalyNewMerchant)
+	if again, ok := anomalyFor(got, 1001); ok {
+		assert.NotContains(t, again.Reasons, system.AnomalyNewMerchant)
+	}
+	_, ok = anomalyFor(got, 1002)
+	assert.False(t, ok)
+}
+
+func TestDetectAnomalies_flagsSpikeInTransactionCount(t *testing.T) {
+	history := spendingHistory()
+	for i := 0; i < 60; i++ {
+		t := system.MockTransaction(int64(1000+i), time.Date(2023, 11, 1+i%30, 0, 0, 0, 0, time.UTC), "debit", -60)
+		t.Merchant, t.Category = "Uber", "transport"
+		history = append(history, t)
+	}
+	from := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	assert.Len(t, got.CountSpikes, 1)
+	assert.Equal(t, "2023-11", got.CountSpikes[0].Key)
+	assert.Equal(t, 78, got.CountSpikes[0].Count)
+	assert.Greater(t, got.CountSpikes[0].Score, system.DefaultAnomalyCountThreshold)
+}
+
+func TestDetectAnomalies_ignoresTransactionsOutsideDates(t *testing.T) {
+	history := spendingHistory()
+	large := system.MockTransaction(1000, time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC), "debit", -4500)
+	large.Merchant, large.Category = "Walmart", "groceries"
+	history = append(history, large)
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	assert.Empty(t, got.Transactions)
+}
+
+func TestDetectAnomalies_needsHistory(t *testing.T) {
+	large := system.MockTransaction(1, time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), "debit", -4500)
+	history := []system.Transaction{
+		system.MockTransaction(2, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "debit", -100),
+		system.MockTransaction(3, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), "debit", -120),
+		large,
+	}
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	got := system.DetectAnomalies(history, from, to, system.DefaultAnomalyConfig())
+
+	assert.Empty(t, got.Transactions)
+	assert.Empty(t, got.CountSpikes)
+}
+
+func TestGetAnomalies_success(t *testing.T) {
+	history := spendingHistory()
+	large := system.MockTransaction(1000, time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), "debit", -4500)
+	large.Merchant, large.Category = "Walmart", "groceries"
+	history = append(history, large)
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	var lookback time.Time
+	mySQLFindTransactions := func(_ context.Context, _ string, from time.Time, _ time.Time) ([]system.Transaction, error) {
+		lookback = from
+		return history, nil
+	}
+	getAnomalies := system.MakeGetAnomalies(mySQLFindTransactions, system.DefaultAnomalyConfig())
+
+	got, err := getAnomalies(context.Background(), system.DefaultAccountID, from, to)
+
+	assert.Nil(t, err)
+	assert.Equal(t, system.DefaultAccountID, got.Account)
+	assert.Equal(t, time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC), lookback)
+	assert.Len(t, got.Transactions, 1)
+}
+
+func TestGetAnomalies_fails(t *testing.T) {
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                  string
+		account               string
+		from                  time.Time
+		to                    time.Time
+		mySQLFindTransactions system.MySQLFindTransactions
+		want                  error
+	}{
+		{"invalid range", system.DefaultAccountID, to, from, system.MockMySQLFindTransactions(nil, nil), system.ErrInvalidDateRange},
+		{"missing account", "", from, to, system.MockMySQLFindTransactions(nil, nil), system.ErrInvalidDateRange},
+		{"can't find transactions", system.DefaultAccountID, from, to, system.MockMySQLFindTransactions(nil, errors.New("connection refused")), system.ErrCantGetAnomalies},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAnomalies := system.MakeGetAnomalies(tt.mySQLFindTransactions, system.DefaultAnomalyConfig())
+
+			_, got := getAnomalies(context.Background(), tt.account, tt.from, tt.to)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
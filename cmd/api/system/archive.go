@@ -0,0 +1,286 @@
+package system
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// DefaultMaxUncompressedSize is the maximum number of bytes extracted from a compressed or archived statement
+	DefaultMaxUncompressedSize int64 = 1 << 30
+
+	// DefaultMaxCompressionRatio is the maximum ratio between the extracted and the compressed size of a statement
+	DefaultMaxCompressionRatio float64 = 100
+
+	// DefaultMaxArchiveMembers is the maximum number of files in an archived statement
+	DefaultMaxArchiveMembers int = 1000
+
+	accountGroup string = "account"
+)
+
+var (
+	gzipMagic     = []byte{0x1f, 0x8b}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic      = []byte("PK\x03\x04")
+	emptyZipMagic = []byte("PK\x05\x06")
+)
+
+type (
+	// ArchiveConfig bounds what is extracted from compressed and archived statements, so a small malicious file
+	// can't exhaust memory or disk, and tells which account every file belongs to
+	ArchiveConfig struct {
+		MaxUncompressedSize int64
+		MaxRatio            float64
+		MaxMembers          int
+		Accounts            []AccountPattern
+	}
+
+	// AccountPattern attributes the files whose name matches Pattern to Account. When Account is empty, the
+	// account is the text matched by the "account" named group of Pattern
+	AccountPattern struct {
+		Pattern *regexp.Regexp
+		Account string
+	}
+
+	// statementMember is a statement found in an input file: the file itself, its decompressed content or one of
+	// the files of an archive
+	statementMember struct {
+		name    string
+		account string
+		reader  io.Reader
+	}
+
+	// limitedReader fails with ErrArchiveTooLarge once more than limit bytes are read
+	limitedReader struct {
+		r        io.Reader
+		limit    int64
+		read     int64
+		exceeded bool
+	}
+)
+
+// DefaultArchiveConfig returns the default limits, without account patterns
+func DefaultArchiveConfig() ArchiveConfig {
+	return ArchiveConfig{
+		MaxUncompressedSize: DefaultMaxUncompressedSize,
+		MaxRatio:            DefaultMaxCompressionRatio,
+		MaxMembers:          DefaultMaxArchiveMembers,
+	}
+}
+
+// NewAccountPattern compiles pattern. When account is empty the pattern must have an "account" named group
+func NewAccountPattern(pattern string, account string) (AccountPattern, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return AccountPattern{}, ErrInvalidAccountPattern
+	}
+	if account == "" && re.SubexpIndex(accountGroup) < 0 {
+		return AccountPattern{}, ErrInvalidAccountPattern
+	}
+
+	return AccountPattern{Pattern: re, Account: account}, nil
+}
+
+// AccountFor returns the account of the first pattern that matches the base name of a file
+func (c ArchiveConfig) AccountFor(name string) (string, bool) {
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	for _, pattern := range c.Accounts {
+		match := pattern.Pattern.FindStringSubmatch(base)
+		if match == nil {
+			continue
+		}
+		if pattern.Account != "" {
+			return pattern.Account, true
+		}
+		if account := match[pattern.Pattern.SubexpIndex(accountGroup)]; account != "" {
+			return account, true
+		}
+	}
+
+	return "", false
+}
+
+// isCompressed reports whether head is the start of a gzip, zstd or zip file
+func isCompressed(head []byte) bool {
+	return bytes.HasPrefix(head, gzipMagic) ||
+		bytes.HasPrefix(head, zstdMagic) ||
+		bytes.HasPrefix(head, zipMagic) ||
+		bytes.HasPrefix(head, emptyZipMagic)
+}
+
+// walkStatement calls visit with every statement in filename, opened from files or from the OS file system when files
+// is nil. gzip and zstd files are decompressed on the fly and every file of a zip archive is visited in order. Plain
+// files are visited as they are
+func walkStatement(ctx context.Context, files fs.FS, filename string, config ArchiveConfig, visit func(member statementMember) error) error {
+	file, err := openStatement(files, filename)
+	if err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't open statement", slog.String("filename", filename), slog.Any("error", err))
+		return ErrOpeningStatement
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't open statement", slog.String("filename", filename), slog.Any("error", err))
+		return ErrOpeningStatement
+	}
+
+	buffered := bufio.NewReader(file)
+	head, _ := buffered.Peek(len(zstdMagic))
+	remaining := config.MaxUncompressedSize
+
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read gzip statement", slog.String("filename", filename), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+		defer decompressed.Close()
+
+		return visitLimited(ctx, strings.TrimSuffix(filename, ".gz"), decompressed, info.Size(), config, &remaining, visit)
+
+	case bytes.HasPrefix(head, zstdMagic):
+		decompressed, err := zstd.NewReader(buffered,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(config.MaxUncompressedSize)),
+		)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read zstd statement", slog.String("filename", filename), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+		defer decompressed.Close()
+
+		return visitLimited(ctx, strings.TrimSuffix(filename, ".zst"), decompressed, info.Size(), config, &remaining, visit)
+
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, emptyZipMagic):
+		archive, ok := file.(io.ReaderAt)
+		if !ok {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read zip statement", slog.String("filename", filename))
+			return ErrReadingStatement
+		}
+		return walkZip(ctx, archive, filename, info.Size(), config, &remaining, visit)
+	}
+
+	account, _ := config.AccountFor(filename)
+	return visit(statementMember{name: filename, account: account, reader: buffered})
+}
+
+// openStatement opens filename from files, or from the OS file system when files is nil
+func openStatement(files fs.FS, filename string) (fs.File, error) {
+	if files == nil {
+		return os.Open(filename)
+	}
+	return files.Open(filename)
+}
+
+// walkZip visits the files of a zip archive. Directories and hidden files are skipped. The sizes declared by the
+// archive are checked before extracting a file, and enforced while it is read
+func walkZip(ctx context.Context, file io.ReaderAt, filename string, size int64, config ArchiveConfig, remaining *int64, visit func(member statementMember) error) error {
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't read zip statement", slog.String("filename", filename), slog.Any("error", err))
+		return ErrReadingStatement
+	}
+
+	members := make([]*zip.File, 0, len(archive.File))
+	for _, member := range archive.File {
+		if member.FileInfo().IsDir() || isHiddenMember(member.Name) {
+			continue
+		}
+		members = append(members, member)
+	}
+	if len(members) > config.MaxMembers {
+		LoggerFrom(ctx).ErrorContext(ctx, "too many files in zip statement", slog.String("filename", filename), slog.Int("members", len(members)))
+		return ErrArchiveTooLarge
+	}
+
+	for _, member := range members {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		declared := member.UncompressedSize64
+		if declared > uint64(*remaining) || float64(declared) > config.MaxRatio*float64(member.CompressedSize64) {
+			LoggerFrom(ctx).ErrorContext(ctx, "zip statement member too large",
+				slog.String("filename", filename),
+				slog.String("member", member.Name),
+				slog.Uint64("compressed_size", member.CompressedSize64),
+				slog.Uint64("uncompressed_size", declared),
+			)
+			return ErrArchiveTooLarge
+		}
+
+		content, err := member.Open()
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read zip statement member", slog.String("member", member.Name), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+		err = visitLimited(ctx, member.Name, content, int64(member.CompressedSize64), config, remaining, visit)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// visitLimited visits decompressed content, failing with ErrArchiveTooLarge when it grows over the remaining size or
+// over the maximum ratio to its compressed size
+func visitLimited(ctx context.Context, name string, r io.Reader, compressed int64, config ArchiveConfig, remaining *int64, visit func(member statementMember) error) error {
+	limited := &limitedReader{
+		r:     r,
+		limit: min(*remaining, int64(config.MaxRatio*float64(compressed))),
+	}
+
+	account, _ := config.AccountFor(name)
+	err := visit(statementMember{name: name, account: account, reader: limited})
+	*remaining -= limited.read
+	if limited.exceeded {
+		LoggerFrom(ctx).ErrorContext(ctx, "compressed statement too large",
+			slog.String("member", name),
+			slog.Int64("compressed_size", compressed),
+			slog.Int64("limit", limited.limit),
+		)
+		return ErrArchiveTooLarge
+	}
+
+	return err
+}
+
+// isHiddenMember reports whether a zip member is metadata added by the archiver rather than a statement
+func isHiddenMember(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".")
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read > l.limit {
+		l.exceeded = true
+		return 0, ErrArchiveTooLarge
+	}
+	if allowed := l.limit - l.read + 1; int64(len(p)) > allowed {
+		p = p[:allowed]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		l.exceeded = true
+		return n, ErrArchiveTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,236 @@
+package system_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/klauspost/compress/zstd"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func readDataCSV(t *testing.T) []byte {
+	content, err := os.ReadFile(filepath.Join("data", system.DataFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func writeGzip(t *testing.T, name string, content []byte) string {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write(content)
+	_ = writer.Close()
+
+	filename := filepath.Join(t.TempDir(), name)
+	_ = os.WriteFile(filename, buf.Bytes(), 0o600)
+	return filename
+}
+
+func writeZstd(t *testing.T, name string, content []byte) string {
+	writer, _ := zstd.NewWriter(nil)
+	compressed := writer.EncodeAll(content, nil)
+	_ = writer.Close()
+
+	filename := filepath.Join(t.TempDir(), name)
+	_ = os.WriteFile(filename, compressed, 0o600)
+	return filename
+}
+
+func writeZip(t *testing.T, name string, members map[string][]byte) string {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	names := make([]string, 0, len(members))
+	for memberName := range members {
+		names = append(names, memberName)
+	}
+	sort.Strings(names)
+	for _, memberName := range names {
+		content := members[memberName]
+		if strings.HasSuffix(memberName, "/") {
+			_, _ = writer.Create(memberName)
+			continue
+		}
+		member, _ := writer.Create(memberName)
+		_, _ = member.Write(content)
+	}
+	_ = writer.Close()
+
+	filename := filepath.Join(t.TempDir(), name)
+	_ = os.WriteFile(filename, buf.Bytes(), 0o600)
+	return filename
+}
+
+func makeArchiveReadStatement(archives system.ArchiveConfig, timezones system.Timezones) system.ReadStatement {
+	streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+	return system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), archives, timezones, system.EncodingAuto)
+}
+
+func TestReadStatement_successWithCompressedStatement(t *testing.T) {
+	content := readDataCSV(t)
+	readStatement := makeArchiveReadStatement(system.DefaultArchiveConfig(), system.Timezones{})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		filename string
+	}{
+		{"gzip", writeGzip(t, "data.csv.gz", content)},
+		{"zstd", writeZstd(t, "data.csv.zst", content)},
+		{"zip", writeZip(t, "data.zip", map[string][]byte{"data.csv": content})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(ctx, readStatement, tt.filename)
+
+			assert.Nil(t, err)
+			assert.Equal(t, system.MockTransactions(), got)
+		})
+	}
+}
+
+func TestReadStatement_attributesArchiveMembersToAccounts(t *testing.T) {
+	byGroup, _ := system.NewAccountPattern(`^acct_(?P<account>\d+)\.csv$`, "")
+	byName, _ := system.NewAccountPattern(`(?i)^savings`, "savings")
+	archives := system.DefaultArchiveConfig()
+	archives.Accounts = []system.AccountPattern{byGroup, byName}
+	timezones := system.Timezones{
+		Default:  time.UTC,
+		Accounts: map[string]*time.Location{"2002": time.FixedZone("UTC+14", 14*60*60)},
+	}
+	filename := writeZip(t, "export.zip", map[string][]byte{
+		"export/":                  nil,
+		"export/acct_1001.csv":     []byte("Id,Date,Amount\n0,2023-12-01T12:00:00Z,10\n"),
+		"export/acct_2002.csv":     []byte("Id,Date,Amount\n0,2023-12-01T12:00:00Z,20\n"),
+		"export/Savings.csv":       []byte("Id,Date,Amount\n0,2023-12-01T12:00:00Z,30\n"),
+		"__MACOSX/._acct_1001.csv": []byte("not a statement"),
+	})
+	readStatement := makeArchiveReadStatement(archives, timezones)
+
+	got, err := collect(context.Background(), readStatement, filename)
+
+	assert.Nil(t, err)
+	accounts := map[string]time.Time{}
+	for _, transaction := range got {
+		accounts[transaction.Account] = transaction.Date
+	}
+	assert.Equal(t, map[string]time.Time{
+		"1001":    time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+		"2002":    time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC),
+		"savings": time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+	}, accounts)
+}
+
+func TestImportStatement_insertsEveryAccountMemberOfAnArchive(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("1001", "1", "2")...).WillReturnResult(sqlmock.NewResult(10, 2))
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("2002", "1", "2")...).WillReturnResult(sqlmock.NewResult(12, 2))
+	byGroup, _ := system.NewAccountPattern(`^acct_(?P<account>\d+)\.csv$`, "")
+	archives := system.DefaultArchiveConfig()
+	archives.Accounts = []system.AccountPattern{byGroup}
+	// every member restarts its Id column
+	filename := writeZip(t, "export.zip", map[string][]byte{
+		"acct_1001.csv": []byte("Id,Date,Amount\n1,2023-12-01T12:00:00Z,10\n2,2023-12-02T12:00:00Z,-5\n"),
+		"acct_2002.csv": []byte("Id,Date,Amount\n1,2023-12-01T12:00:00Z,20\n2,2023-12-03T12:00:00Z,-8\n"),
+	})
+	metrics := system.NewMetricsNop()
+	readStatement := system.MakeReadStatement(system.MakeStreamCSV(metrics, system.MockImportProfiles()), system.MakeReadOFX(), system.MakeReadCamt053(), archives, system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, system.MakeMySQLCreate(db, metrics), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 2)
+
+	summary, err := importStatement(context.Background(), filename, system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 4, summary.Count)
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, float64(4), testutil.ToFloat64(metrics.DBRowsInserted))
+}
+
+func TestReadStatement_failsWhenArchiveExceedsLimits(t *testing.T) {
+	bomb := []byte("Id,Date,Amount\n" + strings.Repeat("0,1/1,1\n", 500000))
+	content := readDataCSV(t)
+
+	tests := []struct {
+		name     string
+		filename string
+		limits   func(*system.ArchiveConfig)
+	}{
+		{"gzip ratio", writeGzip(t, "bomb.csv.gz", bomb), func(*system.ArchiveConfig) {}},
+		{"zstd ratio", writeZstd(t, "bomb.csv.zst", bomb), func(*system.ArchiveConfig) {}},
+		{"zip ratio", writeZip(t, "bomb.zip", map[string][]byte{"bomb.csv": bomb}), func(*system.ArchiveConfig) {}},
+		{"gzip size", writeGzip(t, "data.csv.gz", content), func(c *system.ArchiveConfig) { c.MaxUncompressedSize = 100 }},
+		{"zip size", writeZip(t, "data.zip", map[string][]byte{"a.csv": content, "b.csv": content}), func(c *system.ArchiveConfig) {
+			c.MaxUncompressedSize = int64(len(content)) + 1
+		}},
+		{"zip members", writeZip(t, "data.zip", map[string][]byte{"a.csv": content, "b.csv": content}), func(c *system.ArchiveConfig) { c.MaxMembers = 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archives := system.DefaultArchiveConfig()
+			tt.limits(&archives)
+			readStatement := makeArchiveReadStatement(archives, system.Timezones{})
+
+			want := system.ErrArchiveTooLarge
+			_, got := collect(context.Background(), readStatement, tt.filename)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestReadStatement_failsWhenArchiveIsNested(t *testing.T) {
+	inner, _ := os.ReadFile(writeGzip(t, "data.csv.gz", readDataCSV(t)))
+	filename := writeZip(t, "data.zip", map[string][]byte{"data.csv.gz": inner})
+	readStatement := makeArchiveReadStatement(system.DefaultArchiveConfig(), system.Timezones{})
+
+	want := system.ErrNestedArchive
+	_, got := collect(context.Background(), readStatement, filename)
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadStatement_failsWhenArchiveIsCorrupt(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.zip")
+	_ = os.WriteFile(filename, []byte("PK\x03\x04 truncated"), 0o600)
+	readStatement := makeArchiveReadStatement(system.DefaultArchiveConfig(), system.Timezones{})
+
+	want := system.ErrReadingStatement
+	_, got := collect(context.Background(), readStatement, filename)
+
+	assert.Equal(t, want, got)
+}
+
+func TestNewAccountPattern_failsWhenPatternIsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		account string
+	}{
+		{"invalid expression", `acct_(`, "1001"},
+		{"missing account group", `^acct_\d+\.csv$`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := system.ErrInvalidAccountPattern
+			_, got := system.NewAccountPattern(tt.pattern, tt.account)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
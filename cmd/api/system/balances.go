@@ -0,0 +1,153 @@
+package system
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MaxBalanceDays is the longest range of dates of a balance history
+const MaxBalanceDays int = 3660
+
+type (
+	// DailyBalance is the balance of an account at the end of a calendar date
+	DailyBalance struct {
+		Date    time.Time `json:"date"`
+		Balance float64   `json:"balance"`
+	}
+
+	// TransactionBalance is a transaction with the balance of its account right after it
+	TransactionBalance struct {
+		Transaction
+		Balance float64
+	}
+
+	// OpeningBalances holds the balance of each account before its first transaction
+	OpeningBalances map[string]float64
+
+	// AccountBalances is the end of day balance of an account for every date between From and To. Opening is the
+	// balance at the end of the day before From and Closing the balance at the end of To
+	AccountBalances struct {
+		Account  string         `json:"account"`
+		From     time.Time      `json:"from"`
+		To       time.Time      `json:"to"`
+		Opening  float64        `json:"opening"`
+		Closing  float64        `json:"closing"`
+		Balances []DailyBalance `json:"balances"`
+	}
+
+	// GetBalances is a function that returns the balance history of an account
+	GetBalances func(ctx context.Context, account string, from time.Time, to time.Time) (AccountBalances, error)
+)
+
+// For returns the opening balance of the account, zero when it doesn't define one
+func (o OpeningBalances) For(accountID string) float64 {
+	return o[accountID]
+}
+
+// RunningBalance returns the transactions in chronological order, each with the balance in the given currency right
+// after it. Transactions are converted with the rate effective on their date, and the ones without a rate leave the
+// balance as it was
+func RunningBalance(opening float64, currency string, rates ExchangeRates, transactions []Transaction) []TransactionBalance {
+	sorted := append([]Transaction(nil), transactions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].Date.Before(sorted[j].Date)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	balances := make([]TransactionBalance, 0, len(sorted))
+	balance := opening
+	for _, t := range sorted {
+		if amount, ok := convertTransaction(t, currency, rates); ok {
+			balance += amount
+		}
+		balances = append(balances, TransactionBalance{Transaction: t, Balance: balance})
+	}
+
+	return balances
+}
+
+// DailyBalances returns the balance in the given currency at the end of every calendar date with transactions, in
+// chronological order. Transactions without an exchange rate are left out
+func DailyBalances(opening float64, currency string, rates ExchangeRates, transactions []Transaction) []DailyBalance {
+	nets := make(map[time.Time]float64)
+	for _, t := range transactions {
+		amount, _ := convertTransaction(t, currency, rates)
+		nets[calendarDate(t.Date)] += amount
+	}
+
+	daily := make([]DailyBalance, 0, len(nets))
+	for date, net := range nets {
+		daily = append(daily, DailyBalance{Date: date, Balance: net})
+	}
+	sort.Slice(daily, func(i, j int) bool {
+		return daily[i].Date.Before(daily[j].Date)
+	})
+
+	return accumulateBalances(opening, daily)
+}
+
+// MakeGetBalances creates a GetBalances function that fills the days without transactions with the balance of the
+// previous day
+func MakeGetBalances(mySQLFindBalances MySQLFindBalances) GetBalances {
+	return func(ctx context.Context, account string, from time.Time, to time.Time) (AccountBalances, error) {
+		ctx, span := startSpan(ctx, "GetBalances", attribute.String("balances.account", account))
+		defer span.End()
+
+		from, to = calendarDate(from), calendarDate(to)
+		if account == "" || to.Before(from) || to.Sub(from) >= time.Duration(MaxBalanceDays)*24*time.Hour {
+			return AccountBalances{}, ErrInvalidBalanceRange
+		}
+
+		opening, snapshots, err := mySQLFindBalances(ctx, account, from, to)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find balances", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return AccountBalances{}, ErrCantGetBalances
+		}
+
+		balances := AccountBalances{Account: account, From: from, To: to, Opening: opening}
+		balance := opening
+		for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+			for len(snapshots) > 0 && !snapshots[0].Date.After(date) {
+				balance = snapshots[0].Balance
+				snapshots = snapshots[1:]
+			}
+			balances.Balances = append(balances.Balances, DailyBalance{Date: date, Balance: balance})
+		}
+		balances.Closing = balance
+
+		return balances, nil
+	}
+}
+
+// convertTransaction returns the amount of the transaction in the given currency, or false if there is no rate.
+// Transactions without a currency are already in it
+func convertTransaction(t Transaction, currency string, rates ExchangeRates) (float64, bool) {
+	if t.Currency == "" {
+		return t.Transaction, true
+	}
+	return rates.Convert(t.Transaction, t.Currency, currency, t.Date)
+}
+
+// accumulateBalances turns the net amount of each day into the balance at the end of the day
+func accumulateBalances(opening float64, nets []DailyBalance) []DailyBalance {
+	balances := make([]DailyBalance, 0, len(nets))
+	balance := opening
+	for _, net := range nets {
+		balance += net.Balance
+		balances = append(balances, DailyBalance{Date: net.Date, Balance: balance})
+	}
+
+	return balances
+}
+
+// calendarDate returns the calendar date of t at midnight UTC
+func calendarDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,148 @@
+package system_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockBalanceTransactions() []system.Transaction {
+	return []system.Transaction{
+		system.MockTransaction(2, time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), "debit", -40),
+		system.MockTransaction(0, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "credit", 100),
+		system.MockTransaction(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "debit", -25),
+	}
+}
+
+func TestRunningBalance(t *testing.T) {
+	transactions := mockBalanceTransactions()
+
+	want := []system.TransactionBalance{
+		{Transaction: transactions[1], Balance: 1100},
+		{Transaction: transactions[2], Balance: 1075},
+		{Transaction: transactions[0], Balance: 1035},
+	}
+	got := system.RunningBalance(1000, system.DefaultCurrency, system.ExchangeRates{}, transactions)
+
+	assert.Equal(t, want, got)
+}
+
+func TestDailyBalances(t *testing.T) {
+	want := []system.DailyBalance{
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Balance: 75},
+		{Date: time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), Balance: 35},
+	}
+	got := system.DailyBalances(0, system.DefaultCurrency, system.ExchangeRates{}, mockBalanceTransactions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestDailyBalances_convertsToTheAccountCurrency(t *testing.T) {
+	transactions := mockBalanceTransactions()
+	transactions[0].Currency = "USD"
+	transactions[1].Currency = "MXN"
+	transactions[2].Currency = "EUR"
+	rates := system.NewExchangeRates([]system.ExchangeRate{{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), From: "USD", To: "MXN", Rate: 17}})
+
+	want := []system.DailyBalance{
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Balance: 100},
+		{Date: time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), Balance: -580},
+	}
+	got := system.DailyBalances(0, "MXN", rates, transactions)
+
+	assert.Equal(t, want, got)
+}
+
+func TestGetBalances_fillsDaysWithoutTransactions(t *testing.T) {
+	snapshots := []system.DailyBalance{
+		{Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), Balance: 150},
+		{Date: time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC), Balance: 90},
+	}
+	getBalances := system.MakeGetBalances(system.MockMySQLFindBalances(100, snapshots, nil))
+
+	want := system.AccountBalances{
+		Account: "1234",
+		From:    time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC),
+		Opening: 100,
+		Closing: 90,
+		Balances: []system.DailyBalance{
+			{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Balance: 100},
+			{Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), Balance: 150},
+			{Date: time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), Balance: 150},
+			{Date: time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC), Balance: 90},
+			{Date: time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC), Balance: 90},
+		},
+	}
+	got, err := getBalances(context.Background(), "1234", time.Date(2023, 12, 1, 10, 0, 0, 0, time.UTC), time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestGetBalances_failsWhenRangeIsInvalid(t *testing.T) {
+	getBalances := system.MakeGetBalances(system.MockMySQLFindBalances(0, nil, nil))
+	from := time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		account string
+		to      time.Time
+	}{
+		{"without account", "", from},
+		{"to before from", "1234", from.AddDate(0, 0, -1)},
+		{"too long", "1234", from.AddDate(0, 0, system.MaxBalanceDays)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := system.ErrInvalidBalanceRange
+			_, got := getBalances(context.Background(), tt.account, from, tt.to)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestGetBalances_failsWhenFindBalancesFails(t *testing.T) {
+	getBalances := system.MakeGetBalances(system.MockMySQLFindBalances(0, nil, system.ErrCantRunQuery))
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	want := system.ErrCantGetBalances
+	_, got := getBalances(context.Background(), "1234", from, from)
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportStatement_snapshotsBalancesOfEveryAccount(t *testing.T) {
+	transactions := mockBalanceTransactions()
+	transactions[1].Account = "1234"
+	snapshots := map[string]time.Time{}
+	mysqlSnapshotBalances := func(_ context.Context, account string, from time.Time, _ system.ExchangeRates) error {
+		snapshots[account] = from
+		return nil
+	}
+	importStatement := system.MakeImportStatement(system.MockReadStatement(transactions, nil), system.MockMySQLCreate(nil), mysqlSnapshotBalances, system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	want := map[string]time.Time{
+		system.DefaultAccountID: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+		"1234":                  time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+	}
+	_, err := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, snapshots)
+}
+
+func TestImportStatement_failsWhenSnapshotBalancesFails(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(mockBalanceTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(system.ErrCantRunQuery), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	want := system.ErrCantSnapshotBalances
+	_, got := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,278 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// DefaultBudgetAlerts are the percentages of a budget that trigger an alert when they're used
+var DefaultBudgetAlerts = []float64{80, 100}
+
+type (
+	// Budget is the limit of the spending of an account in a category for every week, month, quarter or year. With
+	// Rollover, the part of the budget that isn't spent in a period is added to the next one
+	Budget struct {
+		ID        int64     `json:"id"`
+		Account   string    `json:"account"`
+		Category  string    `json:"category"`
+		Period    string    `json:"period"`
+		Amount    float64   `json:"amount"`
+		Rollover  bool      `json:"rollover"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	// BudgetRequest is the body of the requests that create or update a budget. The period is a month when it's empty
+	BudgetRequest struct {
+		Category string  `json:"category"`
+		Period   string  `json:"period"`
+		Amount   float64 `json:"amount"`
+		Rollover bool    `json:"rollover"`
+	}
+
+	// BudgetStatus is the spending of a budget in its current period. Budget is the amount of the budget plus
+	// Rollover, the amount not spent in the previous periods, and Alert is the highest alert percentage reached
+	BudgetStatus struct {
+		BudgetID    int64     `json:"budget_id"`
+		Account     string    `json:"account"`
+		Category    string    `json:"category"`
+		Key         string    `json:"key"`
+		Start       time.Time `json:"start"`
+		End         time.Time `json:"end"`
+		Budget      float64   `json:"budget"`
+		Rollover    float64   `json:"rollover"`
+		Actual      float64   `json:"actual"`
+		Remaining   float64   `json:"remaining"`
+		PercentUsed float64   `json:"percent_used"`
+		Alert       float64   `json:"alert,omitempty"`
+	}
+
+	// GetBudgetReport is a function that compares the budgets of an account with its spending in the periods that
+	// contain a date
+	GetBudgetReport func(ctx context.Context, account string, date time.Time) ([]BudgetStatus, error)
+
+	// AlertBudgets is a function that notifies the alerts of the budgets, once for each alert and period
+	AlertBudgets func(ctx context.Context, statuses []BudgetStatus) error
+
+	// NotifyBudgetAlert is a function that tells the customer a budget reached an alert
+	NotifyBudgetAlert func(ctx context.Context, status BudgetStatus) error
+)
+
+// NewBudget validates a BudgetRequest and returns the budget of the account it describes
+func NewBudget(account string, request BudgetRequest) (Budget, error) {
+	period, err := ParseGrouping(request.Period)
+	if err != nil {
+		return Budget{}, ErrInvalidBudget
+	}
+	category := strings.TrimSpace(request.Category)
+	if account == "" || category == "" || request.Amount <= 0 {
+		return Budget{}, ErrInvalidBudget
+	}
+
+	return Budget{
+		Account:  account,
+		Category: category,
+		Period:   period,
+		Amount:   request.Amount,
+		Rollover: request.Rollover,
+	}, nil
+}
+
+// BudgetReport returns the status of every budget in its period that contains the calendar date of date. The
+// spending of a category is the sum of its debits. Budgets with rollover carry the amount not spent since the
+// period they were created in, and overspending a period doesn't reduce the next one. alerts are percentages
+func BudgetReport(budgets []Budget, transactions []Transaction, date time.Time, alerts []float64) []BudgetStatus {
+	spent := make(map[string][]Transaction)
+	for _, t := range transactions {
+		if t.Type == "debit" {
+			spent[t.Category] = append(spent[t.Category], t)
+		}
+	}
+	spending := func(category string, period Period) float64 {
+		var total float64
+		for _, t := range spent[category] {
+			if d := calendarDate(t.Date); !d.Before(period.Start) && !d.After(period.End) {
+				total -= t.Transaction
+			}
+		}
+		return total
+	}
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		current := newPeriod(date, budget.Period)
+
+		var rollover float64
+		if budget.Rollover {
+			for period := newPeriod(budget.CreatedAt, budget.Period); period.Start.Before(current.Start); period = newPeriod(period.End.AddDate(0, 0, 1), budget.Period) {
+				rollover = max(0, budget.Amount+rollover-spending(budget.Category, period))
+			}
+		}
+
+		status := BudgetStatus{
+			BudgetID: budget.ID,
+			Account:  budget.Account,
+			Category: budget.Category,
+			Key:      current.Key,
+			Start:    current.Start,
+			End:      current.End,
+			Budget:   budget.Amount + rollover,
+			Rollover: rollover,
+			Actual:   spending(budget.Category, current),
+		}
+		status.Remaining = status.Budget - status.Actual
+		if status.Budget > 0 {
+			status.PercentUsed = 100 * status.Actual / status.Budget
+		}
+		for _, alert := range alerts {
+			if status.PercentUsed >= alert && alert > status.Alert {
+				status.Alert = alert
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].PercentUsed > statuses[j].PercentUsed
+	})
+
+	return statuses
+}
+
+// MakeGetBudgetReport creates a GetBudgetReport function that compares the budgets of the account with the
+// transactions stored for it
+func MakeGetBudgetReport(mySQLListBudgets MySQLListBudgets, mySQLFindTransactions MySQLFindTransactions, alerts []float64) GetBudgetReport {
+	return func(ctx context.Context, account string, date time.Time) ([]BudgetStatus, error) {
+		ctx, span := startSpan(ctx, "GetBudgetReport", attribute.String("budgets.account", account))
+		defer span.End()
+
+		budgets, err := mySQLListBudgets(ctx, account)
+		if err != nil {
+			spanError(span, err)
+			return nil, ErrCantGetBudgetReport
+		}
+		if len(budgets) == 0 {
+			return make([]BudgetStatus, 0), nil
+		}
+
+		date = calendarDate(date)
+		from, to := date, date
+		for _, budget := range budgets {
+			first := newPeriod(date, budget.Period)
+			if budget.Rollover {
+				first = newPeriod(budget.CreatedAt, budget.Period)
+			}
+			from = minDate(from, first.Start)
+			if end := newPeriod(date, budget.Period).End; end.After(to) {
+				to = end
+			}
+		}
+
+		transactions, err := mySQLFindTransactions(ctx, account, from, to)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find transactions", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantGetBudgetReport
+		}
+
+		statuses := BudgetReport(budgets, transactions, date, alerts)
+		span.SetAttributes(attribute.Int("budgets.count", len(statuses)))
+		return statuses, nil
+	}
+}
+
+// MakeAlertBudgets creates an AlertBudgets function. An alert is saved after it's notified, so a failed
+// notification is sent again the next time the budgets are checked
+func MakeAlertBudgets(mySQLBudgetAlertSent MySQLBudgetAlertSent, mySQLSaveBudgetAlert MySQLSaveBudgetAlert, notify NotifyBudgetAlert) AlertBudgets {
+	return func(ctx context.Context, statuses []BudgetStatus) error {
+		ctx, span := startSpan(ctx, "AlertBudgets")
+		defer span.End()
+
+		sent := 0
+		for _, status := range statuses {
+			if status.Alert == 0 {
+				continue
+			}
+
+			alreadySent, err := mySQLBudgetAlertSent(ctx, status)
+			if err != nil {
+				spanError(span, err)
+				return ErrCantAlertBudget
+			}
+			if alreadySent {
+				continue
+			}
+
+			if err := notify(ctx, status); err != nil {
+				LoggerFrom(ctx).ErrorContext(ctx, "can't notify budget alert", slog.Int64("budget", status.BudgetID), slog.Any("error", err))
+				spanError(span, err)
+				return ErrCantAlertBudget
+			}
+			if err := mySQLSaveBudgetAlert(ctx, status); err != nil {
+				spanError(span, err)
+				return ErrCantAlertBudget
+			}
+			sent++
+		}
+		span.SetAttributes(attribute.Int("budgets.alerts_sent", sent))
+
+		return nil
+	}
+}
+
+// MakeLogNotifyBudgetAlert creates a NotifyBudgetAlert function that writes the alerts to the log
+func MakeLogNotifyBudgetAlert() NotifyBudgetAlert {
+	return func(ctx context.Context, status BudgetStatus) error {
+		LoggerFrom(ctx).WarnContext(ctx, "budget alert",
+			slog.String("account", status.Account),
+			slog.String("category", status.Category),
+			slog.String("period", status.Key),
+			slog.Float64("alert", status.Alert),
+			slog.Float64("percent_used", status.PercentUsed),
+		)
+		return nil
+	}
+}
+
+// MakeWebhookNotifyBudgetAlert creates a NotifyBudgetAlert function that posts the status of the budget as JSON to
+// the URL. Responses other than 2xx are errors
+func MakeWebhookNotifyBudgetAlert(client *http.Client, url string) NotifyBudgetAlert {
+	return func(ctx context.Context, status BudgetStatus) error {
+		body, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			return fmt.Errorf("budget alert webhook answered %s", resp.Status)
+		}
+
+		return nil
+	}
+}
+
+// minDate returns the earliest of two dates
+func minDate(a time.Time, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
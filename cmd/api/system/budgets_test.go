@@ -0,0 +1,242 @@
+package system_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func categorized(id int64, date time.Time, amount float64, category string) system.Transaction {
+	trType := "debit"
+	if amount > 0 {
+		trType = "credit"
+	}
+	t := system.MockTransaction(id, date, trType, amount)
+	t.Category = category
+	return t
+}
+
+func TestNewBudget_success(t *testing.T) {
+	want := system.Budget{Account: "default", Category: "groceries", Period: system.GroupByMonth, Amount: 4000, Rollover: true}
+
+	got, err := system.NewBudget("default", system.BudgetRequest{Category: " groceries ", Amount: 4000, Rollover: true})
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestNewBudget_fails(t *testing.T) {
+	tests := []struct {
+		name    string
+		request system.BudgetRequest
+	}{
+		{"missing category", system.BudgetRequest{Amount: 4000}},
+		{"zero amount", system.BudgetRequest{Category: "groceries"}},
+		{"negative amount", system.BudgetRequest{Category: "groceries", Amount: -10}},
+		{"unknown period", system.BudgetRequest{Category: "groceries", Amount: 4000, Period: "fortnight"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := system.NewBudget("default", tt.request)
+
+			assert.Equal(t, system.ErrInvalidBudget, got)
+		})
+	}
+}
+
+func TestBudgetReport_comparesBudgetWithSpending(t *testing.T) {
+	budgets := []system.Budget{
+		{ID: 1, Account: "default", Category: "groceries", Period: system.GroupByMonth, Amount: 4000, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Account: "default", Category: "transport", Period: system.GroupByWeek, Amount: 500, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	transactions := []system.Transaction{
+		categorized(1, time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC), -900, "groceries"),
+		categorized(2, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), -1800, "groceries"),
+		categorized(3, time.Date(2023, 12, 9, 0, 0, 0, 0, time.UTC), -1500, "groceries"),
+		categorized(4, time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC), 15000, "salary"),
+		categorized(5, time.Date(2023, 12, 11, 0, 0, 0, 0, time.UTC), -120, "transport"),
+		categorized(6, time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC), -300, "transport"),
+	}
+
+	got := system.BudgetReport(budgets, transactions, time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC), system.DefaultBudgetAlerts)
+
+	assert.Len(t, got, 2)
+	assert.Equal(t, system.BudgetStatus{
+		BudgetID:    1,
+		Account:     "default",
+		Category:    "groceries",
+		Key:         "2023-12",
+		Start:       time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		Budget:      4000,
+		Actual:      3300,
+		Remaining:   700,
+		PercentUsed: 82.5,
+		Alert:       80,
+	}, got[0])
+	assert.Equal(t, "2023-W50", got[1].Key)
+	assert.Equal(t, 120.0, got[1].Actual)
+	assert.Equal(t, 0.0, got[1].Alert)
+}
+
+func TestBudgetReport_alertsWhenBudgetIsExceeded(t *testing.T) {
+	budgets := []system.Budget{{ID: 1, Category: "dining", Period: system.GroupByMonth, Amount: 1000}}
+	transactions := []system.Transaction{categorized(1, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), -1250, "dining")}
+
+	got := system.BudgetReport(budgets, transactions, time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC), system.DefaultBudgetAlerts)
+
+	assert.Equal(t, 100.0, got[0].Alert)
+	assert.Equal(t, -250.0, got[0].Remaining)
+	assert.Equal(t, 125.0, got[0].PercentUsed)
+}
+
+func TestBudgetReport_rollsOverUnusedBudget(t *testing.T) {
+	budgets := []system.Budget{{ID: 1, Category: "groceries", Period: system.GroupByMonth, Amount: 1000, Rollover: true, CreatedAt: time.Date(2023, 9, 20, 0, 0, 0, 0, time.UTC)}}
+	transactions := []system.Transaction{
+		// September leaves 400 and October 100, November is overspent and carries nothing
+		categorized(1, time.Date(2023, 9, 25, 0, 0, 0, 0, time.UTC), -600, "groceries"),
+		categorized(2, time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC), -1300, "groceries"),
+		categorized(3, time.Date(2023, 11, 5, 0, 0, 0, 0, time.UTC), -1500, "groceries"),
+		categorized(4, time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC), -200, "groceries"),
+	}
+
+	tests := []struct {
+		name     string
+		date     time.Time
+		rollover float64
+	}{
+		{"after an underspent month", time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC), 400},
+		{"after two months", time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC), 100},
+		{"after an overspent month", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), 0},
+		{"in the first month", time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := system.BudgetReport(budgets, transactions, tt.date, system.DefaultBudgetAlerts)
+
+			assert.Equal(t, tt.rollover, got[0].Rollover)
+			assert.Equal(t, 1000+tt.rollover, got[0].Budget)
+		})
+	}
+}
+
+func TestGetBudgetReport_success(t *testing.T) {
+	budget := mockBudget()
+	var from time.Time
+	mySQLFindTransactions := func(_ context.Context, _ string, f time.Time, _ time.Time) ([]system.Transaction, error) {
+		from = f
+		return []system.Transaction{categorized(1, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), -1800, "groceries")}, nil
+	}
+	getBudgetReport := system.MakeGetBudgetReport(system.MockMySQLListBudgets([]system.Budget{budget}, nil), mySQLFindTransactions, system.DefaultBudgetAlerts)
+
+	got, err := getBudgetReport(context.Background(), system.DefaultAccountID, time.Date(2023, 12, 12, 0, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, err)
+	assert.Equal(t, budget.CreatedAt, from)
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1800.0, got[0].Actual)
+}
+
+func TestGetBudgetReport_fails(t *testing.T) {
+	tests := []struct {
+		name                  string
+		mySQLListBudgets      system.MySQLListBudgets
+		mySQLFindTransactions system.MySQLFindTransactions
+	}{
+		{"can't list budgets", system.MockMySQLListBudgets(nil, system.ErrCantRunQuery), system.MockMySQLFindTransactions(nil, nil)},
+		{"can't find transactions", system.MockMySQLListBudgets([]system.Budget{mockBudget()}, nil), system.MockMySQLFindTransactions(nil, errors.New("connection refused"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getBudgetReport := system.MakeGetBudgetReport(tt.mySQLListBudgets, tt.mySQLFindTransactions, system.DefaultBudgetAlerts)
+
+			_, got := getBudgetReport(context.Background(), system.DefaultAccountID, time.Now())
+
+			assert.Equal(t, system.ErrCantGetBudgetReport, got)
+		})
+	}
+}
+
+func TestAlertBudgets_notifiesEachAlertOnce(t *testing.T) {
+	sent := map[int64]bool{2: true}
+	var saved, notified []int64
+	mySQLBudgetAlertSent := func(_ context.Context, status system.BudgetStatus) (bool, error) {
+		return sent[status.BudgetID], nil
+	}
+	mySQLSaveBudgetAlert := func(_ context.Context, status system.BudgetStatus) error {
+		saved = append(saved, status.BudgetID)
+		return nil
+	}
+	notify := func(_ context.Context, status system.BudgetStatus) error {
+		notified = append(notified, status.BudgetID)
+		return nil
+	}
+	alertBudgets := system.MakeAlertBudgets(mySQLBudgetAlertSent, mySQLSaveBudgetAlert, notify)
+
+	err := alertBudgets(context.Background(), []system.BudgetStatus{
+		{BudgetID: 1, Key: "2023-12", Alert: 100},
+		{BudgetID: 2, Key: "2023-12", Alert: 80},
+		{BudgetID: 3, Key: "2023-12"},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{1}, notified)
+	assert.Equal(t, []int64{1}, saved)
+}
+
+func TestAlertBudgets_failsWhenNotificationFails(t *testing.T) {
+	saved := false
+	mySQLSaveBudgetAlert := func(context.Context, system.BudgetStatus) error {
+		saved = true
+		return nil
+	}
+	notify := func(context.Context, system.BudgetStatus) error {
+		return errors.New("webhook unavailable")
+	}
+	alertBudgets := system.MakeAlertBudgets(system.MockMySQLBudgetAlertSent(false, nil), mySQLSaveBudgetAlert, notify)
+
+	got := alertBudgets(context.Background(), []system.BudgetStatus{{BudgetID: 1, Key: "2023-12", Alert: 100}})
+
+	assert.Equal(t, system.ErrCantAlertBudget, got)
+	assert.False(t, saved)
+}
+
+func TestWebhookNotifyBudgetAlert_success(t *testing.T) {
+	var got system.BudgetStatus
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	notify := system.MakeWebhookNotifyBudgetAlert(srv.Client(), srv.URL)
+	status := system.BudgetStatus{BudgetID: 1, Category: "groceries", Key: "2023-12", Budget: 4000, Actual: 3300, PercentUsed: 82.5, Alert: 80}
+
+	err := notify(context.Background(), status)
+
+	assert.Nil(t, err)
+	assert.Equal(t, status, got)
+}
+
+func TestWebhookNotifyBudgetAlert_failsWhenWebhookFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	notify := system.MakeWebhookNotifyBudgetAlert(srv.Client(), srv.URL)
+
+	err := notify(context.Background(), system.BudgetStatus{BudgetID: 1, Alert: 80})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,362 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	camtCredit          string = "CRDT"
+	camtDebit           string = "DBIT"
+	camtOpeningBooked   string = "OPBD"
+	camtPreviousClosing string = "PRCD"
+	camtInterimBooked   string = "ITBD"
+	camtClosingBooked   string = "CLBD"
+	camtNotProvided     string = "NOTPROVIDED"
+	camtLocalTimeLayout string = "2006-01-02T15:04:05"
+
+	// reconcileTolerance absorbs the float rounding of the sum of the entries
+	reconcileTolerance float64 = 0.005
+)
+
+type (
+	// ReadCamt053 is a function that reads ISO 20022 camt.053 content and returns its statements
+	ReadCamt053 func(ctx context.Context, r io.Reader, opts ImportOptions) ([]BankStatement, error)
+
+	// Balance is the balance of an account at the end of a date
+	Balance struct {
+		Amount float64
+		Date   time.Time
+	}
+
+	// BankStatement is a statement of an account sent by the bank, with the balances it reports. A balance the
+	// statement doesn't report is nil
+	BankStatement struct {
+		ID             string
+		Account        string
+		Currency       string
+		OpeningBalance *Balance
+		ClosingBalance *Balance
+		Transactions   []Transaction
+	}
+
+	// camtBookedBalance is a balance of a camt.053 statement with its type code
+	camtBookedBalance struct {
+		Code    string
+		Balance Balance
+	}
+
+	camtAmount struct {
+		Value    float64 `xml:",chardata"`
+		Currency string  `xml:"Ccy,attr"`
+	}
+
+	camtDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	}
+
+	camtBalance struct {
+		Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+		Amount    camtAmount `xml:"Amt"`
+		Indicator string     `xml:"CdtDbtInd"`
+		Date      camtDate   `xml:"Dt"`
+	}
+
+	camtAccount struct {
+		IBAN     string `xml:"Id>IBAN"`
+		Other    string `xml:"Id>Othr>Id"`
+		Currency string `xml:"Ccy"`
+	}
+
+	camtEntry struct {
+		Amount      camtAmount `xml:"Amt"`
+		Indicator   string     `xml:"CdtDbtInd"`
+		BookingDate camtDate   `xml:"BookgDt"`
+		ValueDate   camtDate   `xml:"ValDt"`
+		Reference   string     `xml:"NtryRef"`
+		ServicerRef string     `xml:"AcctSvcrRef"`
+		EndToEndID  string     `xml:"NtryDtls>TxDtls>Refs>EndToEndId"`
+		Remittance  []string   `xml:"NtryDtls>TxDtls>RmtInf>Ustrd"`
+		Info        string     `xml:"AddtlNtryInf"`
+		Creditor    string     `xml:"NtryDtls>TxDtls>RltdPties>Cdtr>Nm"`
+		Debtor      string     `xml:"NtryDtls>TxDtls>RltdPties>Dbtr>Nm"`
+	}
+)
+
+// MakeReadCamt053 creates a ReadCamt053 function. The content is decoded as a stream, one entry at a time. Entries
+// get the end-to-end id, or the servicer reference, as external id so the database deduplicates them per account.
+// Entries without either get a key derived from the statement id, the entry reference, the booking date, the
+// amount and the position of the entry in the statement, which is the same every time the statement is imported
+func MakeReadCamt053() ReadCamt053 {
+	return func(ctx context.Context, r io.Reader, opts ImportOptions) ([]BankStatement, error) {
+		ctx, span := startSpan(ctx, "ReadCamt053")
+		defer span.End()
+
+		statements, rejected, err := decodeCamt053(ctx, r, opts)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read camt.053", slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrReadingStatement
+		}
+
+		span.SetAttributes(attribute.Int("camt.statements", len(statements)), attribute.Int("camt.rows_rejected", rejected))
+		return statements, nil
+	}
+}
+
+// decodeCamt053 decodes the statements of a camt.053 document and counts the entries it had to skip
+func decodeCamt053(ctx context.Context, r io.Reader, opts ImportOptions) ([]BankStatement, int, error) {
+	decoder := xml.NewDecoder(r)
+	// The content is already transcoded to UTF-8, whatever encoding the declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var statements []BankStatement
+	var current *BankStatement
+	var balances []camtBookedBalance
+	var entries, rejected int
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, rejected, err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "Stmt":
+				statements = append(statements, BankStatement{})
+				current = &statements[len(statements)-1]
+				balances = balances[:0]
+				entries = 0
+			case "Id":
+				// The id of the statement is its first child, the ids of accounts and parties are decoded with them
+				if current == nil || current.ID != "" {
+					continue
+				}
+				if err := decoder.DecodeElement(&current.ID, &element); err != nil {
+					return nil, rejected, err
+				}
+				current.ID = strings.TrimSpace(current.ID)
+			case "Acct":
+				if current == nil {
+					continue
+				}
+				var account camtAccount
+				if err := decoder.DecodeElement(&account, &element); err != nil {
+					return nil, rejected, err
+				}
+				current.Account = account.IBAN
+				if current.Account == "" {
+					current.Account = account.Other
+				}
+				current.Currency = account.Currency
+			case "Bal":
+				if current == nil {
+					continue
+				}
+				var balance camtBalance
+				if err := decoder.DecodeElement(&balance, &element); err != nil {
+					return nil, rejected, err
+				}
+				date, _ := balance.Date.parse(opts.Location)
+				balances = append(balances, camtBookedBalance{
+					Code:    balance.Code,
+					Balance: Balance{Amount: signedCamtAmount(balance.Amount.Value, balance.Indicator), Date: date},
+				})
+			case "Ntry":
+				if current == nil {
+					continue
+				}
+				var entry camtEntry
+				if err := decoder.DecodeElement(&entry, &element); err != nil {
+					return nil, rejected, err
+				}
+				entries++
+				transaction, err := entry.transaction(opts.Location)
+				if err != nil {
+					LoggerFrom(ctx).WarnContext(ctx, "rejected camt.053 entry", slog.String("reference", entry.ServicerRef), slog.Any("error", err))
+					rejected++
+					continue
+				}
+				transaction.Account = current.Account
+				if transaction.ExternalID == "" {
+					transaction.ExternalID = fallbackExternalID(current.ID, entry.Reference, transaction, entries)
+				}
+				if transaction.Currency == "" {
+					transaction.Currency = current.Currency
+				}
+				current.Transactions = append(current.Transactions, transaction)
+			}
+		case xml.EndElement:
+			if element.Name.Local == "Stmt" && current != nil {
+				current.OpeningBalance, current.ClosingBalance = statementBalances(balances)
+				current = nil
+			}
+		}
+	}
+
+	if len(statements) == 0 {
+		return nil, rejected, ErrUnknownStatementFormat
+	}
+
+	return statements, rejected, nil
+}
+
+// statementBalances picks the opening and the closing balance of a statement. The opening is the opening booked
+// balance, or the closing booked balance of the previous statement, or the first interim booked balance of a
+// statement split in pages. The closing is the closing booked balance, or the last interim booked balance
+func statementBalances(balances []camtBookedBalance) (*Balance, *Balance) {
+	find := func(code string, last bool) int {
+		found := -1
+		for i, balance := range balances {
+			if balance.Code == code {
+				found = i
+				if !last {
+					break
+				}
+			}
+		}
+		return found
+	}
+
+	opening := find(camtOpeningBooked, false)
+	if opening < 0 {
+		opening = find(camtPreviousClosing, false)
+	}
+	if opening < 0 {
+		opening = find(camtInterimBooked, false)
+	}
+	closing := find(camtClosingBooked, false)
+	if closing < 0 {
+		closing = find(camtInterimBooked, true)
+	}
+
+	var openingBalance, closingBalance *Balance
+	if opening >= 0 {
+		openingBalance = &balances[opening].Balance
+	}
+	if closing >= 0 && closing != opening {
+		closingBalance = &balances[closing].Balance
+	}
+	return openingBalance, closingBalance
+}
+
+// transaction turns a camt.053 entry into a transaction
+func (e camtEntry) transaction(loc *time.Location) (Transaction, error) {
+	if e.Indicator != camtCredit && e.Indicator != camtDebit {
+		return Transaction{}, ErrInvalidAmount
+	}
+
+	date, err := e.BookingDate.parse(loc)
+	if err != nil {
+		return Transaction{}, err
+	}
+	valueDate, err := e.ValueDate.parse(loc)
+	if err != nil {
+		valueDate = date
+	}
+
+	externalID := e.EndToEndID
+	if externalID == "" || externalID == camtNotProvided {
+		externalID = e.ServicerRef
+	}
+
+	description := strings.Join(e.Remittance, " ")
+	if description == "" {
+		description = e.Info
+	}
+
+	// The merchant is the other party: who got paid on debits, who paid on credits
+	merchant := e.Creditor
+	if e.Indicator == camtCredit {
+		merchant = e.Debtor
+	}
+
+	amount := signedCamtAmount(e.Amount.Value, e.Indicator)
+	transaction := Transaction{
+		Date:        date,
+		ValueDate:   valueDate,
+		Transaction: amount,
+		ExternalID:  externalID,
+		Description: strings.TrimSpace(description),
+		Merchant:    strings.TrimSpace(merchant),
+		Currency:    e.Amount.Currency,
+	}
+
+	transaction.Type = "credit"
+	if amount < 0 {
+		transaction.Type = "debit"
+	}
+
+	return transaction, nil
+}
+
+// fallbackExternalID returns the key of an entry without references, from the statement, the entry reference,
+// the booking date, the amount and the ordinal of the entry in the statement
+func fallbackExternalID(statement string, reference string, t Transaction, ordinal int) string {
+	return fmt.Sprintf("%s/%s/%s/%.2f/%d", statement, strings.TrimSpace(reference), t.Date.Format(isoDateLayout), t.Transaction, ordinal)
+}
+
+// parse returns the calendar date in loc, as midnight UTC, of a camt.053 date or datetime
+func (d camtDate) parse(loc *time.Location) (time.Time, error) {
+	if d.Date != "" {
+		date, err := time.Parse(isoDateLayout, strings.TrimSpace(d.Date))
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		return date, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, camtLocalTimeLayout} {
+		date, err := time.ParseInLocation(layout, strings.TrimSpace(d.DateTime), loc)
+		if err == nil {
+			date = date.In(loc)
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidDate
+}
+
+// signedCamtAmount returns the amount as negative when the indicator is a debit
+func signedCamtAmount(amount float64, indicator string) float64 {
+	if indicator == camtDebit {
+		return -amount
+	}
+	return amount
+}
+
+// ReconcileStatement checks that the opening balance plus the transactions of the statement add up to its
+// closing balance. It returns ErrMissingBalance when the statement doesn't report one of them
+func ReconcileStatement(statement BankStatement) error {
+	if statement.OpeningBalance == nil || statement.ClosingBalance == nil {
+		return ErrMissingBalance
+	}
+
+	total, _, _ := getBalanceInfo(statement.Transactions)
+	if math.Abs(statement.OpeningBalance.Amount+total-statement.ClosingBalance.Amount) > reconcileTolerance {
+		return ErrStatementNotReconciled
+	}
+
+	return nil
+}
+
+// isCamt053 reports whether the beginning of a file looks like an ISO 20022 camt.053 statement
+func isCamt053(head []byte) bool {
+	return bytes.Contains(head, []byte("camt.053")) || bytes.Contains(head, []byte("BkToCstmrStmt"))
+}
@@ -0,0 +1,172 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestReadCamt053_success(t *testing.T) {
+	file := openTestdata(t, "statement.camt053.xml")
+	readCamt053 := system.MakeReadCamt053()
+	ctx := context.Background()
+
+	want := []system.BankStatement{
+		{
+			ID:             "STMT-20231201-1",
+			Account:        "ES9121000418450200051332",
+			Currency:       "EUR",
+			OpeningBalance: &system.Balance{Amount: 1000, Date: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)},
+			ClosingBalance: &system.Balance{Amount: 3250.25, Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+			Transactions: []system.Transaction{
+				{
+					Date:        time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+					ValueDate:   time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+					Transaction: 2500,
+					Type:        "credit",
+					ExternalID:  "INV-2023-118",
+					Description: "Invoice 118 November services",
+					Merchant:    "ACME Corp",
+					Account:     "ES9121000418450200051332",
+					Currency:    "EUR",
+				},
+				{
+					Date:        time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC),
+					ValueDate:   time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC),
+					Transaction: -249.75,
+					Type:        "debit",
+					ExternalID:  "REF-0002",
+					Description: "Card payment OFFICE SUPPLIES",
+					Merchant:    "Office Supplies SA",
+					Account:     "ES9121000418450200051332",
+					Currency:    "EUR",
+				},
+			},
+		},
+	}
+	got, err := readCamt053(ctx, file, system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadCamt053_convertsBookingTimeToAccountTimezone(t *testing.T) {
+	file := openTestdata(t, "statement.camt053.xml")
+	readCamt053 := system.MakeReadCamt053()
+	opts := system.MockImportOptions()
+	opts.Location = time.FixedZone("UTC+3", 3*60*60)
+
+	got, err := readCamt053(context.Background(), file, opts)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), got[0].Transactions[1].Date)
+}
+
+func TestReadCamt053_failsWhenCantRead(t *testing.T) {
+	readCamt053 := system.MakeReadCamt053()
+
+	want := system.ErrReadingStatement
+	_, got := readCamt053(context.Background(), iotest.ErrReader(errors.New("disk failure")), system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadCamt053_failsWhenXMLIsMalformed(t *testing.T) {
+	content := strings.NewReader(`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt>`)
+	readCamt053 := system.MakeReadCamt053()
+
+	want := system.ErrReadingStatement
+	_, got := readCamt053(context.Background(), content, system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestReconcileStatement_success(t *testing.T) {
+	statement := system.BankStatement{
+		OpeningBalance: &system.Balance{Amount: 100},
+		ClosingBalance: &system.Balance{Amount: 90.2},
+		Transactions: []system.Transaction{
+			system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "debit", -10.3),
+			system.MockTransaction(1, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), "credit", 0.5),
+		},
+	}
+
+	got := system.ReconcileStatement(statement)
+
+	assert.Nil(t, got)
+}
+
+func TestReconcileStatement_failsWhenBalancesDontMatch(t *testing.T) {
+	statement := system.BankStatement{
+		OpeningBalance: &system.Balance{Amount: 100},
+		ClosingBalance: &system.Balance{Amount: 50},
+		Transactions:   []system.Transaction{system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "debit", -10.3)},
+	}
+
+	want := system.ErrStatementNotReconciled
+	got := system.ReconcileStatement(statement)
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadCamt053_acceptsOtherOpeningAndClosingBalances(t *testing.T) {
+	tests := []struct {
+		name        string
+		balances    string
+		wantOpening *system.Balance
+		wantClosing *system.Balance
+	}{
+		{"previous closing booked", camtBalance("PRCD", "100.00", "2023-11-30") + camtBalance("CLBD", "90.00", "2023-12-01"), &system.Balance{Amount: 100, Date: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)}, &system.Balance{Amount: 90, Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)}},
+		{"interim booked", camtBalance("ITBD", "100.00", "2023-11-30") + camtBalance("ITBD", "90.00", "2023-12-01"), &system.Balance{Amount: 100, Date: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)}, &system.Balance{Amount: 90, Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)}},
+		{"opening booked over previous closing", camtBalance("PRCD", "80.00", "2023-11-29") + camtBalance("OPBD", "100.00", "2023-11-30") + camtBalance("CLBD", "90.00", "2023-12-01"), &system.Balance{Amount: 100, Date: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)}, &system.Balance{Amount: 90, Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)}},
+		{"missing closing", camtBalance("OPBD", "100.00", "2023-11-30"), &system.Balance{Amount: 100, Date: time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)}, nil},
+		{"missing opening", camtBalance("CLBD", "90.00", "2023-12-01"), nil, &system.Balance{Amount: 90, Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readCamt053 := system.MakeReadCamt053()
+
+			got, err := readCamt053(context.Background(), strings.NewReader(camtDocument(tt.balances)), system.MockImportOptions())
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantOpening, got[0].OpeningBalance)
+			assert.Equal(t, tt.wantClosing, got[0].ClosingBalance)
+		})
+	}
+}
+
+func TestReconcileStatement_failsWhenABalanceIsMissing(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement system.BankStatement
+	}{
+		{"missing opening", system.BankStatement{ClosingBalance: &system.Balance{}}},
+		{"missing closing", system.BankStatement{OpeningBalance: &system.Balance{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := system.ErrMissingBalance
+			got := system.ReconcileStatement(tt.statement)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func camtBalance(code string, amount string, date string) string {
+	return `<Bal><Tp><CdOrPrtry><Cd>` + code + `</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">` + amount + `</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>` + date + `</Dt></Dt></Bal>`
+}
+
+func camtDocument(content string) string {
+	return `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"><BkToCstmrStmt><Stmt><Id>STMT-1</Id>` +
+		`<Acct><Id><IBAN>ES9121000418450200051332</IBAN></Id><Ccy>EUR</Ccy></Acct>` + content + `</Stmt></BkToCstmrStmt></Document>`
+}
@@ -0,0 +1,128 @@
+package system
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultCategory is the category of the transactions that don't match any rule
+const DefaultCategory string = "uncategorized"
+
+type (
+	// CategoryRule assigns Category to the transactions whose merchant or description matches Pattern or contains any
+	// of Keywords, ignoring case. When Type is set, only transactions of that type (debit or credit) match
+	CategoryRule struct {
+		Category string
+		Pattern  *regexp.Regexp
+		Keywords []string
+		Type     string
+	}
+
+	// Categorizer assigns a category to every transaction with the first rule that matches it. The rules of the
+	// transaction account are tried before the global rules
+	Categorizer struct {
+		Rules    []CategoryRule
+		Accounts map[string][]CategoryRule
+		Default  string
+	}
+
+	// CategorySpend is the money spent in a category
+	CategorySpend struct {
+		Category string  `json:"category"`
+		Amount   float64 `json:"amount"`
+	}
+)
+
+// NewCategoryRule builds a rule. It needs a category and either a pattern or keywords
+func NewCategoryRule(category string, pattern string, keywords []string, trType string) (CategoryRule, error) {
+	if category == "" || (pattern == "" && len(keywords) == 0) {
+		return CategoryRule{}, ErrInvalidCategoryRule
+	}
+	if trType != "" && trType != "debit" && trType != "credit" {
+		return CategoryRule{}, ErrInvalidCategoryRule
+	}
+
+	rule := CategoryRule{Category: category, Type: trType}
+	if pattern != "" {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return CategoryRule{}, ErrInvalidCategoryRule
+		}
+		rule.Pattern = re
+	}
+	for _, keyword := range keywords {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+			rule.Keywords = append(rule.Keywords, keyword)
+		}
+	}
+
+	return rule, nil
+}
+
+// Categorize returns the category of the transaction. A transaction that already has one keeps it
+func (c Categorizer) Categorize(t Transaction) string {
+	if t.Category != "" {
+		return t.Category
+	}
+
+	for _, rules := range [][]CategoryRule{c.Accounts[t.Account], c.Rules} {
+		for _, rule := range rules {
+			if rule.matches(t) {
+				return rule.Category
+			}
+		}
+	}
+
+	if c.Default != "" {
+		return c.Default
+	}
+	return DefaultCategory
+}
+
+// matches reports whether the rule applies to the transaction
+func (r CategoryRule) matches(t Transaction) bool {
+	if r.Type != "" && r.Type != t.Type {
+		return false
+	}
+
+	for _, text := range []string{t.Merchant, t.Description} {
+		if text == "" {
+			continue
+		}
+		if r.Pattern != nil && r.Pattern.MatchString(text) {
+			return true
+		}
+		lower := strings.ToLower(text)
+		for _, keyword := range r.Keywords {
+			if strings.Contains(lower, keyword) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Categories returns the names of the categories the categorizer can assign, sorted
+func (c Categorizer) Categories() []string {
+	names := make(map[string]bool)
+	if c.Default != "" {
+		names[c.Default] = true
+	}
+	for _, rule := range c.Rules {
+		names[rule.Category] = true
+	}
+	for _, rules := range c.Accounts {
+		for _, rule := range rules {
+			names[rule.Category] = true
+		}
+	}
+
+	categories := make([]string, 0, len(names))
+	for name := range names {
+		categories = append(categories, name)
+	}
+	sort.Strings(categories)
+	return categories
+}
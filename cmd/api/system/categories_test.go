@@ -0,0 +1,132 @@
+package system_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockCategoryRule(t *testing.T, category string, pattern string, keywords []string, trType string) system.CategoryRule {
+	rule, err := system.NewCategoryRule(category, pattern, keywords, trType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
+
+func mockCategorizer(t *testing.T) system.Categorizer {
+	return system.Categorizer{
+		Rules: []system.CategoryRule{
+			mockCategoryRule(t, "groceries", "", []string{"Supermarket", "soriana"}, "debit"),
+			mockCategoryRule(t, "rent", `^rent\b`, nil, "debit"),
+			mockCategoryRule(t, "salary", "", []string{"payroll"}, "credit"),
+		},
+		Accounts: map[string][]system.CategoryRule{
+			"1234": {mockCategoryRule(t, "business", "", []string{"soriana"}, "")},
+		},
+	}
+}
+
+func mockDescribedTransaction(trType string, amount float64, description string, merchant string) system.Transaction {
+	t := system.MockTransaction(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), trType, amount)
+	t.Description = description
+	t.Merchant = merchant
+	return t
+}
+
+func TestCategorizer_Categorize(t *testing.T) {
+	categorizer := mockCategorizer(t)
+
+	business := mockDescribedTransaction("debit", -30, "", "SORIANA")
+	business.Account = "1234"
+	categorized := mockDescribedTransaction("debit", -30, "Weekly groceries", "")
+	categorized.Category = "household"
+
+	tests := []struct {
+		name        string
+		transaction system.Transaction
+		want        string
+	}{
+		{"matches keyword in merchant ignoring case", mockDescribedTransaction("debit", -30, "", "SORIANA SUCURSAL 12"), "groceries"},
+		{"matches pattern in description", mockDescribedTransaction("debit", -8500, "Rent December", ""), "rent"},
+		{"matches only its type", mockDescribedTransaction("debit", -20, "Payroll correction", ""), system.DefaultCategory},
+		{"credit rule", mockDescribedTransaction("credit", 15000, "PAYROLL ACME", ""), "salary"},
+		{"account rules go first", business, "business"},
+		{"keeps an existing category", categorized, "household"},
+		{"without text", system.MockTransaction(1, time.Now(), "debit", -1), system.DefaultCategory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := categorizer.Categorize(tt.transaction)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCategorizer_Categorize_usesConfiguredDefault(t *testing.T) {
+	categorizer := system.Categorizer{Default: "other"}
+
+	got := categorizer.Categorize(mockDescribedTransaction("debit", -10, "Coffee", ""))
+
+	assert.Equal(t, "other", got)
+}
+
+func TestCategorizer_Categories(t *testing.T) {
+	categorizer := mockCategorizer(t)
+	categorizer.Default = system.DefaultCategory
+
+	got := categorizer.Categories()
+
+	assert.Equal(t, []string{"business", "groceries", "rent", "salary", system.DefaultCategory}, got)
+}
+
+func TestNewCategoryRule_fails(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		pattern  string
+		keywords []string
+		trType   string
+	}{
+		{"without category", "", "rent", nil, ""},
+		{"without pattern nor keywords", "rent", "", nil, ""},
+		{"with invalid pattern", "rent", "(rent", nil, ""},
+		{"with invalid type", "rent", "rent", nil, "transfer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := system.ErrInvalidCategoryRule
+			_, got := system.NewCategoryRule(tt.category, tt.pattern, tt.keywords, tt.trType)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestImportStatement_summarizesSpendingPerCategory(t *testing.T) {
+	transactions := []system.Transaction{
+		mockDescribedTransaction("debit", -100, "", "Soriana"),
+		mockDescribedTransaction("debit", -850, "Rent December", ""),
+		mockDescribedTransaction("debit", -25.5, "Supermarket", ""),
+		mockDescribedTransaction("debit", -12, "Coffee", ""),
+		mockDescribedTransaction("credit", 1500, "Payroll", ""),
+	}
+	importStatement := system.MakeImportStatement(system.MockReadStatement(transactions, nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), mockCategorizer(t), system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	want := []system.CategorySpend{
+		{Category: "rent", Amount: 850},
+		{Category: "groceries", Amount: 125.5},
+		{Category: system.DefaultCategory, Amount: 12},
+	}
+	got, err := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got.Email().Spending)
+}
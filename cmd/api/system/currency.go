@@ -0,0 +1,257 @@
+package system
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/text/currency"
+)
+
+// DefaultCurrency is the currency of the accounts that don't define one
+const DefaultCurrency string = "MXN"
+
+type (
+	// Currencies resolves the ISO 4217 currency of each account
+	Currencies struct {
+		Default  string
+		Accounts map[string]string
+	}
+
+	// ExchangeRate is the price in the To currency of one unit of the From currency, effective from its date until
+	// the next rate of the same pair
+	ExchangeRate struct {
+		Date time.Time `json:"date"`
+		From string    `json:"from"`
+		To   string    `json:"to"`
+		Rate float64   `json:"rate"`
+	}
+
+	// ExchangeRates holds the rates of every currency pair sorted by date
+	ExchangeRates struct {
+		pairs map[[2]string][]ExchangeRate
+	}
+
+	// CurrencyTotal holds the aggregates of the transactions of a summary in one currency. Converted is their total
+	// in the currency of the summary, and Unconverted counts the ones without an exchange rate, which are left out of
+	// the balance
+	CurrencyTotal struct {
+		Currency    string  `json:"currency"`
+		Count       int     `json:"count"`
+		Credit      float64 `json:"credit"`
+		Debit       float64 `json:"debit"`
+		Total       float64 `json:"total"`
+		Converted   float64 `json:"converted"`
+		Unconverted int     `json:"unconverted,omitempty"`
+	}
+
+	// GetExchangeRates is a function that loads the exchange rates
+	GetExchangeRates func(ctx context.Context) (ExchangeRates, error)
+)
+
+// ParseCurrency validates an ISO 4217 currency code, ignoring case and surrounding spaces, and returns it in upper
+// case
+func ParseCurrency(code string) (string, error) {
+	unit, err := currency.ParseISO(strings.TrimSpace(code))
+	if err != nil {
+		return "", ErrInvalidCurrency
+	}
+	return unit.String(), nil
+}
+
+// currencyFormat returns the symbol and the minor units of a currency. Currencies without a symbol use their code
+// followed by a space, and an empty code is DefaultCurrency
+func currencyFormat(code string) (string, int) {
+	if code == "" {
+		code = DefaultCurrency
+	}
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return code + " ", 2
+	}
+
+	scale, _ := currency.Standard.Rounding(unit)
+	symbol := fmt.Sprint(currency.NarrowSymbol(unit))
+	if symbol == unit.String() {
+		symbol += " "
+	}
+
+	return symbol, scale
+}
+
+// For returns the currency of the account, or the default one if the account doesn't define it
+func (c Currencies) For(accountID string) string {
+	if code, ok := c.Accounts[accountID]; ok {
+		return code
+	}
+	if c.Default != "" {
+		return c.Default
+	}
+	return DefaultCurrency
+}
+
+// NewExchangeRates indexes the rates by currency pair. When a pair has several rates for the same date, the last
+// one wins
+func NewExchangeRates(rates []ExchangeRate) ExchangeRates {
+	exchangeRates := ExchangeRates{pairs: make(map[[2]string][]ExchangeRate)}
+	for _, rate := range rates {
+		rate.Date = calendarDate(rate.Date)
+		pair := [2]string{rate.From, rate.To}
+		exchangeRates.pairs[pair] = append(exchangeRates.pairs[pair], rate)
+	}
+
+	for pair, rates := range exchangeRates.pairs {
+		sort.SliceStable(rates, func(i, j int) bool {
+			return rates[i].Date.Before(rates[j].Date)
+		})
+		deduplicated := rates[:0]
+		for _, rate := range rates {
+			if n := len(deduplicated); n > 0 && deduplicated[n-1].Date.Equal(rate.Date) {
+				deduplicated[n-1] = rate
+				continue
+			}
+			deduplicated = append(deduplicated, rate)
+		}
+		exchangeRates.pairs[pair] = deduplicated
+	}
+
+	return exchangeRates
+}
+
+// Rate returns the rate from one currency to another effective on the calendar date of date: the latest one on or
+// before it. Pairs without rates of their own use the inverse of the opposite pair
+func (r ExchangeRates) Rate(from string, to string, date time.Time) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+
+	date = calendarDate(date)
+	if rate, ok := effectiveRate(r.pairs[[2]string{from, to}], date); ok {
+		return rate, true
+	}
+	if rate, ok := effectiveRate(r.pairs[[2]string{to, from}], date); ok {
+		return 1 / rate, true
+	}
+
+	return 0, false
+}
+
+// Convert returns the amount in the to currency with the rate effective on date, or false if there is no rate
+func (r ExchangeRates) Convert(amount float64, from string, to string, date time.Time) (float64, bool) {
+	rate, ok := r.Rate(from, to, date)
+	if !ok {
+		return 0, false
+	}
+	return amount * rate, true
+}
+
+// Len returns the number of rates
+func (r ExchangeRates) Len() int {
+	n := 0
+	for _, rates := range r.pairs {
+		n += len(rates)
+	}
+	return n
+}
+
+// effectiveRate returns the latest of the rates, sorted by date, on or before date
+func effectiveRate(rates []ExchangeRate, date time.Time) (float64, bool) {
+	i := sort.Search(len(rates), func(i int) bool {
+		return rates[i].Date.After(date)
+	})
+	if i == 0 {
+		return 0, false
+	}
+	return rates[i-1].Rate, true
+}
+
+// add accumulates the transaction, whose amount in the currency of the summary is converted, in the total
+func (c *CurrencyTotal) add(t Transaction, converted float64, ok bool) {
+	c.Count++
+	c.Total += t.Transaction
+	if t.Type == "debit" {
+		c.Debit += t.Transaction
+	}
+	if t.Type == "credit" {
+		c.Credit += t.Transaction
+	}
+	if !ok {
+		c.Unconverted++
+		return
+	}
+	c.Converted += converted
+}
+
+// MakeReadExchangeRates creates a GetExchangeRates function that reads the rates from a CSV file with a
+// date,from,to,rate header. Dates are yyyy-mm-dd and rates must be positive. The file is read on every call, so it
+// can be updated without restarting the service
+func MakeReadExchangeRates(filename string) GetExchangeRates {
+	return func(ctx context.Context) (ExchangeRates, error) {
+		ctx, span := startSpan(ctx, "ReadExchangeRates", attribute.String("exchange_rates.filename", filename))
+		defer span.End()
+
+		file, err := os.Open(filename)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't open exchange rates", slog.String("filename", filename), slog.Any("error", err))
+			spanError(span, err)
+			return ExchangeRates{}, ErrCantGetExchangeRates
+		}
+		defer file.Close()
+
+		rates, err := parseExchangeRates(file)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read exchange rates", slog.String("filename", filename), slog.Any("error", err))
+			spanError(span, err)
+			return ExchangeRates{}, ErrCantGetExchangeRates
+		}
+		span.SetAttributes(attribute.Int("exchange_rates.count", len(rates)))
+
+		return NewExchangeRates(rates), nil
+	}
+}
+
+// parseExchangeRates reads the rates of a CSV file with a date,from,to,rate header
+func parseExchangeRates(reader io.Reader) ([]ExchangeRate, error) {
+	records := csv.NewReader(reader)
+	records.FieldsPerRecord = 4
+	records.TrimLeadingSpace = true
+
+	if _, err := records.Read(); err != nil {
+		return nil, fmt.Errorf("missing header: %w", err)
+	}
+
+	var rates []ExchangeRate
+	for line := 2; ; line++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		date, err := time.Parse(isoDateLayout, strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, ErrInvalidDate)
+		}
+		from, errFrom := ParseCurrency(record[1])
+		to, errTo := ParseCurrency(record[2])
+		if errFrom != nil || errTo != nil {
+			return nil, fmt.Errorf("line %d: %w", line, ErrInvalidCurrency)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[3]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+
+		rates = append(rates, ExchangeRate{Date: date, From: from, To: to, Rate: rate})
+	}
+}
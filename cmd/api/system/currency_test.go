@@ -0,0 +1,133 @@
+package system_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestParseCurrency_success(t *testing.T) {
+	got, err := system.ParseCurrency(" usd ")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "USD", got)
+}
+
+func TestParseCurrency_failsWhenCodeIsUnknown(t *testing.T) {
+	for _, code := range []string{"", "US", "ABC", "dollars"} {
+		_, got := system.ParseCurrency(code)
+
+		assert.Equal(t, system.ErrInvalidCurrency, got, code)
+	}
+}
+
+func TestCurrencies_For(t *testing.T) {
+	currencies := system.Currencies{Default: "USD", Accounts: map[string]string{"savings": "EUR"}}
+
+	assert.Equal(t, "EUR", currencies.For("savings"))
+	assert.Equal(t, "USD", currencies.For("checking"))
+	assert.Equal(t, system.DefaultCurrency, system.Currencies{}.For("checking"))
+}
+
+func TestExchangeRates_Rate(t *testing.T) {
+	rates := system.NewExchangeRates([]system.ExchangeRate{
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), From: "USD", To: "MXN", Rate: 17.3},
+		{Date: time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC), From: "USD", To: "MXN", Rate: 17.1},
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), From: "MXN", To: "EUR", Rate: 0.05},
+	})
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		date time.Time
+		want float64
+		ok   bool
+	}{
+		{"rate of the date", "USD", "MXN", time.Date(2023, 12, 4, 0, 0, 0, 0, time.UTC), 17.1, true},
+		{"latest rate before the date", "USD", "MXN", time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), 17.3, true},
+		{"inverse of the opposite pair", "EUR", "MXN", time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC), 20, true},
+		{"same currency", "EUR", "EUR", time.Time{}, 1, true},
+		{"before the first rate", "USD", "MXN", time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC), 0, false},
+		{"unknown pair", "USD", "EUR", time.Date(2023, 12, 10, 0, 0, 0, 0, time.UTC), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rates.Rate(tt.from, tt.to, tt.date)
+
+			assert.Equal(t, tt.ok, ok)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestSummary_Add_convertsToItsCurrency(t *testing.T) {
+	date := time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC)
+	usd := system.MockTransaction(1, date, "debit", -10)
+	usd.Currency = "USD"
+	summary := system.Summary{Currency: "MXN", Rates: system.NewExchangeRates([]system.ExchangeRate{{Date: date, From: "USD", To: "MXN", Rate: 17}})}
+
+	summary.Add(system.MockTransaction(0, date, "credit", 100))
+	summary.Add(usd)
+
+	email := summary.Email()
+	assert.Equal(t, "MXN", email.Currency)
+	assert.Equal(t, -70.0, email.Balance)
+	assert.Equal(t, []system.CurrencyTotal{
+		{Currency: "MXN", Count: 1, Credit: 100, Total: 100, Converted: 100},
+		{Currency: "USD", Count: 1, Debit: -10, Total: -10, Converted: -170},
+	}, email.Currencies)
+}
+
+func TestReadExchangeRates_success(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "rates.csv")
+	_ = os.WriteFile(filename, []byte("date,from,to,rate\n2023-12-01,usd,MXN,17.3\n2023-12-04, USD, MXN, 17.1\n"), 0o600)
+	readExchangeRates := system.MakeReadExchangeRates(filename)
+
+	got, err := readExchangeRates(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, got.Len())
+	rate, _ := got.Rate("USD", "MXN", time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 17.1, rate)
+}
+
+func TestReadExchangeRates_fails(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"invalid date", "date,from,to,rate\n01/12/2023,USD,MXN,17.3\n"},
+		{"invalid currency", "date,from,to,rate\n2023-12-01,USD,PESOS,17.3\n"},
+		{"negative rate", "date,from,to,rate\n2023-12-01,USD,MXN,-17.3\n"},
+		{"missing column", "date,from,to,rate\n2023-12-01,USD,MXN\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "rates.csv")
+			_ = os.WriteFile(filename, []byte(tt.content), 0o600)
+			readExchangeRates := system.MakeReadExchangeRates(filename)
+
+			_, got := readExchangeRates(context.Background())
+
+			assert.Equal(t, system.ErrCantGetExchangeRates, got)
+		})
+	}
+}
+
+func TestReadExchangeRates_failsWhenFileDoesntExist(t *testing.T) {
+	readExchangeRates := system.MakeReadExchangeRates(filepath.Join(t.TempDir(), "rates.csv"))
+
+	want := system.ErrCantGetExchangeRates
+	_, got := readExchangeRates(context.Background())
+
+	assert.Equal(t, want, got)
+}
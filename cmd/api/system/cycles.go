@@ -0,0 +1,93 @@
+package system
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// DefaultCycleDay starts the statement cycles on the first day of the month, so they are calendar months
+	DefaultCycleDay int = 1
+
+	// MaxCycleDay is the latest day a cycle can start on, so every month has it
+	MaxCycleDay int = 28
+)
+
+type (
+	// StatementCycle is the recurring period a statement covers: it starts on the Day of a month and ends the day
+	// before the same day of the next month
+	StatementCycle struct {
+		Day int
+	}
+
+	// GetStatementSummary is a function that summarizes the transactions stored for an account in a period
+	GetStatementSummary func(ctx context.Context, account string, period StatementPeriod) (Summary, error)
+)
+
+// NewStatementCycle returns the cycle that starts on the given day of every month, from 1 to MaxCycleDay
+func NewStatementCycle(day int) (StatementCycle, error) {
+	if day < 1 || day > MaxCycleDay {
+		return StatementCycle{}, ErrInvalidCycleDay
+	}
+
+	return StatementCycle{Day: day}, nil
+}
+
+// Containing returns the period of the cycle the calendar date of t belongs to
+func (c StatementCycle) Containing(t time.Time) StatementPeriod {
+	day := c.Day
+	if day < 1 {
+		day = DefaultCycleDay
+	}
+
+	date := calendarDate(t)
+	start := time.Date(date.Year(), date.Month(), day, 0, 0, 0, 0, time.UTC)
+	if date.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+
+	return StatementPeriod{Start: start, End: start.AddDate(0, 1, -1)}
+}
+
+// LastClosed returns the period of the latest cycle that ended before the calendar date of now
+func (c StatementCycle) LastClosed(now time.Time) StatementPeriod {
+	return c.Containing(c.Containing(now).Start.AddDate(0, 0, -1))
+}
+
+// MakeGetStatementSummary creates a GetStatementSummary function. The summary is computed from the transactions
+// stored for the account between the start and the end of the period, whatever file they were imported from,
+// with periods of the given grouping, converted to the currency of the account with the current exchange rates
+func MakeGetStatementSummary(mySQLFindTransactions MySQLFindTransactions, getExchangeRates GetExchangeRates, currencies Currencies, grouping string) GetStatementSummary {
+	return func(ctx context.Context, account string, period StatementPeriod) (Summary, error) {
+		ctx, span := startSpan(ctx, "GetStatementSummary", attribute.String("statement.account", account))
+		defer span.End()
+
+		if account == "" || period.End.Before(period.Start) {
+			return Summary{}, ErrInvalidDateRange
+		}
+
+		rates, err := getExchangeRates(ctx)
+		if err != nil {
+			spanError(span, err)
+			return Summary{}, err
+		}
+
+		transactions, err := mySQLFindTransactions(ctx, account, period.Start, period.End)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find transactions", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return Summary{}, ErrCantGetStatementSummary
+		}
+
+		summary := Summary{Grouping: grouping, Currency: currencies.For(account), Rates: rates}
+		for _, t := range transactions {
+			summary.Add(t)
+		}
+
+		span.SetAttributes(attribute.Int("statement.transactions", summary.Count))
+		return summary, nil
+	}
+}
@@ -0,0 +1,104 @@
+package system_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestNewStatementCycle_failsWhenDayIsOutOfRange(t *testing.T) {
+	for _, day := range []int{0, 29, 31} {
+		_, got := system.NewStatementCycle(day)
+
+		assert.Equal(t, system.ErrInvalidCycleDay, got)
+	}
+}
+
+func TestStatementCycle_LastClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		day  int
+		now  time.Time
+		want system.StatementPeriod
+	}{
+		{"calendar month", 1, time.Date(2024, 3, 10, 18, 30, 0, 0, time.UTC), period(2024, 2, 1, 2024, 2, 29)},
+		{"first day of the month", 1, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), period(2024, 2, 1, 2024, 2, 29)},
+		{"year rollover", 1, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), period(2023, 12, 1, 2023, 12, 31)},
+		{"before the cycle day", 15, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), period(2024, 1, 15, 2024, 2, 14)},
+		{"on the cycle day", 15, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), period(2024, 2, 15, 2024, 3, 14)},
+		{"default day", 0, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), period(2024, 2, 1, 2024, 2, 29)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := system.StatementCycle{Day: tt.day}.LastClosed(tt.now)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatementCycle_LastClosed_isTheSameForEveryDayOfTheCycle(t *testing.T) {
+	cycle, _ := system.NewStatementCycle(1)
+	want := cycle.LastClosed(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	for day := 2; day <= 31; day++ {
+		got := cycle.LastClosed(time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC))
+
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestGetStatementSummary_summarizesTheStoredTransactionsOfThePeriod(t *testing.T) {
+	statementPeriod := period(2023, 1, 1, 2023, 1, 31)
+	var from, to time.Time
+	mysqlFindTransactions := func(_ context.Context, _ string, start time.Time, end time.Time) ([]system.Transaction, error) {
+		from, to = start, end
+		return system.MockTransactions()[:16], nil
+	}
+	getStatementSummary := system.MakeGetStatementSummary(mysqlFindTransactions, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.Currencies{}, system.DefaultGrouping)
+
+	got, err := getStatementSummary(context.Background(), system.DefaultAccountID, statementPeriod)
+
+	assert.Nil(t, err)
+	assert.Equal(t, statementPeriod.Start, from)
+	assert.Equal(t, statementPeriod.End, to)
+	assert.Equal(t, 16, got.Count)
+	assert.Equal(t, system.DefaultCurrency, got.Currency)
+	assert.Equal(t, []system.Period{system.MockPeriods()[0]}, got.Email().Periods)
+}
+
+func TestGetStatementSummary_fails(t *testing.T) {
+	tests := []struct {
+		name    string
+		account string
+		find    system.MySQLFindTransactions
+		rates   system.GetExchangeRates
+		want    error
+	}{
+		{"no account", "", system.MockMySQLFindTransactions(nil, nil), system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.ErrInvalidDateRange},
+		{"can't get rates", system.DefaultAccountID, system.MockMySQLFindTransactions(nil, nil), system.MockGetExchangeRates(system.ExchangeRates{}, system.ErrCantGetExchangeRates), system.ErrCantGetExchangeRates},
+		{"can't find transactions", system.DefaultAccountID, system.MockMySQLFindTransactions(nil, system.ErrCantRunQuery), system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.ErrCantGetStatementSummary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getStatementSummary := system.MakeGetStatementSummary(tt.find, tt.rates, system.Currencies{}, system.DefaultGrouping)
+
+			_, got := getStatementSummary(context.Background(), tt.account, period(2023, 1, 1, 2023, 1, 31))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func period(fromYear int, fromMonth time.Month, fromDay int, toYear int, toMonth time.Month, toDay int) system.StatementPeriod {
+	return system.StatementPeriod{
+		Start: time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC),
+	}
+}
@@ -0,0 +1,143 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultAccountID identifies the account of the imports that don't name one
+	DefaultAccountID string = "default"
+
+	isoDateLayout string = "2006-01-02"
+)
+
+type (
+	// DateParser parses the dates of an import. Formats use the d, dd, m, mm, yy and yyyy tokens, e.g. d/m/yyyy.
+	// Formats without a year are resolved against the statement period of the import
+	DateParser struct {
+		layouts         []string
+		yearlessLayouts []string
+	}
+
+	// Timezones resolves the timezone used to interpret the dates of each account
+	Timezones struct {
+		Default  *time.Location
+		Accounts map[string]*time.Location
+	}
+)
+
+// NewDateParser creates a DateParser that accepts ISO 8601 dates and the given formats
+func NewDateParser(formats ...string) (DateParser, error) {
+	parser := DateParser{layouts: []string{isoDateLayout, time.RFC3339}}
+	for _, format := range formats {
+		layout, hasYear, err := dateLayout(format)
+		if err != nil {
+			return DateParser{}, err
+		}
+
+		if hasYear {
+			parser.layouts = append(parser.layouts, layout)
+		} else {
+			parser.yearlessLayouts = append(parser.yearlessLayouts, layout)
+		}
+	}
+
+	return parser, nil
+}
+
+// DefaultDateParser returns a DateParser that accepts ISO 8601, d/m/yyyy and d/m dates
+func DefaultDateParser() DateParser {
+	parser, _ := NewDateParser("d/m/yyyy", "d/m")
+	return parser
+}
+
+// Parse returns the calendar date of value in the given location as midnight UTC. Dates without a year get the
+// latest year that places them inside the period; if there is none, the date is rejected
+func (p DateParser) Parse(value string, loc *time.Location, period StatementPeriod) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range p.layouts {
+		date, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			date = date.In(loc)
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+
+	for _, layout := range p.yearlessLayouts {
+		date, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+
+		for year := period.End.Year(); year >= period.Start.Year(); year-- {
+			candidate := time.Date(year, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+			if candidate.Day() == date.Day() && period.Contains(candidate) {
+				return candidate, nil
+			}
+		}
+
+		return time.Time{}, ErrDateOutOfPeriod
+	}
+
+	return time.Time{}, ErrInvalidDate
+}
+
+// For returns the timezone of the account, or the default one if the account doesn't define it
+func (tz Timezones) For(accountID string) *time.Location {
+	if loc, ok := tz.Accounts[accountID]; ok {
+		return loc
+	}
+	if tz.Default != nil {
+		return tz.Default
+	}
+	return time.UTC
+}
+
+// dateLayout translates a format such as d/m/yyyy into a Go time layout and reports whether it has a year
+func dateLayout(format string) (string, bool, error) {
+	tokens := []struct {
+		token  string
+		layout string
+	}{
+		{"yyyy", "2006"},
+		{"yy", "06"},
+		{"dd", "02"},
+		{"d", "2"},
+		{"mm", "01"},
+		{"m", "1"},
+	}
+
+	var layout strings.Builder
+	var hasDay, hasMonth, hasYear bool
+	for rest := strings.ToLower(format); rest != ""; {
+		matched := false
+		for _, t := range tokens {
+			if strings.HasPrefix(rest, t.token) {
+				layout.WriteString(t.layout)
+				hasDay = hasDay || t.token[0] == 'd'
+				hasMonth = hasMonth || t.token[0] == 'm'
+				hasYear = hasYear || t.token[0] == 'y'
+				rest = rest[len(t.token):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if strings.ContainsAny(rest[:1], "0123456789abcdefghijklmnopqrstuvwxyz") {
+			return "", false, fmt.Errorf("invalid date format %q", format)
+		}
+		layout.WriteString(rest[:1])
+		rest = rest[1:]
+	}
+
+	if !hasDay || !hasMonth {
+		return "", false, fmt.Errorf("invalid date format %q: day and month are required", format)
+	}
+
+	return layout.String(), hasYear, nil
+}
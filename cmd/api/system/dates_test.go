@@ -0,0 +1,99 @@
+package system_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestDateParser_Parse_success(t *testing.T) {
+	parser := system.DefaultDateParser()
+	mexico := time.FixedZone("UTC-6", -6*60*60)
+
+	tests := []struct {
+		name  string
+		value string
+		loc   *time.Location
+		end   time.Time
+		want  time.Time
+	}{
+		{"iso date", "2023-06-04", time.UTC, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2023, 6, 4, 0, 0, 0, 0, time.UTC)},
+		{"iso timestamp in account timezone", "2024-01-01T02:00:00Z", mexico, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"full date", "21/2/2022", time.UTC, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2022, 2, 21, 0, 0, 0, 0, time.UTC)},
+		{"full leap day", "29/2/2024", time.UTC, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"year-less date in the current year", "5/1", time.UTC, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"year-less date before a rollover", "20/12", time.UTC, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC)},
+		{"year-less date on the period end", "15/1", time.UTC, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"year-less date on the period start", "16/1", time.UTC, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"year-less leap day in a leap year", "29/2", time.UTC, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"year-less leap day after a rollover", "29/2", time.UTC, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parser.Parse(tt.value, tt.loc, system.NewStatementPeriod(tt.end))
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDateParser_Parse_fails(t *testing.T) {
+	parser := system.DefaultDateParser()
+
+	tests := []struct {
+		name  string
+		value string
+		end   time.Time
+		want  error
+	}{
+		{"leap day in a non leap year", "29/2/2023", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), system.ErrInvalidDate},
+		{"year-less leap day without a leap year in the period", "29/2", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), system.ErrDateOutOfPeriod},
+		{"invalid day", "31/2", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), system.ErrInvalidDate},
+		{"not a date", "yesterday", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), system.ErrInvalidDate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := parser.Parse(tt.value, time.UTC, system.NewStatementPeriod(tt.end))
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewDateParser_success(t *testing.T) {
+	parser, err := system.NewDateParser("yyyy.mm.dd", "dd-mm")
+	period := system.NewStatementPeriod(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	full, fullErr := parser.Parse("2023.07.09", time.UTC, period)
+	yearless, yearlessErr := parser.Parse("09-07", time.UTC, period)
+
+	assert.Nil(t, err)
+	assert.Nil(t, fullErr)
+	assert.Nil(t, yearlessErr)
+	assert.Equal(t, time.Date(2023, 7, 9, 0, 0, 0, 0, time.UTC), full)
+	assert.Equal(t, time.Date(2023, 7, 9, 0, 0, 0, 0, time.UTC), yearless)
+}
+
+func TestNewDateParser_failsWhenFormatIsInvalid(t *testing.T) {
+	_, errToken := system.NewDateParser("d/month/yyyy")
+	_, errMissingDay := system.NewDateParser("m/yyyy")
+
+	assert.NotNil(t, errToken)
+	assert.NotNil(t, errMissingDay)
+}
+
+func TestTimezones_For_success(t *testing.T) {
+	mexico := time.FixedZone("UTC-6", -6*60*60)
+	madrid := time.FixedZone("UTC+1", 60*60)
+	timezones := system.Timezones{Default: mexico, Accounts: map[string]*time.Location{"savings": madrid}}
+
+	assert.Equal(t, madrid, timezones.For("savings"))
+	assert.Equal(t, mexico, timezones.For("checking"))
+	assert.Equal(t, time.UTC, system.Timezones{}.For("checking"))
+}
@@ -0,0 +1,205 @@
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// EncodingAuto detects the encoding of a statement from its first bytes
+	EncodingAuto        string = "auto"
+	EncodingUTF8        string = "utf-8"
+	EncodingUTF16LE     string = "utf-16le"
+	EncodingUTF16BE     string = "utf-16be"
+	EncodingWindows1252 string = "windows-1252"
+	EncodingLatin1      string = "iso-8859-1"
+
+	detectSize int = 4096
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+
+	encodingAliases = map[string]string{
+		"":             EncodingAuto,
+		"auto":         EncodingAuto,
+		"utf-8":        EncodingUTF8,
+		"utf8":         EncodingUTF8,
+		"utf-16le":     EncodingUTF16LE,
+		"utf16le":      EncodingUTF16LE,
+		"utf-16be":     EncodingUTF16BE,
+		"utf16be":      EncodingUTF16BE,
+		"windows-1252": EncodingWindows1252,
+		"cp1252":       EncodingWindows1252,
+		"1252":         EncodingWindows1252,
+		"iso-8859-1":   EncodingLatin1,
+		"latin-1":      EncodingLatin1,
+		"latin1":       EncodingLatin1,
+	}
+)
+
+// ParseEncoding returns the canonical name of a source encoding. An empty name means EncodingAuto
+func ParseEncoding(name string) (string, error) {
+	canonical, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", ErrUnknownEncoding
+	}
+
+	return canonical, nil
+}
+
+// newDecodingReader returns the content of r transcoded to UTF-8 and the encoding it was read in. A byte order mark
+// wins over the configured encoding and is removed. With EncodingAuto the encoding is detected from the first bytes:
+// valid UTF-8 is kept as it is, and anything else that isn't UTF-16 is read as Windows-1252, a superset of the
+// printable Latin-1 characters. UTF-8 content is validated while it is read: with EncodingAuto, content that was
+// plain ASCII up to the first invalid byte is read as Windows-1252 from there, and otherwise reading fails with
+// ErrInvalidUTF8
+func newDecodingReader(r *bufio.Reader, configured string) (io.Reader, string, error) {
+	head, err := r.Peek(detectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+
+	name, bomSize := detectBOM(head)
+	switch {
+	case bomSize > 0:
+		_, _ = r.Discard(bomSize)
+	case configured == EncodingAuto:
+		name = detectEncoding(head, err == nil)
+	default:
+		name = configured
+	}
+
+	var decoder *encoding.Decoder
+	switch name {
+	case EncodingUTF16LE:
+		decoder = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+	case EncodingUTF16BE:
+		decoder = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+	case EncodingWindows1252:
+		decoder = charmap.Windows1252.NewDecoder()
+	case EncodingLatin1:
+		decoder = charmap.ISO8859_1.NewDecoder()
+	default:
+		validator := &utf8Validator{ascii: true}
+		if bomSize == 0 && configured == EncodingAuto {
+			validator.fallback = charmap.Windows1252.NewDecoder()
+		}
+		return transform.NewReader(r, validator), EncodingUTF8, nil
+	}
+
+	return transform.NewReader(r, decoder), name, nil
+}
+
+// utf8Validator copies valid UTF-8 content as it is. The first invalid byte fails the transformation, unless a
+// fallback is set and every byte before it was ASCII, which reads the same in UTF-8 and in the fallback encoding
+type utf8Validator struct {
+	fallback transform.Transformer
+	ascii    bool
+	switched bool
+}
+
+// Transform implements transform.Transformer
+func (v *utf8Validator) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	if v.switched {
+		return v.fallback.Transform(dst, src, atEOF)
+	}
+
+	var nDst, nSrc int
+	for nSrc < len(src) {
+		size := 1
+		if src[nSrc] >= utf8.RuneSelf {
+			var r rune
+			r, size = utf8.DecodeRune(src[nSrc:])
+			if r == utf8.RuneError && size == 1 {
+				if !atEOF && !utf8.FullRune(src[nSrc:]) {
+					return nDst, nSrc, transform.ErrShortSrc
+				}
+				if v.fallback == nil || !v.ascii {
+					return nDst, nSrc, ErrInvalidUTF8
+				}
+				v.switched = true
+				fallbackDst, fallbackSrc, err := v.fallback.Transform(dst[nDst:], src[nSrc:], atEOF)
+				return nDst + fallbackDst, nSrc + fallbackSrc, err
+			}
+			v.ascii = false
+		}
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], src[nSrc:nSrc+size])
+		nSrc += size
+	}
+
+	return nDst, nSrc, nil
+}
+
+// Reset implements transform.Transformer
+func (v *utf8Validator) Reset() {
+	v.ascii = true
+	v.switched = false
+	if v.fallback != nil {
+		v.fallback.Reset()
+	}
+}
+
+// detectBOM returns the encoding announced by the byte order mark at the start of head and the size of the mark
+func detectBOM(head []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return EncodingUTF8, len(utf8BOM)
+	case bytes.HasPrefix(head, utf16LEBOM):
+		return EncodingUTF16LE, len(utf16LEBOM)
+	case bytes.HasPrefix(head, utf16BEBOM):
+		return EncodingUTF16BE, len(utf16BEBOM)
+	}
+
+	return "", 0
+}
+
+// detectEncoding guesses the encoding of a text without byte order mark. UTF-16 is recognized by the zero bytes of
+// its ASCII characters. When head isn't the whole content, a rune cut at its end doesn't make it invalid UTF-8
+func detectEncoding(head []byte, truncated bool) string {
+	var evenZeros, oddZeros int
+	for i := 0; i+1 < len(head); i += 2 {
+		if head[i] == 0 {
+			evenZeros++
+		}
+		if head[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(head) / 2
+	if pairs > 0 && oddZeros*4 > pairs && evenZeros < oddZeros {
+		return EncodingUTF16LE
+	}
+	if pairs > 0 && evenZeros*4 > pairs && oddZeros < evenZeros {
+		return EncodingUTF16BE
+	}
+
+	if truncated {
+		for i := 1; i <= utf8.UTFMax && i <= len(head); i++ {
+			if utf8.RuneStart(head[len(head)-i]) {
+				if !utf8.FullRune(head[len(head)-i:]) {
+					head = head[:len(head)-i]
+				}
+				break
+			}
+		}
+	}
+	if utf8.Valid(head) {
+		return EncodingUTF8
+	}
+
+	return EncodingWindows1252
+}
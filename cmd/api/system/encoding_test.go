@@ -0,0 +1,179 @@
+package system_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockSpanishProfile(t *testing.T) system.ImportProfile {
+	dates, err := system.NewDateParser("dd/mm/yyyy")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return system.ImportProfile{
+		Name:             "spanish",
+		Delimiter:        ';',
+		DecimalSeparator: ',',
+		Sign:             system.SignNegativeDebit,
+		Columns:          system.ColumnMapping{ID: "Número", Date: "Fecha", Amount: "Importe"},
+		Dates:            dates,
+	}
+}
+
+func makeEncodingReadStatement(t *testing.T, sourceEncoding string) system.ReadStatement {
+	streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), []system.ImportProfile{mockSpanishProfile(t)})
+	return system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, sourceEncoding)
+}
+
+func TestReadStatement_transcodesSourceEncodings(t *testing.T) {
+	readStatement := makeEncodingReadStatement(t, system.EncodingAuto)
+	ctx := context.Background()
+
+	want := []system.Transaction{
+		system.MockCSVTransaction(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "debit", -45.5),
+		system.MockCSVTransaction(2, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), "credit", 1200),
+		system.MockCSVTransaction(3, time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), "debit", -12.75),
+	}
+
+	tests := []string{
+		"statement_utf8.csv",
+		"statement_utf8_bom.csv",
+		"statement_utf16le_bom.csv",
+		"statement_utf16be_bom.csv",
+		"statement_utf16le.csv",
+		"statement_windows1252.csv",
+		"statement_latin1.csv",
+	}
+
+	for _, name := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := collect(ctx, readStatement, filepath.Join("testdata", "encodings", name))
+
+			assert.Nil(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestReadStatement_transcodesOFXDescription(t *testing.T) {
+	filename := filepath.Join("testdata", "encodings", "statement_windows1252.ofx")
+
+	tests := []struct {
+		name           string
+		sourceEncoding string
+		want           string
+	}{
+		{"detected", system.EncodingAuto, "Café Mañana – €5 propina"},
+		{"configured", system.EncodingLatin1, "Café Mañana \u0096 \u00805 propina"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readStatement := makeEncodingReadStatement(t, tt.sourceEncoding)
+
+			got, err := collect(context.Background(), readStatement, filename)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got[0].Description)
+		})
+	}
+}
+
+// writeLateAccentCSV writes a CSV whose first row after 4 KB has the given description
+func writeLateAccentCSV(t *testing.T, first string, late string) string {
+	var content strings.Builder
+	content.WriteString("Id,Date,Amount,Description\n")
+	fmt.Fprintf(&content, "0,1/12/2023,-1,%s\n", first)
+	for id := 1; content.Len() <= 4096; id++ {
+		fmt.Fprintf(&content, "%d,1/12/2023,-1,Groceries\n", id)
+	}
+	fmt.Fprintf(&content, "9999,2/12/2023,-4.5,%s\n", late)
+
+	filename := filepath.Join(t.TempDir(), "late.csv")
+	_ = os.WriteFile(filename, []byte(content.String()), 0o600)
+	return filename
+}
+
+func TestReadStatement_readsLegacyEncodingFoundAfterTheDetectedBytes(t *testing.T) {
+	filename := writeLateAccentCSV(t, "Groceries", "Caf\xe9 Ni\xf1o")
+	streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+	readStatement := system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+
+	got, err := collect(context.Background(), readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "Café Niño", got[len(got)-1].Description)
+}
+
+func TestReadStatement_failsWhenUTF8IsInvalidAfterTheDetectedBytes(t *testing.T) {
+	tests := []struct {
+		name           string
+		first          string
+		sourceEncoding string
+	}{
+		{"detected from accents", "Caf\u00e9", system.EncodingAuto},
+		{"configured", "Groceries", system.EncodingUTF8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeLateAccentCSV(t, tt.first, "Caf\xe9 Ni\xf1o")
+			streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+			readStatement := system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, tt.sourceEncoding)
+
+			want := system.ErrInvalidUTF8
+			_, got := collect(context.Background(), readStatement, filename)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestReadCSV_successWithLegacyEncoding(t *testing.T) {
+	filename := filepath.Join("testdata", "encodings", "statement_windows1252.csv")
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), []system.ImportProfile{mockSpanishProfile(t)})
+
+	got, err := collect(context.Background(), readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Len(t, got, 3)
+}
+
+func TestParseEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", system.EncodingAuto},
+		{"UTF8", system.EncodingUTF8},
+		{"utf-16LE", system.EncodingUTF16LE},
+		{"CP1252", system.EncodingWindows1252},
+		{" latin1 ", system.EncodingLatin1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := system.ParseEncoding(tt.name)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseEncoding_failsWhenEncodingIsUnknown(t *testing.T) {
+	want := system.ErrUnknownEncoding
+	_, got := system.ParseEncoding("ebcdic")
+
+	assert.Equal(t, want, got)
+}
@@ -7,23 +7,78 @@ import (
 )
 
 var (
-	ErrOpeningCsv             = errors.New("error opening csv")
-	ErrReadingCsv             = errors.New("error reading csv")
-	ErrCantGetCsvFile         = errors.New("can't get csv file")
-	ErrCantGetTransactionInfo = errors.New("can't get transaction info")
-	ErrReadTemplateFile       = errors.New("can't read template file")
-	ErrTemplateParse          = errors.New("can't parse template")
-	ErrTemplateExecute        = errors.New("can't execute template")
-	ErrCantPrepareStatement   = errors.New("can't prepare statement")
-	ErrCantRunQuery           = errors.New("can't run query")
-	ErrCantGetLastID          = errors.New("can't get last id")
-	ErrCantCreateTransactions = errors.New("can't create transactions")
+	ErrOpeningCsv              = errors.New("error opening csv")
+	ErrReadingCsv              = errors.New("error reading csv")
+	ErrCantGetCsvFile          = errors.New("can't get csv file")
+	ErrCantGetTransactionInfo  = errors.New("can't get transaction info")
+	ErrReadTemplateFile        = errors.New("can't read template file")
+	ErrTemplateParse           = errors.New("can't parse template")
+	ErrTemplateExecute         = errors.New("can't execute template")
+	ErrCantPrepareStatement    = errors.New("can't prepare statement")
+	ErrCantRunQuery            = errors.New("can't run query")
+	ErrCantGetLastID           = errors.New("can't get last id")
+	ErrCantCreateTransactions  = errors.New("can't create transactions")
+	ErrCantPingDB              = errors.New("can't ping database")
+	ErrCantReadFile            = errors.New("can't read file")
+	ErrInvalidDate             = errors.New("invalid date")
+	ErrDateOutOfPeriod         = errors.New("date out of statement period")
+	ErrInvalidAmount           = errors.New("invalid amount")
+	ErrUnknownCSVFormat        = errors.New("unknown csv format")
+	ErrOpeningStatement        = errors.New("error opening statement")
+	ErrReadingStatement        = errors.New("error reading statement")
+	ErrUnknownStatementFormat  = errors.New("unknown statement format")
+	ErrMissingBalance          = errors.New("statement doesn't report its opening or closing booked balance")
+	ErrStatementNotReconciled  = errors.New("statement balances don't match its transactions")
+	ErrArchiveTooLarge         = errors.New("compressed statement exceeds the extraction limits")
+	ErrNestedArchive           = errors.New("compressed statements can't contain other compressed files")
+	ErrInvalidAccountPattern   = errors.New("invalid account pattern")
+	ErrUnknownEncoding         = errors.New("unknown character encoding")
+	ErrInvalidUTF8             = errors.New("statement isn't valid utf-8, set imports.encoding to its source encoding")
+	ErrImportNotFound          = errors.New("import not found")
+	ErrInvalidCategoryRule     = errors.New("invalid category rule")
+	ErrUnknownGrouping         = errors.New("unknown summary grouping")
+	ErrInvalidBalanceRange     = errors.New("invalid range of balance dates")
+	ErrCantGetBalances         = errors.New("can't get balances")
+	ErrCantSnapshotBalances    = errors.New("can't snapshot balances")
+	ErrCantIssueStatement      = errors.New("can't issue statement")
+	ErrStatementNotFound       = errors.New("statement not found")
+	ErrInvalidCycleDay         = errors.New("invalid statement cycle day, expected a day from 1 to 28")
+	ErrCantGetStatementSummary = errors.New("can't get statement summary")
+	ErrInvalidDateRange        = errors.New("invalid range of dates")
+	ErrCantGetRecurring        = errors.New("can't get recurring transactions")
+	ErrCantGetAnomalies        = errors.New("can't get anomalies")
+	ErrInvalidForecastDays     = errors.New("invalid number of forecast days")
+	ErrCantGetForecast         = errors.New("can't get forecast")
+	ErrInvalidBudget           = errors.New("invalid budget, expected a category, a positive amount and a week, month, quarter or year period")
+	ErrBudgetExists            = errors.New("the account already has a budget for the category and period")
+	ErrBudgetNotFound          = errors.New("budget not found")
+	ErrCantGetBudgetReport     = errors.New("can't get budget report")
+	ErrCantAlertBudget         = errors.New("can't alert budget")
+	ErrInvalidCurrency         = errors.New("invalid currency, expected an ISO 4217 code")
+	ErrCantGetExchangeRates    = errors.New("can't get exchange rates")
+	ErrIncompleteCatalog       = errors.New("incomplete message catalogs")
+	ErrMissingTranslation      = errors.New("missing translation")
+	ErrUnknownLocale           = errors.New("unknown locale")
+	ErrUnknownTemplate         = errors.New("unknown template")
 )
 
 const (
 	CantGetInfo         string = "can't get info"
 	CantWriteHtml       string = "can't write html"
 	CantWriteSwaggerYML string = "can't write swagger yml"
+	CantGetBalances     string = "can't get balances"
+	InvalidQueryDates   string = "invalid from or to date, expected yyyy-mm-dd"
+	CantGetStatements   string = "can't get statements"
+	InvalidStatementID  string = "invalid statement id"
+	CantGetRecurring    string = "can't get recurring transactions"
+	CantGetAnomalies    string = "can't get anomalies"
+	CantGetForecast     string = "can't get forecast"
+	InvalidQueryDays    string = "invalid days, expected a number"
+	InvalidBudgetID     string = "invalid budget id"
+	InvalidBudgetBody   string = "invalid budget body"
+	CantGetBudgets      string = "can't get budgets"
+	CantSaveBudget      string = "can't save budget"
+	InvalidQueryDate    string = "invalid date, expected yyyy-mm-dd"
 )
 
 type Error struct {
@@ -0,0 +1,198 @@
+package system
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	DefaultForecastDays          int = 30
+	DefaultForecastLookbackDays  int = 365
+	DefaultForecastSeasonalWeeks int = 12
+
+	// MaxForecastDays is the longest projection of a forecast
+	MaxForecastDays int = 366
+)
+
+type (
+	// ForecastConfig tunes the projection of balances. Days is the length of the projection when it isn't given,
+	// the recurring series are detected with Recurring in the LookbackDays before it, and the spending that isn't
+	// recurring is the average of each day of the week over the last SeasonalWeeks weeks
+	ForecastConfig struct {
+		Days          int
+		LookbackDays  int
+		SeasonalWeeks int
+		Recurring     RecurringConfig
+	}
+
+	// ForecastDay is the projected balance at the end of a calendar date, with the expected recurring and
+	// discretionary amounts of the day
+	ForecastDay struct {
+		Date          time.Time `json:"date"`
+		Recurring     float64   `json:"recurring"`
+		Discretionary float64   `json:"discretionary"`
+		Balance       float64   `json:"balance"`
+	}
+
+	// Forecast is the projected end of day balance of an account for every date between From and To. Opening is the
+	// balance at the end of the day before From, Lowest the lowest projected balance and NegativeDate the first date
+	// with a negative balance, nil when the balance is never projected below zero
+	Forecast struct {
+		Account      string        `json:"account,omitempty"`
+		From         time.Time     `json:"from"`
+		To           time.Time     `json:"to"`
+		Opening      float64       `json:"opening"`
+		Closing      float64       `json:"closing"`
+		Lowest       float64       `json:"lowest"`
+		LowestDate   time.Time     `json:"lowest_date"`
+		NegativeDate *time.Time    `json:"negative_date,omitempty"`
+		Days         []ForecastDay `json:"days"`
+	}
+
+	// GetForecast is a function that projects the balance of an account for the given number of days from a date.
+	// Zero days is the default length of the forecast
+	GetForecast func(ctx context.Context, account string, from time.Time, days int) (Forecast, error)
+)
+
+// DefaultForecastConfig returns the default ForecastConfig
+func DefaultForecastConfig() ForecastConfig {
+	return ForecastConfig{
+		Days:          DefaultForecastDays,
+		LookbackDays:  DefaultForecastLookbackDays,
+		SeasonalWeeks: DefaultForecastSeasonalWeeks,
+		Recurring:     DefaultRecurringConfig(),
+	}
+}
+
+// ProjectBalances projects the balance for days days from the calendar date of from, starting with opening. Every
+// date gets the recurring series of the history expected on it, and the average of the spending that isn't
+// recurring on the same day of the week. Series that missed their last two occurrences are considered finished.
+// Only the history before from is used, so past dates can be projected to measure the accuracy of the forecast
+func ProjectBalances(history []Transaction, opening float64, from time.Time, days int, config ForecastConfig) Forecast {
+	from = calendarDate(from)
+	to := from.AddDate(0, 0, days-1)
+
+	past := make([]Transaction, 0, len(history))
+	for _, t := range history {
+		if calendarDate(t.Date).Before(from) {
+			past = append(past, t)
+		}
+	}
+	recurring, members := detectRecurring(past, config.Recurring)
+
+	expected := make(map[time.Time]float64)
+	for _, r := range recurring {
+		c, ok := cadenceNamed(r.Cadence)
+		if !ok {
+			continue
+		}
+		occurrence := 1
+		for c.add(r.LastDate, occurrence).Before(from) {
+			occurrence++
+		}
+		if occurrence > 2 {
+			continue
+		}
+		for date := c.add(r.LastDate, occurrence); !date.After(to); date = c.add(r.LastDate, occurrence) {
+			expected[date] += r.Amount
+			occurrence++
+		}
+	}
+
+	weekdays := weekdayAverages(past, members, from, config.SeasonalWeeks)
+
+	forecast := Forecast{From: from, To: to, Opening: opening, Lowest: opening, LowestDate: from.AddDate(0, 0, -1), Days: make([]ForecastDay, 0, days)}
+	balance := opening
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := ForecastDay{Date: date, Recurring: expected[date], Discretionary: weekdays[date.Weekday()]}
+		balance += day.Recurring + day.Discretionary
+		day.Balance = balance
+		forecast.Days = append(forecast.Days, day)
+
+		if balance < forecast.Lowest {
+			forecast.Lowest, forecast.LowestDate = balance, date
+		}
+		if balance < 0 && forecast.NegativeDate == nil {
+			negative := date
+			forecast.NegativeDate = &negative
+		}
+	}
+	forecast.Closing = balance
+
+	return forecast
+}
+
+// MakeGetForecast creates a GetForecast function that projects the balance of the account from its balance at the
+// end of the day before from and the transactions stored before it
+func MakeGetForecast(mySQLFindTransactions MySQLFindTransactions, getBalances GetBalances, config ForecastConfig) GetForecast {
+	return func(ctx context.Context, account string, from time.Time, days int) (Forecast, error) {
+		ctx, span := startSpan(ctx, "GetForecast", attribute.String("forecast.account", account), attribute.Int("forecast.days", days))
+		defer span.End()
+
+		if days == 0 {
+			days = config.Days
+		}
+		if account == "" || days < 1 || days > MaxForecastDays {
+			return Forecast{}, ErrInvalidForecastDays
+		}
+
+		before := calendarDate(from).AddDate(0, 0, -1)
+		balances, err := getBalances(ctx, account, before, before)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't get balance", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return Forecast{}, ErrCantGetForecast
+		}
+
+		history, err := mySQLFindTransactions(ctx, account, before.AddDate(0, 0, -config.LookbackDays), before)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find transactions", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return Forecast{}, ErrCantGetForecast
+		}
+
+		forecast := ProjectBalances(history, balances.Closing, from, days, config)
+		forecast.Account = account
+		span.SetAttributes(attribute.Bool("forecast.negative", forecast.NegativeDate != nil))
+		return forecast, nil
+	}
+}
+
+// weekdayAverages returns the average net amount of the transactions that aren't recurring for every day of the
+// week, over the weeks weeks before from or since the first transaction when the history is shorter
+func weekdayAverages(past []Transaction, recurring map[Transaction]bool, from time.Time, weeks int) map[time.Weekday]float64 {
+	averages := make(map[time.Weekday]float64)
+	if len(past) == 0 {
+		return averages
+	}
+
+	start := from.AddDate(0, 0, -7*weeks)
+	first := calendarDate(past[0].Date)
+	for _, t := range past {
+		if date := calendarDate(t.Date); date.Before(first) {
+			first = date
+		}
+	}
+	if first.After(start) {
+		start = first
+	}
+
+	sums := make(map[time.Weekday]float64)
+	for _, t := range past {
+		if date := calendarDate(t.Date); !recurring[t] && !date.Before(start) {
+			sums[date.Weekday()] += t.Transaction
+		}
+	}
+	counts := make(map[time.Weekday]int)
+	for date := start; date.Before(from); date = date.AddDate(0, 0, 1) {
+		counts[date.Weekday()]++
+	}
+	for weekday, sum := range sums {
+		averages[weekday] = sum / float64(counts[weekday])
+	}
+
+	return averages
+}
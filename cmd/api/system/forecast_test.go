@@ -0,0 +1,233 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+// cashFlowHistory returns two years of an account with a biweekly payroll, the monthly rent and subscription, a
+// weekly gym and daily purchases that are larger on weekends
+func cashFlowHistory(seed int64) []system.Transaction {
+	r := rand.New(rand.NewSource(seed))
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var history []system.Transaction
+	add := func(date time.Time, trType string, amount float64, merchant string) {
+		t := system.MockTransaction(int64(len(history)+1), date, trType, amount)
+		t.Merchant = merchant
+		history = append(history, t)
+	}
+	for i := 0; i < 52; i++ {
+		add(start.AddDate(0, 0, 6+14*i), "credit", 12000, "PAYROLL ACME")
+	}
+	for i := 0; i < 24; i++ {
+		add(start.AddDate(0, i, 0), "debit", -8500, "Rent Polanco")
+		add(start.AddDate(0, i, 8), "debit", -219, "NETFLIX.COM")
+	}
+	for i := 0; i < 104; i++ {
+		add(start.AddDate(0, 0, 2+7*i), "debit", -150, "Gym Sport City")
+	}
+	shops := []string{"Soriana", "Walmart", "Oxxo", "Amazon", "Liverpool", "Starbucks", "Uber", "Cinepolis"}
+	for day := 0; day < 730; day++ {
+		date := start.AddDate(0, 0, day)
+		mean := 250.0
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			mean = 900
+		}
+		for purchases := r.Intn(3); purchases > 0; purchases-- {
+			add(date, "debit", -math.Round(mean*(0.5+r.Float64())), shops[r.Intn(len(shops))])
+		}
+	}
+
+	r.Shuffle(len(history), func(i, j int) { history[i], history[j] = history[j], history[i] })
+	return history
+}
+
+// backtest projects days days from every cutoff with the history before it, and returns the mean absolute error of
+// the projected end of day balances against the actual ones, along with the error of assuming the balance doesn't
+// change
+func backtest(history []system.Transaction, opening float64, cutoffs []time.Time, days int, config system.ForecastConfig) (float64, float64) {
+	nets := make(map[time.Time]float64)
+	for _, t := range history {
+		nets[time.Date(t.Date.Year(), t.Date.Month(), t.Date.Day(), 0, 0, 0, 0, time.UTC)] += t.Transaction
+	}
+
+	var forecastError, flatError float64
+	for _, cutoff := range cutoffs {
+		balance := opening
+		for _, t := range history {
+			if t.Date.Before(cutoff) {
+				balance += t.Transaction
+			}
+		}
+
+		forecast := system.ProjectBalances(history, balance, cutoff, days, config)
+		actual := balance
+		for _, day := range forecast.Days {
+			actual += nets[day.Date]
+			forecastError += math.Abs(day.Balance - actual)
+			flatError += math.Abs(balance - actual)
+		}
+	}
+
+	samples := float64(len(cutoffs) * days)
+	return forecastError / samples, flatError / samples
+}
+
+func TestProjectBalances_backtestsAgainstHistory(t *testing.T) {
+	var cutoffs []time.Time
+	for month := 0; month < 11; month++ {
+		cutoffs = append(cutoffs, time.Date(2023, time.February, 1, 0, 0, 0, 0, time.UTC).AddDate(0, month, 0))
+	}
+
+	// Daily purchases are random, so the error grows with the days; it must still be far below the swings of the
+	// balance between paydays and a fraction of the monthly income
+	for _, seed := range []int64{1, 2, 3} {
+		forecastError, flatError := backtest(cashFlowHistory(seed), 20000, cutoffs, 30, system.DefaultForecastConfig())
+
+		assert.Less(t, forecastError, flatError/2)
+		assert.Less(t, forecastError, 2500.0)
+	}
+}
+
+func TestProjectBalances_projectsRecurringSeries(t *testing.T) {
+	var history []system.Transaction
+	for i := 0; i < 6; i++ {
+		history = append(history, system.MockTransaction(int64(2*i), time.Date(2023, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC), "debit", -8500))
+		history[len(history)-1].Description = "Rent Polanco"
+		history = append(history, system.MockTransaction(int64(2*i+1), time.Date(2023, time.Month(1+i), 15, 0, 0, 0, 0, time.UTC), "credit", 15000))
+		history[len(history)-1].Description = "PAYROLL ACME"
+	}
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	got := system.ProjectBalances(history, 1000, from, 31, system.DefaultForecastConfig())
+
+	assert.Len(t, got.Days, 31)
+	assert.Equal(t, time.Date(2023, 7, 31, 0, 0, 0, 0, time.UTC), got.To)
+	assert.Equal(t, -8500.0, got.Days[0].Recurring)
+	assert.Equal(t, 15000.0, got.Days[14].Recurring)
+	assert.Equal(t, 0.0, got.Days[14].Discretionary)
+	assert.Equal(t, 7500.0, got.Closing)
+	assert.Equal(t, -7500.0, got.Lowest)
+	assert.Equal(t, from, got.LowestDate)
+	assert.Equal(t, &from, got.NegativeDate)
+}
+
+func TestProjectBalances_projectsSeriesOnTheLastDayOfTheMonth(t *testing.T) {
+	var history []system.Transaction
+	for i := 0; i < 6; i++ {
+		date := time.Date(2023, time.Month(8+i), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+		history = append(history, system.MockTransaction(int64(i), date, "debit", -8500))
+		history[len(history)-1].Description = "Rent Polanco"
+	}
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	got := system.ProjectBalances(history, 10000, from, 60, system.DefaultForecastConfig())
+
+	for _, day := range got.Days {
+		switch day.Date {
+		case time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC):
+			assert.Equal(t, -8500.0, day.Recurring, day.Date.String())
+		default:
+			assert.Zero(t, day.Recurring, day.Date.String())
+		}
+	}
+}
+
+func TestProjectBalances_averagesSpendingByDayOfWeek(t *testing.T) {
+	var history []system.Transaction
+	saturday := time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 12; i++ {
+		t := system.MockTransaction(int64(i), saturday.AddDate(0, 0, 7*i), "debit", -float64(100+10*(i%3)))
+		t.Merchant = []string{"Soriana", "Cinepolis", "Amazon"}[i%3]
+		history = append(history, t)
+	}
+	from := time.Date(2023, 8, 26, 0, 0, 0, 0, time.UTC)
+
+	got := system.ProjectBalances(history, 5000, from, 7, system.DefaultForecastConfig())
+
+	assert.Equal(t, time.Saturday, got.Days[0].Date.Weekday())
+	assert.InDelta(t, -110, got.Days[0].Discretionary, 0.001)
+	for _, day := range got.Days[1:] {
+		assert.Equal(t, 0.0, day.Discretionary)
+	}
+	assert.InDelta(t, 4890, got.Closing, 0.001)
+	assert.Nil(t, got.NegativeDate)
+}
+
+func TestProjectBalances_ignoresFinishedSeries(t *testing.T) {
+	var history []system.Transaction
+	for i := 0; i < 6; i++ {
+		t := system.MockTransaction(int64(i), time.Date(2023, time.Month(1+i), 9, 0, 0, 0, 0, time.UTC), "debit", -219)
+		t.Description = "NETFLIX.COM"
+		history = append(history, t)
+	}
+	from := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	got := system.ProjectBalances(history, 1000, from, 30, system.ForecastConfig{SeasonalWeeks: 1, Recurring: system.DefaultRecurringConfig()})
+
+	assert.Equal(t, 1000.0, got.Closing)
+}
+
+func TestProjectBalances_ignoresHistoryAfterFrom(t *testing.T) {
+	history := []system.Transaction{
+		system.MockTransaction(1, time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC), "debit", -100),
+		system.MockTransaction(2, time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC), "debit", -9000),
+	}
+	from := time.Date(2023, 7, 8, 0, 0, 0, 0, time.UTC)
+
+	got := system.ProjectBalances(history, 1000, from, 7, system.DefaultForecastConfig())
+
+	assert.Equal(t, 900.0, got.Closing)
+}
+
+func TestGetForecast_success(t *testing.T) {
+	var balanceDate time.Time
+	getBalances := func(_ context.Context, _ string, from time.Time, _ time.Time) (system.AccountBalances, error) {
+		balanceDate = from
+		return system.AccountBalances{Closing: 2500}, nil
+	}
+	getForecast := system.MakeGetForecast(system.MockMySQLFindTransactions(nil, nil), getBalances, system.DefaultForecastConfig())
+
+	got, err := getForecast(context.Background(), system.DefaultAccountID, time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC), 0)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), balanceDate)
+	assert.Equal(t, system.DefaultAccountID, got.Account)
+	assert.Len(t, got.Days, system.DefaultForecastDays)
+	assert.Equal(t, 2500.0, got.Opening)
+	assert.Equal(t, 2500.0, got.Closing)
+}
+
+func TestGetForecast_fails(t *testing.T) {
+	tests := []struct {
+		name                  string
+		days                  int
+		getBalances           system.GetBalances
+		mySQLFindTransactions system.MySQLFindTransactions
+		want                  error
+	}{
+		{"negative days", -1, system.MockGetBalances(system.AccountBalances{}, nil), system.MockMySQLFindTransactions(nil, nil), system.ErrInvalidForecastDays},
+		{"too many days", system.MaxForecastDays + 1, system.MockGetBalances(system.AccountBalances{}, nil), system.MockMySQLFindTransactions(nil, nil), system.ErrInvalidForecastDays},
+		{"can't get balances", 30, system.MockGetBalances(system.AccountBalances{}, system.ErrCantGetBalances), system.MockMySQLFindTransactions(nil, nil), system.ErrCantGetForecast},
+		{"can't find transactions", 30, system.MockGetBalances(system.AccountBalances{}, nil), system.MockMySQLFindTransactions(nil, errors.New("connection refused")), system.ErrCantGetForecast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getForecast := system.MakeGetForecast(tt.mySQLFindTransactions, tt.getBalances, system.DefaultForecastConfig())
+
+			_, got := getForecast(context.Background(), system.DefaultAccountID, time.Now(), tt.days)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,23 +1,391 @@
 package system
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const queryDateLayout string = "2006-01-02"
+
 // GetHTMLInfoV1 show the information about the csv balance file in html format
 func GetHTMLInfoV1(htmlProcessTransactions HTMLProcessTransactions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		html, err := htmlProcessTransactions(c)
 		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get info", slog.Any("error", err))
 			WebError(c, http.StatusInternalServerError, CantGetInfo)
+			return
 		}
 
 		c.Writer.WriteHeader(http.StatusOK)
 		_, err = c.Writer.Write(html)
 		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't write html", slog.Any("error", err))
 			WebError(c, http.StatusInternalServerError, CantWriteHtml)
 		}
 	}
 }
+
+// GetAccountBalancesV1 shows the end of day balances of an account between the from and to query dates, the last
+// 30 days when they're missing
+func GetAccountBalancesV1(getBalances GetBalances) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := queryDates(c, 30)
+		if !ok {
+			WebError(c, http.StatusBadRequest, InvalidQueryDates)
+			return
+		}
+
+		balances, err := getBalances(c, c.Param("id"), from, to)
+		if errors.Is(err, ErrInvalidBalanceRange) {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get balances", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetBalances)
+			return
+		}
+
+		c.JSON(http.StatusOK, balances)
+	}
+}
+
+// GetAccountRecurringV1 shows the recurring transactions of an account detected between the from and to query
+// dates, the last 365 days when they're missing
+func GetAccountRecurringV1(getRecurring GetRecurring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := queryDates(c, 365)
+		if !ok {
+			WebError(c, http.StatusBadRequest, InvalidQueryDates)
+			return
+		}
+
+		recurring, err := getRecurring(c, c.Param("id"), from, to)
+		if errors.Is(err, ErrInvalidDateRange) {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get recurring transactions", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetRecurring)
+			return
+		}
+
+		c.JSON(http.StatusOK, recurring)
+	}
+}
+
+// GetAccountAnomaliesV1 shows the unusual transactions and months of an account between the from and to query
+// dates, the last 30 days when they're missing
+func GetAccountAnomaliesV1(getAnomalies GetAnomalies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from, to, ok := queryDates(c, 30)
+		if !ok {
+			WebError(c, http.StatusBadRequest, InvalidQueryDates)
+			return
+		}
+
+		anomalies, err := getAnomalies(c, c.Param("id"), from, to)
+		if errors.Is(err, ErrInvalidDateRange) {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get anomalies", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetAnomalies)
+			return
+		}
+
+		c.JSON(http.StatusOK, anomalies)
+	}
+}
+
+// GetAccountForecastV1 projects the balance of an account for the days query days (the default length of the
+// forecast when it's missing) from the from query date, tomorrow when it's missing
+func GetAccountForecastV1(getForecast GetForecast) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from := time.Now().UTC().AddDate(0, 0, 1)
+		if value := c.Query("from"); value != "" {
+			var err error
+			if from, err = time.Parse(queryDateLayout, value); err != nil {
+				WebError(c, http.StatusBadRequest, InvalidQueryDates)
+				return
+			}
+		}
+		days := 0
+		if value := c.Query("days"); value != "" {
+			var err error
+			if days, err = strconv.Atoi(value); err != nil {
+				WebError(c, http.StatusBadRequest, InvalidQueryDays)
+				return
+			}
+		}
+
+		forecast, err := getForecast(c, c.Param("id"), from, days)
+		if errors.Is(err, ErrInvalidForecastDays) {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get forecast", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetForecast)
+			return
+		}
+
+		c.JSON(http.StatusOK, forecast)
+	}
+}
+
+// GetAccountBudgetsV1 lists the budgets of an account
+func GetAccountBudgetsV1(mySQLListBudgets MySQLListBudgets) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		budgets, err := mySQLListBudgets(c, c.Param("id"))
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't list budgets", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetBudgets)
+			return
+		}
+
+		c.JSON(http.StatusOK, budgets)
+	}
+}
+
+// GetAccountBudgetV1 shows a budget of an account
+func GetAccountBudgetV1(mySQLFindBudget MySQLFindBudget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("budget_id"), 10, 64)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, InvalidBudgetID)
+			return
+		}
+
+		budget, err := mySQLFindBudget(c, c.Param("id"), id)
+		if errors.Is(err, ErrBudgetNotFound) {
+			WebError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't find budget", slog.String("account", c.Param("id")), slog.Int64("id", id), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetBudgets)
+			return
+		}
+
+		c.JSON(http.StatusOK, budget)
+	}
+}
+
+// CreateAccountBudgetV1 creates a budget of an account from the BudgetRequest in the body
+func CreateAccountBudgetV1(mySQLCreateBudget MySQLCreateBudget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request BudgetRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			WebError(c, http.StatusBadRequest, InvalidBudgetBody)
+			return
+		}
+		budget, err := NewBudget(c.Param("id"), request)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		budget, err = mySQLCreateBudget(c, budget)
+		if errors.Is(err, ErrBudgetExists) {
+			WebError(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't create budget", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantSaveBudget)
+			return
+		}
+
+		c.JSON(http.StatusCreated, budget)
+	}
+}
+
+// UpdateAccountBudgetV1 replaces the category, period, amount and rollover of a budget of an account with the
+// BudgetRequest in the body
+func UpdateAccountBudgetV1(mySQLFindBudget MySQLFindBudget, mySQLUpdateBudget MySQLUpdateBudget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("budget_id"), 10, 64)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, InvalidBudgetID)
+			return
+		}
+		var request BudgetRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			WebError(c, http.StatusBadRequest, InvalidBudgetBody)
+			return
+		}
+		changes, err := NewBudget(c.Param("id"), request)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		budget, err := mySQLFindBudget(c, c.Param("id"), id)
+		if errors.Is(err, ErrBudgetNotFound) {
+			WebError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't find budget", slog.String("account", c.Param("id")), slog.Int64("id", id), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantSaveBudget)
+			return
+		}
+
+		budget.Category, budget.Period, budget.Amount, budget.Rollover = changes.Category, changes.Period, changes.Amount, changes.Rollover
+		err = mySQLUpdateBudget(c, budget)
+		if errors.Is(err, ErrBudgetExists) {
+			WebError(c, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't update budget", slog.String("account", c.Param("id")), slog.Int64("id", id), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantSaveBudget)
+			return
+		}
+
+		c.JSON(http.StatusOK, budget)
+	}
+}
+
+// DeleteAccountBudgetV1 deletes a budget of an account
+func DeleteAccountBudgetV1(mySQLDeleteBudget MySQLDeleteBudget) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("budget_id"), 10, 64)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, InvalidBudgetID)
+			return
+		}
+
+		err = mySQLDeleteBudget(c, c.Param("id"), id)
+		if errors.Is(err, ErrBudgetNotFound) {
+			WebError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't delete budget", slog.String("account", c.Param("id")), slog.Int64("id", id), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantSaveBudget)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetAccountBudgetReportV1 compares the budgets of an account with its spending in the periods that contain the date
+// query date, today when it's missing
+func GetAccountBudgetReportV1(getBudgetReport GetBudgetReport) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		date := time.Now().UTC()
+		if value := c.Query("date"); value != "" {
+			var err error
+			if date, err = time.Parse(queryDateLayout, value); err != nil {
+				WebError(c, http.StatusBadRequest, InvalidQueryDate)
+				return
+			}
+		}
+
+		statuses, err := getBudgetReport(c, c.Param("id"), date)
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't get budget report", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetBudgets)
+			return
+		}
+
+		c.JSON(http.StatusOK, statuses)
+	}
+}
+
+// GetAccountStatementsV1 lists the statements issued to an account, the latest first
+func GetAccountStatementsV1(mySQLListStatements MySQLListStatements) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statements, err := mySQLListStatements(c, c.Param("id"))
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't list statements", slog.String("account", c.Param("id")), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetStatements)
+			return
+		}
+
+		c.JSON(http.StatusOK, statements)
+	}
+}
+
+// GetAccountStatementV1 shows a statement exactly as it was issued to the account
+func GetAccountStatementV1(mySQLFindStatement MySQLFindStatement) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("statement_id"), 10, 64)
+		if err != nil {
+			WebError(c, http.StatusBadRequest, InvalidStatementID)
+			return
+		}
+
+		statement, err := mySQLFindStatement(c, c.Param("id"), id)
+		if errors.Is(err, ErrStatementNotFound) {
+			WebError(c, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			LoggerFrom(c).ErrorContext(c, "can't find statement", slog.String("account", c.Param("id")), slog.Int64("id", id), slog.Any("error", err))
+			WebError(c, http.StatusInternalServerError, CantGetStatements)
+			return
+		}
+
+		c.JSON(http.StatusOK, statement)
+	}
+}
+
+// GetHealthLiveV1 reports that the process is up and able to serve requests
+func GetHealthLiveV1() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, HealthStatus{Status: statusOK})
+	}
+}
+
+// GetHealthReadyV1 reports whether the service dependencies are available
+func GetHealthReadyV1(readiness *Readiness) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, ready := readiness.Check(c)
+		if !ready {
+			LoggerFrom(c).WarnContext(c, "service not ready", slog.String("status", status.Status), slog.Any("checks", status.Checks))
+			c.JSON(http.StatusServiceUnavailable, status)
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// GetSystemInfoV1 shows the version, commit and build time of the running binary
+func GetSystemInfoV1(info BuildInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, info)
+	}
+}
+
+// queryDates parses the from and to query dates. to defaults to today and from to the given days before to
+func queryDates(c *gin.Context, days int) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if value := c.Query("to"); value != "" {
+		var err error
+		if to, err = time.Parse(queryDateLayout, value); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	from := to.AddDate(0, 0, -days)
+	if value := c.Query("from"); value != "" {
+		var err error
+		if from, err = time.Parse(queryDateLayout, value); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	return from, to, true
+}
@@ -3,7 +3,9 @@ package system_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -34,3 +36,452 @@ func TestHTTPHandler_GetHTMLInfoV1_fails(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestHTTPHandler_GetAccountBalancesV1_success(t *testing.T) {
+	getAccountBalancesV1 := system.GetAccountBalancesV1(system.MockGetBalances(system.AccountBalances{Account: "1234"}, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "1234"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/1234/balances/v1?from=2023-12-01&to=2023-12-31", nil)
+
+	getAccountBalancesV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"account":"1234"`)
+}
+
+func TestHTTPHandler_GetAccountBalancesV1_fails(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		getBalances system.GetBalances
+		want        int
+	}{
+		{"invalid date", "?from=01/12/2023", system.MockGetBalances(system.AccountBalances{}, nil), http.StatusBadRequest},
+		{"invalid range", "?from=2023-12-31&to=2023-12-01", system.MockGetBalances(system.AccountBalances{}, system.ErrInvalidBalanceRange), http.StatusBadRequest},
+		{"can't get balances", "", system.MockGetBalances(system.AccountBalances{}, system.ErrCantGetBalances), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountBalancesV1 := system.GetAccountBalancesV1(tt.getBalances)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "1234"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/1234/balances/v1"+tt.query, nil)
+
+			getAccountBalancesV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetAccountRecurringV1_success(t *testing.T) {
+	getAccountRecurringV1 := system.GetAccountRecurringV1(system.MockGetRecurring([]system.Recurring{{Name: "NETFLIX.COM", Cadence: system.CadenceMonthly}}, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/recurring/v1", nil)
+
+	getAccountRecurringV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"cadence":"monthly"`)
+}
+
+func TestHTTPHandler_GetAccountRecurringV1_fails(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		getRecurring system.GetRecurring
+		want         int
+	}{
+		{"invalid date", "?to=yesterday", system.MockGetRecurring(nil, nil), http.StatusBadRequest},
+		{"invalid range", "?from=2023-12-31&to=2023-12-01", system.MockGetRecurring(nil, system.ErrInvalidDateRange), http.StatusBadRequest},
+		{"can't get recurring", "", system.MockGetRecurring(nil, system.ErrCantGetRecurring), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountRecurringV1 := system.GetAccountRecurringV1(tt.getRecurring)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "default"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/recurring/v1"+tt.query, nil)
+
+			getAccountRecurringV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetAccountAnomaliesV1_success(t *testing.T) {
+	anomalies := system.Anomalies{Account: "default", Transactions: []system.Anomaly{{ID: 7, Amount: -4500, Reasons: []string{system.AnomalyLargeDebit}}}}
+	getAccountAnomaliesV1 := system.GetAccountAnomaliesV1(system.MockGetAnomalies(anomalies, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/anomalies/v1?from=2023-12-01&to=2023-12-31", nil)
+
+	getAccountAnomaliesV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"reasons":["large_debit"]`)
+}
+
+func TestHTTPHandler_GetAccountAnomaliesV1_fails(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		getAnomalies system.GetAnomalies
+		want         int
+	}{
+		{"invalid date", "?from=01/12/2023", system.MockGetAnomalies(system.Anomalies{}, nil), http.StatusBadRequest},
+		{"invalid range", "?from=2023-12-31&to=2023-12-01", system.MockGetAnomalies(system.Anomalies{}, system.ErrInvalidDateRange), http.StatusBadRequest},
+		{"can't get anomalies", "", system.MockGetAnomalies(system.Anomalies{}, system.ErrCantGetAnomalies), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountAnomaliesV1 := system.GetAccountAnomaliesV1(tt.getAnomalies)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "default"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/anomalies/v1"+tt.query, nil)
+
+			getAccountAnomaliesV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetAccountForecastV1_success(t *testing.T) {
+	forecast := system.Forecast{Account: "default", Opening: 1000, Closing: 1200}
+	getAccountForecastV1 := system.GetAccountForecastV1(system.MockGetForecast(forecast, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/forecast/v1?from=2024-01-01&days=60", nil)
+
+	getAccountForecastV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"closing":1200`)
+}
+
+func TestHTTPHandler_GetAccountForecastV1_fails(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		getForecast system.GetForecast
+		want        int
+	}{
+		{"invalid date", "?from=tomorrow", system.MockGetForecast(system.Forecast{}, nil), http.StatusBadRequest},
+		{"invalid days", "?days=month", system.MockGetForecast(system.Forecast{}, nil), http.StatusBadRequest},
+		{"too many days", "?days=1000", system.MockGetForecast(system.Forecast{}, system.ErrInvalidForecastDays), http.StatusBadRequest},
+		{"can't get forecast", "", system.MockGetForecast(system.Forecast{}, system.ErrCantGetForecast), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountForecastV1 := system.GetAccountForecastV1(tt.getForecast)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "default"}}
+			c.Request = httptest.NewRequest(http.MethodGet, "/system/accounts/default/forecast/v1"+tt.query, nil)
+
+			getAccountForecastV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func budgetContext(method string, path string, body string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = append(gin.Params{{Key: "id", Value: "default"}}, params...)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestHTTPHandler_GetAccountBudgetsV1_success(t *testing.T) {
+	getAccountBudgetsV1 := system.GetAccountBudgetsV1(system.MockMySQLListBudgets([]system.Budget{{ID: 3, Category: "groceries"}}, nil))
+	c, w := budgetContext(http.MethodGet, "/system/accounts/default/budgets/v1", "", nil)
+
+	getAccountBudgetsV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"category":"groceries"`)
+}
+
+func TestHTTPHandler_GetAccountBudgetV1_fails(t *testing.T) {
+	tests := []struct {
+		name            string
+		budgetID        string
+		mySQLFindBudget system.MySQLFindBudget
+		want            int
+	}{
+		{"invalid id", "groceries", system.MockMySQLFindBudget(system.Budget{}, nil), http.StatusBadRequest},
+		{"not found", "3", system.MockMySQLFindBudget(system.Budget{}, system.ErrBudgetNotFound), http.StatusNotFound},
+		{"can't find budget", "3", system.MockMySQLFindBudget(system.Budget{}, system.ErrCantRunQuery), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountBudgetV1 := system.GetAccountBudgetV1(tt.mySQLFindBudget)
+			c, w := budgetContext(http.MethodGet, "/system/accounts/default/budgets/v1/"+tt.budgetID, "", gin.Params{{Key: "budget_id", Value: tt.budgetID}})
+
+			getAccountBudgetV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_CreateAccountBudgetV1_success(t *testing.T) {
+	createAccountBudgetV1 := system.CreateAccountBudgetV1(system.MockMySQLCreateBudget(3, nil))
+	c, w := budgetContext(http.MethodPost, "/system/accounts/default/budgets/v1", `{"category":"groceries","amount":4000,"rollover":true}`, nil)
+
+	createAccountBudgetV1(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":3`)
+	assert.Contains(t, w.Body.String(), `"period":"month"`)
+}
+
+func TestHTTPHandler_CreateAccountBudgetV1_fails(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		mySQLCreateBudget system.MySQLCreateBudget
+		want              int
+	}{
+		{"invalid body", `{"category":`, system.MockMySQLCreateBudget(3, nil), http.StatusBadRequest},
+		{"invalid budget", `{"category":"groceries","amount":0}`, system.MockMySQLCreateBudget(3, nil), http.StatusBadRequest},
+		{"budget exists", `{"category":"groceries","amount":4000}`, system.MockMySQLCreateBudget(0, system.ErrBudgetExists), http.StatusConflict},
+		{"can't create budget", `{"category":"groceries","amount":4000}`, system.MockMySQLCreateBudget(0, system.ErrCantRunQuery), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createAccountBudgetV1 := system.CreateAccountBudgetV1(tt.mySQLCreateBudget)
+			c, w := budgetContext(http.MethodPost, "/system/accounts/default/budgets/v1", tt.body, nil)
+
+			createAccountBudgetV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_UpdateAccountBudgetV1_success(t *testing.T) {
+	budget := system.Budget{ID: 3, Account: "default", Category: "groceries", Period: system.GroupByMonth, Amount: 4000}
+	updateAccountBudgetV1 := system.UpdateAccountBudgetV1(system.MockMySQLFindBudget(budget, nil), system.MockMySQLUpdateBudget(nil))
+	c, w := budgetContext(http.MethodPut, "/system/accounts/default/budgets/v1/3", `{"category":"groceries","period":"week","amount":1000}`, gin.Params{{Key: "budget_id", Value: "3"}})
+
+	updateAccountBudgetV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"period":"week","amount":1000`)
+}
+
+func TestHTTPHandler_UpdateAccountBudgetV1_fails(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		mySQLFindBudget   system.MySQLFindBudget
+		mySQLUpdateBudget system.MySQLUpdateBudget
+		want              int
+	}{
+		{"invalid budget", `{"category":"groceries","amount":-1}`, system.MockMySQLFindBudget(system.Budget{}, nil), system.MockMySQLUpdateBudget(nil), http.StatusBadRequest},
+		{"not found", `{"category":"groceries","amount":1000}`, system.MockMySQLFindBudget(system.Budget{}, system.ErrBudgetNotFound), system.MockMySQLUpdateBudget(nil), http.StatusNotFound},
+		{"budget exists", `{"category":"dining","amount":1000}`, system.MockMySQLFindBudget(system.Budget{}, nil), system.MockMySQLUpdateBudget(system.ErrBudgetExists), http.StatusConflict},
+		{"can't update budget", `{"category":"groceries","amount":1000}`, system.MockMySQLFindBudget(system.Budget{}, nil), system.MockMySQLUpdateBudget(system.ErrCantRunQuery), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updateAccountBudgetV1 := system.UpdateAccountBudgetV1(tt.mySQLFindBudget, tt.mySQLUpdateBudget)
+			c, w := budgetContext(http.MethodPut, "/system/accounts/default/budgets/v1/3", tt.body, gin.Params{{Key: "budget_id", Value: "3"}})
+
+			updateAccountBudgetV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_DeleteAccountBudgetV1(t *testing.T) {
+	tests := []struct {
+		name              string
+		mySQLDeleteBudget system.MySQLDeleteBudget
+		want              int
+	}{
+		{"deleted", system.MockMySQLDeleteBudget(nil), http.StatusNoContent},
+		{"not found", system.MockMySQLDeleteBudget(system.ErrBudgetNotFound), http.StatusNotFound},
+		{"can't delete budget", system.MockMySQLDeleteBudget(system.ErrCantRunQuery), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleteAccountBudgetV1 := system.DeleteAccountBudgetV1(tt.mySQLDeleteBudget)
+			c, w := budgetContext(http.MethodDelete, "/system/accounts/default/budgets/v1/3", "", gin.Params{{Key: "budget_id", Value: "3"}})
+
+			deleteAccountBudgetV1(c)
+			c.Writer.WriteHeaderNow()
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetAccountBudgetReportV1(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		getBudgetReport system.GetBudgetReport
+		want            int
+	}{
+		{"report", "?date=2023-12-12", system.MockGetBudgetReport([]system.BudgetStatus{{BudgetID: 3, PercentUsed: 82.5}}, nil), http.StatusOK},
+		{"invalid date", "?date=12/12/2023", system.MockGetBudgetReport(nil, nil), http.StatusBadRequest},
+		{"can't get report", "", system.MockGetBudgetReport(nil, system.ErrCantGetBudgetReport), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountBudgetReportV1 := system.GetAccountBudgetReportV1(tt.getBudgetReport)
+			c, w := budgetContext(http.MethodGet, "/system/accounts/default/budgets/report/v1"+tt.query, "", nil)
+
+			getAccountBudgetReportV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetAccountStatementsV1_success(t *testing.T) {
+	getAccountStatementsV1 := system.GetAccountStatementsV1(system.MockMySQLListStatements([]system.Statement{{ID: 7}}, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}}
+
+	getAccountStatementsV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":7`)
+}
+
+func TestHTTPHandler_GetAccountStatementsV1_fails(t *testing.T) {
+	getAccountStatementsV1 := system.GetAccountStatementsV1(system.MockMySQLListStatements(nil, system.ErrCantRunQuery))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}}
+
+	getAccountStatementsV1(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHTTPHandler_GetAccountStatementV1_success(t *testing.T) {
+	getAccountStatementV1 := system.GetAccountStatementV1(system.MockMySQLFindStatement(system.Statement{ID: 7, Summary: system.Email{Balance: 264.7}}, nil))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "default"}, {Key: "statement_id", Value: "7"}}
+
+	getAccountStatementV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"balance":264.7`)
+}
+
+func TestHTTPHandler_GetAccountStatementV1_fails(t *testing.T) {
+	tests := []struct {
+		name               string
+		statementID        string
+		mySQLFindStatement system.MySQLFindStatement
+		want               int
+	}{
+		{"invalid id", "seven", system.MockMySQLFindStatement(system.Statement{}, nil), http.StatusBadRequest},
+		{"not found", "7", system.MockMySQLFindStatement(system.Statement{}, system.ErrStatementNotFound), http.StatusNotFound},
+		{"can't find statement", "7", system.MockMySQLFindStatement(system.Statement{}, system.ErrCantRunQuery), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getAccountStatementV1 := system.GetAccountStatementV1(tt.mySQLFindStatement)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Params = gin.Params{{Key: "id", Value: "default"}, {Key: "statement_id", Value: tt.statementID}}
+
+			getAccountStatementV1(c)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestHTTPHandler_GetHealthLiveV1_success(t *testing.T) {
+	getHealthLiveV1 := system.GetHealthLiveV1()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	getHealthLiveV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHTTPHandler_GetHealthReadyV1_success(t *testing.T) {
+	readiness := system.NewReadiness(time.Second, map[string]system.HealthCheck{"database": system.MockHealthCheck(nil)})
+	getHealthReadyV1 := system.GetHealthReadyV1(readiness)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+
+	getHealthReadyV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHTTPHandler_GetHealthReadyV1_fails(t *testing.T) {
+	readiness := system.NewReadiness(time.Second, map[string]system.HealthCheck{"database": system.MockHealthCheck(system.ErrCantPingDB)})
+	getHealthReadyV1 := system.GetHealthReadyV1(readiness)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+
+	getHealthReadyV1(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestHTTPHandler_GetSystemInfoV1_success(t *testing.T) {
+	getSystemInfoV1 := system.GetSystemInfoV1(system.BuildInfo{Version: "1.0.0", Commit: "abc123", BuildTime: "2023-06-04T00:00:00Z"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	getSystemInfoV1(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"version":"1.0.0","commit":"abc123","build_time":"2023-06-04T00:00:00Z"}`, w.Body.String())
+}
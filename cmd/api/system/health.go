@@ -0,0 +1,113 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statusOK           string = "ok"
+	statusFailing      string = "failing"
+	statusShuttingDown string = "shutting down"
+)
+
+type (
+	// HealthCheck is a function that verifies that a dependency of the service is available
+	HealthCheck func(ctx context.Context) error
+
+	// HealthStatus is the body returned by the health endpoints
+	HealthStatus struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}
+
+	// BuildInfo describes the running binary. Its values are injected at link time
+	BuildInfo struct {
+		Version   string `json:"version"`
+		Commit    string `json:"commit"`
+		BuildTime string `json:"build_time"`
+	}
+
+	// Readiness runs the checks that must pass for the service to receive traffic
+	Readiness struct {
+		checks       map[string]HealthCheck
+		timeout      time.Duration
+		shuttingDown atomic.Bool
+	}
+)
+
+// NewReadiness creates a Readiness that runs every check with the given timeout
+func NewReadiness(timeout time.Duration, checks map[string]HealthCheck) *Readiness {
+	return &Readiness{
+		checks:  checks,
+		timeout: timeout,
+	}
+}
+
+// SetShuttingDown makes every following readiness check fail, so no new traffic is routed to the service
+func (r *Readiness) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs all the checks and reports whether the service is ready
+func (r *Readiness) Check(ctx context.Context) (HealthStatus, bool) {
+	if r.shuttingDown.Load() {
+		return HealthStatus{Status: statusShuttingDown}, false
+	}
+
+	names := make([]string, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ready := true
+	status := HealthStatus{Status: statusOK, Checks: make(map[string]string, len(names))}
+	for _, name := range names {
+		checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		err := r.checks[name](checkCtx)
+		cancel()
+
+		status.Checks[name] = statusOK
+		if err != nil {
+			ready = false
+			status.Checks[name] = err.Error()
+		}
+	}
+
+	if !ready {
+		status.Status = statusFailing
+	}
+
+	return status, ready
+}
+
+// MakeMySQLPing creates a HealthCheck that pings the database
+func MakeMySQLPing(db *sql.DB) HealthCheck {
+	return func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't ping database", slog.Any("error", err))
+			return ErrCantPingDB
+		}
+
+		return nil
+	}
+}
+
+// MakeFileCheck creates a HealthCheck that verifies a file can be opened for reading
+func MakeFileCheck(filename string) HealthCheck {
+	return func(ctx context.Context) error {
+		f, err := os.Open(filename)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read file", slog.String("filename", filename), slog.Any("error", err))
+			return ErrCantReadFile
+		}
+
+		return f.Close()
+	}
+}
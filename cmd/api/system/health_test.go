@@ -0,0 +1,100 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestReadiness_Check_success(t *testing.T) {
+	readiness := system.NewReadiness(time.Second, map[string]system.HealthCheck{
+		"database": system.MockHealthCheck(nil),
+		"template": system.MockHealthCheck(nil),
+	})
+
+	want := system.HealthStatus{Status: "ok", Checks: map[string]string{"database": "ok", "template": "ok"}}
+	got, ready := readiness.Check(context.Background())
+
+	assert.True(t, ready)
+	assert.Equal(t, want, got)
+}
+
+func TestReadiness_Check_failsWhenACheckFails(t *testing.T) {
+	readiness := system.NewReadiness(time.Second, map[string]system.HealthCheck{
+		"database": system.MockHealthCheck(system.ErrCantPingDB),
+		"template": system.MockHealthCheck(nil),
+	})
+
+	want := system.HealthStatus{Status: "failing", Checks: map[string]string{"database": "can't ping database", "template": "ok"}}
+	got, ready := readiness.Check(context.Background())
+
+	assert.False(t, ready)
+	assert.Equal(t, want, got)
+}
+
+func TestReadiness_Check_failsWhenShuttingDown(t *testing.T) {
+	readiness := system.NewReadiness(time.Second, map[string]system.HealthCheck{
+		"database": system.MockHealthCheck(nil),
+	})
+	readiness.SetShuttingDown()
+
+	want := system.HealthStatus{Status: "shutting down"}
+	got, ready := readiness.Check(context.Background())
+
+	assert.False(t, ready)
+	assert.Equal(t, want, got)
+}
+
+func TestReadiness_Check_appliesTimeoutToEachCheck(t *testing.T) {
+	slowCheck := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	readiness := system.NewReadiness(10*time.Millisecond, map[string]system.HealthCheck{"slow": slowCheck})
+
+	got, ready := readiness.Check(context.Background())
+
+	assert.False(t, ready)
+	assert.Equal(t, context.DeadlineExceeded.Error(), got.Checks["slow"])
+}
+
+func TestMySQLPing_success(t *testing.T) {
+	db, mock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	mock.ExpectPing()
+
+	got := system.MakeMySQLPing(db)(context.Background())
+
+	assert.Nil(t, got)
+}
+
+func TestMySQLPing_failsWhenCantPingDB(t *testing.T) {
+	db, mock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	mock.ExpectPing().WillReturnError(errors.New("some error"))
+
+	want := system.ErrCantPingDB
+	got := system.MakeMySQLPing(db)(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestFileCheck_success(t *testing.T) {
+	filename := filepath.Join("data", system.DataFile)
+
+	got := system.MakeFileCheck(filename)(context.Background())
+
+	assert.Nil(t, got)
+}
+
+func TestFileCheck_failsWhenCantReadFile(t *testing.T) {
+	want := system.ErrCantReadFile
+	got := system.MakeFileCheck("")(context.Background())
+
+	assert.Equal(t, want, got)
+}
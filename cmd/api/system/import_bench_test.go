@@ -0,0 +1,103 @@
+package system_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+// writeGeneratedCSV writes a CSV file in the default profile with the given number of rows
+func writeGeneratedCSV(tb testing.TB, rows int) string {
+	tb.Helper()
+
+	filename := filepath.Join(tb.TempDir(), fmt.Sprintf("generated_%d.csv", rows))
+	file, err := os.Create(filename)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	_, _ = w.WriteString("Id,Date,Amount\n")
+	for i := 0; i < rows; i++ {
+		amount := float64(i%1000) + 0.25
+		if i%3 == 0 {
+			amount = -amount
+		}
+		_, _ = fmt.Fprintf(w, "%d,%d/%d,%.2f\n", i, i%28+1, i%12+1, amount)
+	}
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+
+	return filename
+}
+
+// peakHeap samples the heap in use until stop is called and returns the highest value seen
+func peakHeap() (stop func() uint64) {
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var stats runtime.MemStats
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapInuse > peak.Load() {
+				peak.Store(stats.HeapInuse)
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+		wg.Wait()
+		return peak.Load()
+	}
+}
+
+// BenchmarkImportStatement imports generated files of growing size. The peak-heap-MB metric stays flat because
+// only one batch of transactions is in memory at a time. Run with
+// go test ./cmd/api/system -run ^$ -bench ImportStatement -benchtime 1x
+func BenchmarkImportStatement(b *testing.B) {
+	for _, rows := range []int{10_000, 100_000, 1_000_000, 10_000_000} {
+		b.Run(fmt.Sprintf("rows=%d", rows), func(b *testing.B) {
+			filename := writeGeneratedCSV(b, rows)
+			streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+			readStatement := system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+			importStatement := system.MakeImportStatement(readStatement, system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+			opts := system.MockImportOptions()
+
+			runtime.GC()
+			stop := peakHeap()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				summary, err := importStatement(context.Background(), filename, opts)
+				if err != nil || summary.Count != rows {
+					b.Fatalf("imported %d of %d rows: %v", summary.Count, rows, err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(stop())/(1<<20), "peak-heap-MB")
+			b.ReportMetric(float64(rows*b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package system
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLocale is the locale of the accounts that don't define one
+	DefaultLocale string = "en"
+
+	// categoryMessagePrefix prefixes the name of a category in the key of its message
+	categoryMessagePrefix string = "category_"
+)
+
+//go:embed locales/*.json
+var embeddedLocales embed.FS
+
+type (
+	// Catalog holds the messages of the statement in a locale and how numbers, months and dates are written in it.
+	// Messages are fmt formats; the date message gets the day, the month name and the year, and the month message
+	// the month name and the year
+	Catalog struct {
+		Locale   string            `json:"-"`
+		Decimal  string            `json:"decimal"`
+		Group    string            `json:"group"`
+		Months   []string          `json:"months"`
+		Messages map[string]string `json:"messages"`
+	}
+
+	// Localizer resolves the catalog of each account from its locale
+	Localizer struct {
+		Catalogs map[string]Catalog
+		Default  string
+		Accounts map[string]string
+	}
+)
+
+// EmbeddedCatalogs returns the catalogs built into the binary
+func EmbeddedCatalogs() fs.FS {
+	catalogs, _ := fs.Sub(embeddedLocales, "locales")
+	return catalogs
+}
+
+// LoadCatalogs reads the catalogs of a folder, one <locale>.json file per locale, and checks that all of them
+// translate the same messages
+func LoadCatalogs(dir fs.FS) (map[string]Catalog, error) {
+	filenames, err := fs.Glob(dir, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	catalogs := make(map[string]Catalog, len(filenames))
+	for _, filename := range filenames {
+		content, err := fs.ReadFile(dir, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		var catalog Catalog
+		if err := json.Unmarshal(content, &catalog); err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", path.Base(filename), err)
+		}
+		catalog.Locale = strings.TrimSuffix(path.Base(filename), ".json")
+		catalogs[catalog.Locale] = catalog
+	}
+
+	if err := CheckCatalogs(catalogs); err != nil {
+		return nil, err
+	}
+
+	return catalogs, nil
+}
+
+// CheckCatalogs returns an error naming the missing translations when a catalog lacks a message another catalog
+// has or the category_<name> message of one of the categories, and when a catalog doesn't define its separators or
+// the twelve months
+func CheckCatalogs(catalogs map[string]Catalog, categories ...string) error {
+	keys := make(map[string]bool)
+	for _, catalog := range catalogs {
+		for key := range catalog.Messages {
+			keys[key] = true
+		}
+	}
+	for _, category := range categories {
+		keys[categoryMessagePrefix+category] = true
+	}
+
+	var problems []string
+	for locale, catalog := range catalogs {
+		if catalog.Decimal == "" || catalog.Group == "" || catalog.Decimal == catalog.Group {
+			problems = append(problems, fmt.Sprintf("%s: invalid decimal and group separators", locale))
+		}
+		if len(catalog.Months) != 12 {
+			problems = append(problems, fmt.Sprintf("%s: expected 12 months, got %d", locale, len(catalog.Months)))
+		}
+		for key := range keys {
+			if _, ok := catalog.Messages[key]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing translation of %q", locale, key))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%w: %s", ErrIncompleteCatalog, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Validate checks there is a catalog for the default locale and the locale of every account
+func (l Localizer) Validate() error {
+	if _, ok := l.Catalogs[l.Default]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownLocale, l.Default)
+	}
+	for account, locale := range l.Accounts {
+		if _, ok := l.Catalogs[locale]; !ok {
+			return fmt.Errorf("%w: %s of account %s", ErrUnknownLocale, locale, account)
+		}
+	}
+
+	return nil
+}
+
+// For returns the catalog of the locale of the account, or of the default locale if the account doesn't define it
+func (l Localizer) For(accountID string) Catalog {
+	if locale, ok := l.Accounts[accountID]; ok {
+		return l.Catalogs[locale]
+	}
+	return l.Catalogs[l.Default]
+}
+
+// Translate formats the message with the arguments. Messages missing from the catalog are an error, never a
+// fallback to another locale
+func (c Catalog) Translate(key string, args ...any) (string, error) {
+	message, ok := c.Messages[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %q in locale %q", ErrMissingTranslation, key, c.Locale)
+	}
+	return fmt.Sprintf(message, args...), nil
+}
+
+// FormatNumber writes the value with the given decimals and the separators of the locale, e.g. 1.234,56 in es
+func (c Catalog) FormatNumber(value float64, decimals int) string {
+	digits := strconv.FormatFloat(math.Abs(value), 'f', decimals, 64)
+	integer, fraction, _ := strings.Cut(digits, ".")
+
+	var number strings.Builder
+	if value < 0 && strings.Trim(digits, "0.") != "" {
+		number.WriteString("-")
+	}
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			number.WriteString(c.Group)
+		}
+		number.WriteRune(digit)
+	}
+	if fraction != "" {
+		number.WriteString(c.Decimal)
+		number.WriteString(fraction)
+	}
+
+	return number.String()
+}
+
+// FormatMoney writes the amount with the symbol and the minor units of its currency and the separators of the
+// locale, e.g. $1.234,56 for MXN in es or -¥1,235 for JPY in en
+func (c Catalog) FormatMoney(amount float64, code string) string {
+	symbol, scale := currencyFormat(code)
+	number := c.FormatNumber(amount, scale)
+	if negative, ok := strings.CutPrefix(number, "-"); ok {
+		return "-" + symbol + negative
+	}
+	return symbol + number
+}
+
+// MonthName returns the name of the month of t
+func (c Catalog) MonthName(t time.Time) string {
+	if len(c.Months) != 12 {
+		return t.Month().String()
+	}
+	return c.Months[t.Month()-1]
+}
+
+// FormatDate writes the calendar date of t with the month name, e.g. 10 de enero de 2024 in es
+func (c Catalog) FormatDate(t time.Time) (string, error) {
+	return c.Translate("date", t.Day(), c.MonthName(t), t.Year())
+}
+
+// FormatPeriod writes the key of a period with the month name when it is a month, e.g. diciembre de 2023 in es for
+// 2023-12. Weeks, quarters and years are written as their key
+func (c Catalog) FormatPeriod(key string) (string, error) {
+	month, err := time.Parse("2006-01", key)
+	if err != nil {
+		return key, nil
+	}
+	return c.Translate("month", c.MonthName(month), month.Year())
+}
+
+// Category returns the category_<name> message of the category, or the name itself for categories created at
+// runtime, such as the ones of budgets, which are user-facing labels
+func (c Catalog) Category(name string) string {
+	if message, ok := c.Messages[categoryMessagePrefix+name]; ok {
+		return message
+	}
+	return name
+}
+
+// Funcs returns the template functions of the catalog:
+//   - t translates a message with its arguments
+//   - category translates the name of a category
+//   - money, number, month, date and period format values the way the locale writes them
+//   - locale returns the name of the locale
+func (c Catalog) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"t":        c.Translate,
+		"category": c.Category,
+		"money":    c.FormatMoney,
+		"number":   c.FormatNumber,
+		"month":    c.MonthName,
+		"date":     c.FormatDate,
+		"period":   c.FormatPeriod,
+		"locale":   func() string { return c.Locale },
+	}
+}
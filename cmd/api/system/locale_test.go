@@ -0,0 +1,179 @@
+package system_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func loadCatalogs(t *testing.T) map[string]system.Catalog {
+	catalogs, err := system.LoadCatalogs(system.EmbeddedCatalogs())
+	assert.Nil(t, err)
+	return catalogs
+}
+
+// mockFullEmail returns an email that fills every section of the template
+func mockFullEmail() system.Email {
+	date := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	return system.Email{
+		Currency:       "MXN",
+		Balance:        1234.56,
+		OpeningBalance: 1000,
+		ClosingBalance: 2234.56,
+		AverageDebit:   -300,
+		AverageCredit:  900,
+		Currencies: []system.CurrencyTotal{
+			{Currency: "MXN", Count: 3, Total: 1334.56, Converted: 1334.56},
+			{Currency: "USD", Count: 1, Total: -10, Unconverted: 1},
+			{Currency: "EUR", Count: 1, Total: -5, Converted: -100},
+		},
+		Periods:  []system.Period{{Key: "2023-12", Count: 5, Credit: 1800, Debit: -565.44, Net: 1234.56, Average: 246.91}},
+		Spending: []system.CategorySpend{{Category: "groceries", Amount: 565.44}},
+		Recurring: []system.Recurring{
+			{Name: "NETFLIX.COM", Amount: -219, Cadence: system.CadenceWeekly, NextDate: date},
+			{Name: "NETFLIX.COM", Amount: -219, Cadence: system.CadenceBiweekly, NextDate: date},
+			{Name: "NETFLIX.COM", Amount: -219, Cadence: system.CadenceMonthly, NextDate: date},
+			{Name: "NETFLIX.COM", Amount: -219, Cadence: system.CadenceQuarterly, NextDate: date},
+			{Name: "NETFLIX.COM", Amount: -219, Cadence: system.CadenceYearly, NextDate: date},
+		},
+		Anomalies:   []system.Anomaly{{Date: date, Name: "Liverpool", Amount: -4500, Typical: -800, Reasons: []string{system.AnomalyLargeDebit, system.AnomalyNewMerchant}}},
+		CountSpikes: []system.CountSpike{{Key: "2023-12", Count: 40, Typical: 12}},
+		Forecast:    &system.Forecast{To: date, Closing: 2300, Lowest: -6200, LowestDate: date, NegativeDate: &date},
+		Budgets:     []system.BudgetStatus{{Category: "groceries", Key: "2023-12", Budget: 4000, Actual: 3300, Remaining: 700, PercentUsed: 82.5, Alert: 80}},
+	}
+}
+
+func TestLoadCatalogs_translatesTheTemplateInEveryLocale(t *testing.T) {
+	catalogs := loadCatalogs(t)
+	templates := system.MockTemplateRegistry()
+
+	assert.Contains(t, catalogs, system.DefaultLocale)
+	for locale := range catalogs {
+		t.Run(locale, func(t *testing.T) {
+			var got bytes.Buffer
+			err := templates.Render(context.Background(), &got, system.AccountInfoTemplate, locale, mockFullEmail())
+
+			assert.Nil(t, err)
+			assert.NotContains(t, got.String(), "%!")
+		})
+	}
+}
+
+func TestLoadCatalogs_failsWhenATranslationIsMissing(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"decimal": ".", "group": ",", "months": ["1","2","3","4","5","6","7","8","9","10","11","12"], "messages": {"title": "Account Info", "thanks": "Thanks,"}}`), 0o600)
+	_ = os.WriteFile(filepath.Join(dir, "es.json"), []byte(`{"decimal": ",", "group": ".", "months": ["1","2","3","4","5","6","7","8","9","10","11","12"], "messages": {"title": "Información de la cuenta"}}`), 0o600)
+
+	_, err := system.LoadCatalogs(os.DirFS(dir))
+
+	assert.ErrorIs(t, err, system.ErrIncompleteCatalog)
+	assert.ErrorContains(t, err, `es: missing translation of "thanks"`)
+}
+
+func TestCheckCatalogs_failsWhenACategoryIsntTranslated(t *testing.T) {
+	catalogs := loadCatalogs(t)
+
+	err := system.CheckCatalogs(catalogs, "groceries", "pets")
+
+	assert.ErrorIs(t, err, system.ErrIncompleteCatalog)
+	assert.ErrorContains(t, err, `en: missing translation of "category_pets"`)
+	assert.ErrorContains(t, err, `es: missing translation of "category_pets"`)
+	assert.NotContains(t, err.Error(), "category_groceries")
+}
+
+func TestCatalog_Category(t *testing.T) {
+	catalog := loadCatalogs(t)["es"]
+
+	assert.Equal(t, "Supermercado", catalog.Category("groceries"))
+	assert.Equal(t, "mascotas", catalog.Category("mascotas"))
+}
+
+func TestLoadCatalogs_translatesTheCategoriesOfTheEmail(t *testing.T) {
+	templates := system.MockTemplateRegistry()
+
+	var got bytes.Buffer
+	err := templates.Render(context.Background(), &got, system.AccountInfoTemplate, "es", mockFullEmail())
+
+	assert.Nil(t, err)
+	assert.Contains(t, got.String(), "Supermercado (diciembre de 2023)")
+	assert.NotContains(t, got.String(), "groceries")
+}
+
+func TestCatalog_Translate_failsWhenMessageIsMissing(t *testing.T) {
+	catalog := loadCatalogs(t)["es"]
+
+	_, err := catalog.Translate("farewell")
+
+	assert.ErrorIs(t, err, system.ErrMissingTranslation)
+}
+
+func TestCatalog_FormatMoney(t *testing.T) {
+	catalogs := loadCatalogs(t)
+	tests := []struct {
+		locale string
+		amount float64
+		code   string
+		want   string
+	}{
+		{"es", 1234.56, "MXN", "$1.234,56"},
+		{"es", -219, "MXN", "-$219,00"},
+		{"es", 1234567.891, "", "$1.234.567,89"},
+		{"en", 1234.56, "MXN", "$1,234.56"},
+		{"en", 1234.5, "EUR", "€1,234.50"},
+		{"en", -1234.6, "JPY", "-¥1,235"},
+		{"en", 12.3456, "BHD", "BHD 12.346"},
+		{"en", -0.001, "USD", "$0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, catalogs[tt.locale].FormatMoney(tt.amount, tt.code))
+		})
+	}
+}
+
+func TestCatalog_FormatDate(t *testing.T) {
+	catalogs := loadCatalogs(t)
+	date := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	es, _ := catalogs["es"].FormatDate(date)
+	en, _ := catalogs["en"].FormatDate(date)
+
+	assert.Equal(t, "10 de enero de 2024", es)
+	assert.Equal(t, "January 10, 2024", en)
+	assert.Equal(t, "enero", catalogs["es"].MonthName(date))
+}
+
+func TestCatalog_FormatPeriod(t *testing.T) {
+	catalog := loadCatalogs(t)["es"]
+
+	month, _ := catalog.FormatPeriod("2023-12")
+	week, _ := catalog.FormatPeriod("2023-W50")
+
+	assert.Equal(t, "diciembre de 2023", month)
+	assert.Equal(t, "2023-W50", week)
+}
+
+func TestLocalizer_For(t *testing.T) {
+	catalogs := loadCatalogs(t)
+	localizer := system.Localizer{Catalogs: catalogs, Default: "es", Accounts: map[string]string{"savings": "en"}}
+
+	assert.Nil(t, localizer.Validate())
+	assert.Equal(t, "en", localizer.For("savings").Locale)
+	assert.Equal(t, "es", localizer.For("checking").Locale)
+}
+
+func TestLocalizer_Validate_failsWhenLocaleHasNoCatalog(t *testing.T) {
+	localizer := system.Localizer{Catalogs: loadCatalogs(t), Default: "es", Accounts: map[string]string{"savings": "fr"}}
+
+	err := localizer.Validate()
+
+	assert.ErrorIs(t, err, system.ErrUnknownLocale)
+}
@@ -0,0 +1,135 @@
+package system_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestNewLogger_success(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := system.NewLogger(&buf, "warn", "json")
+
+	logger.Info("ignored")
+	logger.Warn("logged", "key", "value")
+
+	var got map[string]any
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "WARN", got["level"])
+	assert.Equal(t, "logged", got["msg"])
+	assert.Equal(t, "value", got["key"])
+}
+
+func TestNewLogger_failsWhenLevelIsInvalid(t *testing.T) {
+	_, err := system.NewLogger(&bytes.Buffer{}, "verbose", "json")
+
+	assert.NotNil(t, err)
+}
+
+func TestNewLogger_failsWhenFormatIsInvalid(t *testing.T) {
+	_, err := system.NewLogger(&bytes.Buffer{}, "info", "xml")
+
+	assert.NotNil(t, err)
+}
+
+func TestLoggerFrom_returnsDefaultLogger(t *testing.T) {
+	got := system.LoggerFrom(context.Background())
+
+	assert.NotNil(t, got)
+}
+
+func TestRequestIDMiddleware_propagatesRequestIDToHTMLProcessTransactions(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := system.NewLogger(&buf, "info", "json")
+	var gotRequestID string
+	htmlProcessTransactions := func(ctx context.Context) ([]byte, error) {
+		gotRequestID = system.RequestIDFrom(ctx)
+		system.LoggerFrom(ctx).ErrorContext(ctx, "can't get csv file")
+		return nil, system.ErrCantGetCsvFile
+	}
+	app := gin.New()
+	app.ContextWithFallback = true
+	app.Use(system.RequestIDMiddleware(logger))
+	app.GET("/system/html/v1", system.GetHTMLInfoV1(htmlProcessTransactions))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/system/html/v1", nil)
+	req.Header.Set(system.RequestIDHeader, "request-1")
+	app.ServeHTTP(w, req)
+
+	var record map[string]any
+	_ = json.Unmarshal(bytes.Split(buf.Bytes(), []byte("\n"))[0], &record)
+	assert.Equal(t, "request-1", gotRequestID)
+	assert.Equal(t, "request-1", w.Header().Get(system.RequestIDHeader))
+	assert.Equal(t, "request-1", record["request_id"])
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRequestIDMiddleware_generatesRequestID(t *testing.T) {
+	logger, _ := system.NewLogger(&bytes.Buffer{}, "info", "json")
+	app := gin.New()
+	app.Use(system.RequestIDMiddleware(logger))
+	app.GET("/health/live", system.GetHealthLiveV1())
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+	assert.Len(t, w.Header().Get(system.RequestIDHeader), 32)
+}
+
+func TestRequestIDMiddleware_replacesInvalidRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+	}{
+		{"oversized", strings.Repeat("a", 65)},
+		{"forbidden characters", "request 1\" injected=\"true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, _ := system.NewLogger(&buf, "info", "json")
+			app := gin.New()
+			app.Use(system.RequestIDMiddleware(logger), system.RequestLoggerMiddleware())
+			app.GET("/health/live", system.GetHealthLiveV1())
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+			req.Header.Set(system.RequestIDHeader, tt.requestID)
+			app.ServeHTTP(w, req)
+
+			assert.Len(t, w.Header().Get(system.RequestIDHeader), 32)
+			assert.NotContains(t, buf.String(), tt.requestID)
+		})
+	}
+}
+
+func TestRequestLoggerMiddleware_success(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := system.NewLogger(&buf, "info", "json")
+	app := gin.New()
+	app.Use(system.RequestIDMiddleware(logger), system.RequestLoggerMiddleware())
+	app.GET("/health/live", system.GetHealthLiveV1())
+
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	req.Header.Set(system.RequestIDHeader, "request-2")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got map[string]any
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, "request handled", got["msg"])
+	assert.Equal(t, "request-2", got["request_id"])
+	assert.Equal(t, "/health/live", got["route"])
+	assert.Equal(t, float64(http.StatusOK), got["status"])
+}
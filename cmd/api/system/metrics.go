@@ -0,0 +1,125 @@
+package system
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const (
+	metricsNamespace string = "stori"
+	unmatchedRoute   string = "unmatched"
+
+	RejectReasonID       string = "invalid_id"
+	RejectReasonDate     string = "invalid_date"
+	RejectReasonAmount   string = "invalid_amount"
+	RejectReasonCurrency string = "invalid_currency"
+	RejectReasonFormat   string = "invalid_format"
+)
+
+type (
+	// Metrics holds the Prometheus collectors of the service
+	Metrics struct {
+		HTTPRequests        *prometheus.CounterVec
+		HTTPRequestDuration *prometheus.HistogramVec
+		CSVRowsParsed       prometheus.Counter
+		CSVRowsRejected     *prometheus.CounterVec
+		DBInsertDuration    prometheus.Histogram
+		DBRowsInserted      prometheus.Counter
+		TemplateRenderTime  prometheus.Histogram
+		FilesIngested       *prometheus.CounterVec
+	}
+)
+
+// NewMetrics creates the service collectors and registers them in the given registerer
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests handled, by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		HTTPRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		CSVRowsParsed: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "csv_rows_parsed_total",
+			Help:      "Number of CSV rows parsed into transactions.",
+		}),
+		CSVRowsRejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "csv_rows_rejected_total",
+			Help:      "Number of CSV rows rejected, by reason.",
+		}, []string{"reason"}),
+		DBInsertDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_insert_duration_seconds",
+			Help:      "Latency of the transactions bulk insert.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		DBRowsInserted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "db_rows_inserted_total",
+			Help:      "Number of transactions inserted in the database.",
+		}),
+		TemplateRenderTime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "template_render_duration_seconds",
+			Help:      "Time spent parsing and executing the HTML template.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		FilesIngested: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "files_ingested_total",
+			Help:      "Number of files taken from the drop folder, by import status.",
+		}, []string{"status"}),
+	}
+
+	registerer.MustRegister(
+		m.HTTPRequests,
+		m.HTTPRequestDuration,
+		m.CSVRowsParsed,
+		m.CSVRowsRejected,
+		m.DBInsertDuration,
+		m.DBRowsInserted,
+		m.TemplateRenderTime,
+		m.FilesIngested,
+	)
+
+	return m
+}
+
+// NewMetricsNop creates collectors that aren't registered anywhere, for tests and tools that don't expose metrics
+func NewMetricsNop() *Metrics {
+	return NewMetrics(prometheus.NewRegistry())
+}
+
+// RegisterDBStats registers the database/sql pool stats of db, labeled with dbName
+func RegisterDBStats(registerer prometheus.Registerer, db *sql.DB, dbName string) {
+	registerer.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// HTTPMetricsMiddleware records the count and latency of every request handled by gin
+func HTTPMetricsMiddleware(m *Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		m.HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
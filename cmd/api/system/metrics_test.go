@@ -0,0 +1,76 @@
+package system_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func gatheredNames(t *testing.T, registry *prometheus.Registry) []string {
+	families, err := registry.Gather()
+	assert.Nil(t, err)
+
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	return names
+}
+
+func TestNewMetrics_success(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics := system.NewMetrics(registry)
+	metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/system/html/v1", "200")
+	metrics.HTTPRequestDuration.WithLabelValues(http.MethodGet, "/system/html/v1", "200")
+	metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonDate)
+
+	want := []string{
+		"stori_csv_rows_parsed_total",
+		"stori_csv_rows_rejected_total",
+		"stori_db_insert_duration_seconds",
+		"stori_db_rows_inserted_total",
+		"stori_http_request_duration_seconds",
+		"stori_http_requests_total",
+		"stori_template_render_duration_seconds",
+	}
+	got := gatheredNames(t, registry)
+
+	assert.Equal(t, want, got)
+}
+
+func TestRegisterDBStats_success(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	db, _, _ := sqlmock.New()
+
+	system.RegisterDBStats(registry, db, "stori")
+
+	got := gatheredNames(t, registry)
+
+	assert.Contains(t, got, "go_sql_open_connections")
+	assert.Contains(t, got, "go_sql_in_use_connections")
+	assert.Contains(t, got, "go_sql_wait_count_total")
+}
+
+func TestHTTPMetricsMiddleware_success(t *testing.T) {
+	metrics := system.NewMetricsNop()
+	app := gin.New()
+	app.Use(system.HTTPMetricsMiddleware(metrics))
+	app.GET("/system/html/v1", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/system/html/v1", nil))
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/system/html/v1", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.HTTPRequestDuration, "stori_http_request_duration_seconds"))
+}
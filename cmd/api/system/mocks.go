@@ -2,16 +2,52 @@ package system
 
 import (
 	"context"
+	"io"
+	"strconv"
 	"time"
 )
 
-// MockReadCSV mock
-func MockReadCSV(trans []Transaction, err error) ReadCSV {
-	return func(context.Context, string) ([]Transaction, error) {
+// MockReadOFX mock
+func MockReadOFX(trans []Transaction, err error) ReadOFX {
+	return func(context.Context, io.Reader, ImportOptions) ([]Transaction, error) {
 		return trans, err
 	}
 }
 
+// MockReadCamt053 mock
+func MockReadCamt053(statements []BankStatement, err error) ReadCamt053 {
+	return func(context.Context, io.Reader, ImportOptions) ([]BankStatement, error) {
+		return statements, err
+	}
+}
+
+// MockStreamCSV mock
+func MockStreamCSV(trans []Transaction, err error) StreamCSV {
+	return func(_ context.Context, _ io.Reader, _ ImportOptions, yield func(Transaction) error) error {
+		if err := yieldAll(trans, yield); err != nil {
+			return err
+		}
+		return err
+	}
+}
+
+// MockReadStatement mock
+func MockReadStatement(trans []Transaction, err error) ReadStatement {
+	return func(_ context.Context, _ string, _ ImportOptions, yield func(Transaction) error) error {
+		if err := yieldAll(trans, yield); err != nil {
+			return err
+		}
+		return err
+	}
+}
+
+// MockImportStatement mock
+func MockImportStatement(summary Summary, err error) ImportStatement {
+	return func(context.Context, string, ImportOptions) (Summary, error) {
+		return summary, err
+	}
+}
+
 // MockHTMLProcessTransactions mock
 func MockHTMLProcessTransactions(html []byte, err error) HTMLProcessTransactions {
 	return func(context.Context) ([]byte, error) {
@@ -19,20 +55,196 @@ func MockHTMLProcessTransactions(html []byte, err error) HTMLProcessTransactions
 	}
 }
 
-// MockMySQLCreate mock
+// MockMySQLCreate mock, it inserts every transaction unless it fails
 func MockMySQLCreate(err error) MySQLCreate {
-	return func(context.Context, []Transaction) error {
+	return func(_ context.Context, transactions []Transaction) (int64, error) {
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(transactions)), nil
+	}
+}
+
+// MockMySQLFindBalances mock
+func MockMySQLFindBalances(opening float64, snapshots []DailyBalance, err error) MySQLFindBalances {
+	return func(context.Context, string, time.Time, time.Time) (float64, []DailyBalance, error) {
+		return opening, snapshots, err
+	}
+}
+
+// MockMySQLSnapshotBalances mock
+func MockMySQLSnapshotBalances(err error) MySQLSnapshotBalances {
+	return func(context.Context, string, time.Time, ExchangeRates) error {
+		return err
+	}
+}
+
+// MockGetStatementSummary mock
+func MockGetStatementSummary(summary Summary, err error) GetStatementSummary {
+	return func(context.Context, string, StatementPeriod) (Summary, error) {
+		return summary, err
+	}
+}
+
+// MockGetBalances mock
+func MockGetBalances(balances AccountBalances, err error) GetBalances {
+	return func(context.Context, string, time.Time, time.Time) (AccountBalances, error) {
+		return balances, err
+	}
+}
+
+// MockMySQLFindTransactions mock
+func MockMySQLFindTransactions(trans []Transaction, err error) MySQLFindTransactions {
+	return func(context.Context, string, time.Time, time.Time) ([]Transaction, error) {
+		return trans, err
+	}
+}
+
+// MockGetRecurring mock
+func MockGetRecurring(recurring []Recurring, err error) GetRecurring {
+	return func(context.Context, string, time.Time, time.Time) ([]Recurring, error) {
+		return recurring, err
+	}
+}
+
+// MockGetAnomalies mock
+func MockGetAnomalies(anomalies Anomalies, err error) GetAnomalies {
+	return func(context.Context, string, time.Time, time.Time) (Anomalies, error) {
+		return anomalies, err
+	}
+}
+
+// MockGetForecast mock
+func MockGetForecast(forecast Forecast, err error) GetForecast {
+	return func(context.Context, string, time.Time, int) (Forecast, error) {
+		return forecast, err
+	}
+}
+
+// MockMySQLCreateBudget mock, it returns the budget to create with the given id
+func MockMySQLCreateBudget(id int64, err error) MySQLCreateBudget {
+	return func(_ context.Context, budget Budget) (Budget, error) {
+		if err != nil {
+			return Budget{}, err
+		}
+		budget.ID = id
+		return budget, nil
+	}
+}
+
+// MockMySQLFindBudget mock
+func MockMySQLFindBudget(budget Budget, err error) MySQLFindBudget {
+	return func(context.Context, string, int64) (Budget, error) {
+		return budget, err
+	}
+}
+
+// MockMySQLListBudgets mock
+func MockMySQLListBudgets(budgets []Budget, err error) MySQLListBudgets {
+	return func(context.Context, string) ([]Budget, error) {
+		return budgets, err
+	}
+}
+
+// MockMySQLUpdateBudget mock
+func MockMySQLUpdateBudget(err error) MySQLUpdateBudget {
+	return func(context.Context, Budget) error {
+		return err
+	}
+}
+
+// MockMySQLDeleteBudget mock
+func MockMySQLDeleteBudget(err error) MySQLDeleteBudget {
+	return func(context.Context, string, int64) error {
+		return err
+	}
+}
+
+// MockMySQLBudgetAlertSent mock
+func MockMySQLBudgetAlertSent(sent bool, err error) MySQLBudgetAlertSent {
+	return func(context.Context, BudgetStatus) (bool, error) {
+		return sent, err
+	}
+}
+
+// MockGetBudgetReport mock
+func MockGetBudgetReport(statuses []BudgetStatus, err error) GetBudgetReport {
+	return func(context.Context, string, time.Time) ([]BudgetStatus, error) {
+		return statuses, err
+	}
+}
+
+// MockAlertBudgets mock
+func MockAlertBudgets(err error) AlertBudgets {
+	return func(context.Context, []BudgetStatus) error {
+		return err
+	}
+}
+
+// MockMySQLIssueStatement mock, it returns the statement to issue
+func MockMySQLIssueStatement(err error) MySQLIssueStatement {
+	return func(_ context.Context, statement Statement) (Statement, error) {
+		if err != nil {
+			return Statement{}, err
+		}
+		return statement, nil
+	}
+}
+
+// MockMySQLFindStatement mock
+func MockMySQLFindStatement(statement Statement, err error) MySQLFindStatement {
+	return func(context.Context, string, int64) (Statement, error) {
+		return statement, err
+	}
+}
+
+// MockMySQLListStatements mock
+func MockMySQLListStatements(statements []Statement, err error) MySQLListStatements {
+	return func(context.Context, string) ([]Statement, error) {
+		return statements, err
+	}
+}
+
+// MockMySQLFindImport mock
+func MockMySQLFindImport(record ImportRecord, err error) MySQLFindImport {
+	return func(context.Context, string) (ImportRecord, error) {
+		return record, err
+	}
+}
+
+// MockMySQLSaveImport mock
+func MockMySQLSaveImport(err error) MySQLSaveImport {
+	return func(context.Context, ImportRecord) error {
 		return err
 	}
 }
 
-// MockMySQLFind mock
-func MockMySQLFind(id int64, err error) MySQLFind {
-	return func(context.Context) (int64, error) {
-		return id, err
+// MockIngestFile mock
+func MockIngestFile(err error) IngestFile {
+	return func(context.Context, string) error {
+		return err
+	}
+}
+
+// MockGetExchangeRates mock
+func MockGetExchangeRates(rates ExchangeRates, err error) GetExchangeRates {
+	return func(context.Context) (ExchangeRates, error) {
+		return rates, err
 	}
 }
 
+// MockLocalizer mock
+func MockLocalizer() Localizer {
+	catalogs, _ := LoadCatalogs(EmbeddedCatalogs())
+	return Localizer{Catalogs: catalogs, Default: DefaultLocale}
+}
+
+// MockTemplateRegistry mock
+func MockTemplateRegistry() *TemplateRegistry {
+	templates, _ := NewTemplateRegistry(EmbeddedTemplates(), MockLocalizer().Catalogs, false)
+	return templates
+}
+
 // MockTransaction mock
 func MockTransaction(id int64, date time.Time, trType string, amount float64) Transaction {
 	return Transaction{
@@ -43,30 +255,37 @@ func MockTransaction(id int64, date time.Time, trType string, amount float64) Tr
 	}
 }
 
+// MockCSVTransaction mock of a transaction read from a CSV file, whose Id column is its external id
+func MockCSVTransaction(id int64, date time.Time, trType string, amount float64) Transaction {
+	t := MockTransaction(id, date, trType, amount)
+	t.ExternalID = strconv.FormatInt(id, 10)
+	return t
+}
+
 // MockTranssactions mock
 func MockTransactions() []Transaction {
 	return []Transaction{
-		MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5),
-		MockTransaction(1, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), "debit", -10.3),
-		MockTransaction(2, time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), "debit", -20.46),
-		MockTransaction(3, time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), "credit", +10),
-		MockTransaction(4, time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC), "credit", +61.5),
-		MockTransaction(5, time.Date(2023, 1, 6, 0, 0, 0, 0, time.UTC), "debit", -11.4),
-		MockTransaction(6, time.Date(2023, 1, 7, 0, 0, 0, 0, time.UTC), "debit", -21.46),
-		MockTransaction(7, time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC), "credit", +11),
-		MockTransaction(8, time.Date(2023, 1, 9, 0, 0, 0, 0, time.UTC), "credit", +62.5),
-		MockTransaction(9, time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), "debit", -12.4),
-		MockTransaction(10, time.Date(2023, 1, 11, 0, 0, 0, 0, time.UTC), "debit", -22.46),
-		MockTransaction(11, time.Date(2023, 1, 12, 0, 0, 0, 0, time.UTC), "credit", +12),
-		MockTransaction(12, time.Date(2023, 1, 13, 0, 0, 0, 0, time.UTC), "credit", +63.5),
-		MockTransaction(13, time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC), "debit", -13.4),
-		MockTransaction(14, time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), "debit", -23.46),
-		MockTransaction(15, time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), "credit", +13),
-		MockTransaction(16, time.Date(2023, 2, 17, 0, 0, 0, 0, time.UTC), "credit", +64.5),
-		MockTransaction(17, time.Date(2023, 2, 18, 0, 0, 0, 0, time.UTC), "debit", -14.5),
-		MockTransaction(18, time.Date(2023, 2, 19, 0, 0, 0, 0, time.UTC), "debit", -23.46),
-		MockTransaction(19, time.Date(2023, 2, 20, 0, 0, 0, 0, time.UTC), "credit", +14),
-		MockTransaction(20, time.Date(2023, 2, 21, 0, 0, 0, 0, time.UTC), "credit", +65.5),
+		MockCSVTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5),
+		MockCSVTransaction(1, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), "debit", -10.3),
+		MockCSVTransaction(2, time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC), "debit", -20.46),
+		MockCSVTransaction(3, time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC), "credit", +10),
+		MockCSVTransaction(4, time.Date(2023, 1, 5, 0, 0, 0, 0, time.UTC), "credit", +61.5),
+		MockCSVTransaction(5, time.Date(2023, 1, 6, 0, 0, 0, 0, time.UTC), "debit", -11.4),
+		MockCSVTransaction(6, time.Date(2023, 1, 7, 0, 0, 0, 0, time.UTC), "debit", -21.46),
+		MockCSVTransaction(7, time.Date(2023, 1, 8, 0, 0, 0, 0, time.UTC), "credit", +11),
+		MockCSVTransaction(8, time.Date(2023, 1, 9, 0, 0, 0, 0, time.UTC), "credit", +62.5),
+		MockCSVTransaction(9, time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC), "debit", -12.4),
+		MockCSVTransaction(10, time.Date(2023, 1, 11, 0, 0, 0, 0, time.UTC), "debit", -22.46),
+		MockCSVTransaction(11, time.Date(2023, 1, 12, 0, 0, 0, 0, time.UTC), "credit", +12),
+		MockCSVTransaction(12, time.Date(2023, 1, 13, 0, 0, 0, 0, time.UTC), "credit", +63.5),
+		MockCSVTransaction(13, time.Date(2023, 1, 14, 0, 0, 0, 0, time.UTC), "debit", -13.4),
+		MockCSVTransaction(14, time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC), "debit", -23.46),
+		MockCSVTransaction(15, time.Date(2023, 1, 16, 0, 0, 0, 0, time.UTC), "credit", +13),
+		MockCSVTransaction(16, time.Date(2023, 2, 17, 0, 0, 0, 0, time.UTC), "credit", +64.5),
+		MockCSVTransaction(17, time.Date(2023, 2, 18, 0, 0, 0, 0, time.UTC), "debit", -14.5),
+		MockCSVTransaction(18, time.Date(2023, 2, 19, 0, 0, 0, 0, time.UTC), "debit", -23.46),
+		MockCSVTransaction(19, time.Date(2023, 2, 20, 0, 0, 0, 0, time.UTC), "credit", +14),
+		MockCSVTransaction(20, time.Date(2023, 2, 21, 0, 0, 0, 0, time.UTC), "credit", +65.5),
 	}
 }
 
@@ -76,14 +295,50 @@ func MockEmail() Email {
 		Balance:       264.69999999999993,
 		AverageDebit:  -86.65000000000002,
 		AverageCredit: 219,
-		WorkingMonths: MockMonthsMap(),
+		Periods:       MockPeriods(),
+		Spending:      []CategorySpend{{Category: DefaultCategory, Amount: 173.30000000000004}},
+	}
+}
+
+// MockPeriods mock
+func MockPeriods() []Period {
+	return []Period{
+		{
+			Key:     "2023-01",
+			Start:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+			Count:   16,
+			Credit:  294,
+			Debit:   -135.34000000000003,
+			Net:     158.65999999999997,
+			Average: 9.916249999999998,
+		},
+		{
+			Key:     "2023-02",
+			Start:   time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+			Count:   5,
+			Credit:  144,
+			Debit:   -37.96,
+			Net:     106.03999999999999,
+			Average: 21.208,
+		},
 	}
 }
 
-// MockMonthsMap mock
-func MockMonthsMap() map[string]int {
-	return map[string]int{
-		"January":  16,
-		"February": 5,
+// MockHealthCheck mock
+func MockHealthCheck(err error) HealthCheck {
+	return func(context.Context) error {
+		return err
 	}
 }
+
+// MockImportOptions mock
+func MockImportOptions() ImportOptions {
+	return NewImportOptions(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), time.UTC)
+}
+
+// MockImportProfiles mock
+func MockImportProfiles() []ImportProfile {
+	return []ImportProfile{DefaultImportProfile()}
+}
@@ -0,0 +1,196 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	queryLastBalance    = "SELECT balance FROM stori.balance_snapshots WHERE account = ? AND date < ? ORDER BY date DESC LIMIT 1"
+	queryFindBalances   = "SELECT date, balance FROM stori.balance_snapshots WHERE account = ? AND date BETWEEN ? AND ? ORDER BY date"
+	queryDailyNets      = "SELECT date, currency, SUM(transaction) FROM stori.transactions WHERE account = ? AND date >= ? GROUP BY date, currency ORDER BY date, currency"
+	querySaveBalances   = "INSERT INTO stori.balance_snapshots (account, date, balance) VALUES "
+	querySaveBalancesOn = " ON DUPLICATE KEY UPDATE balance = VALUES(balance)"
+)
+
+type (
+	// MySQLFindBalances is a function that finds the balance of an account at the end of the day before from, and
+	// the snapshots of the balance at the end of the dates between from and to that have transactions
+	MySQLFindBalances func(ctx context.Context, account string, from time.Time, to time.Time) (float64, []DailyBalance, error)
+
+	// MySQLSnapshotBalances is a function that recomputes and saves the end of day balances of an account from the
+	// given date on, in the currency of the account converted with the given exchange rates
+	MySQLSnapshotBalances func(ctx context.Context, account string, from time.Time, rates ExchangeRates) error
+)
+
+// MakeMySQLFindBalances creates a new MySQLFindBalances. Accounts without earlier snapshots start with their opening
+// balance
+func MakeMySQLFindBalances(db *sql.DB, openings OpeningBalances) MySQLFindBalances {
+	return func(ctx context.Context, account string, from time.Time, to time.Time) (float64, []DailyBalance, error) {
+		ctx, span := startSpan(ctx, "MySQLFindBalances", semconv.DBSystemMySQL, semconv.DBStatement(queryFindBalances))
+		defer span.End()
+
+		opening, err := lastBalance(ctx, db, account, from, openings.For(account))
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find opening balance", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return 0, nil, ErrCantRunQuery
+		}
+
+		rows, err := db.QueryContext(ctx, queryFindBalances, account, from, to)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find balances", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return 0, nil, ErrCantRunQuery
+		}
+		snapshots, err := scanDailyBalances(rows)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read balances", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return 0, nil, ErrCantRunQuery
+		}
+
+		return opening, snapshots, nil
+	}
+}
+
+// MakeMySQLSnapshotBalances creates a new MySQLSnapshotBalances. The balance before from is the last snapshot, or
+// the opening balance of the account when there is none. The daily nets of every currency are converted to the
+// currency of the account with the rate effective on their date, and the ones without a rate are left out of the
+// balance, as in the summary
+func MakeMySQLSnapshotBalances(db *sql.DB, openings OpeningBalances, currencies Currencies) MySQLSnapshotBalances {
+	return func(ctx context.Context, account string, from time.Time, rates ExchangeRates) error {
+		ctx, span := startSpan(ctx, "MySQLSnapshotBalances", semconv.DBSystemMySQL, attribute.String("balances.account", account))
+		defer span.End()
+
+		from = calendarDate(from)
+		fail := func(msg string, err error) error {
+			LoggerFrom(ctx).ErrorContext(ctx, msg, slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fail("can't begin balances transaction", err)
+		}
+		defer tx.Rollback()
+
+		opening, err := lastBalance(ctx, tx, account, from, openings.For(account))
+		if err != nil {
+			return fail("can't find opening balance", err)
+		}
+
+		rows, err := tx.QueryContext(ctx, queryDailyNets, account, from)
+		if err != nil {
+			return fail("can't sum daily transactions", err)
+		}
+		nets, unconverted, err := scanDailyNets(rows, currencies.For(account), rates)
+		if err != nil {
+			return fail("can't read daily transactions", err)
+		}
+		if unconverted > 0 {
+			LoggerFrom(ctx).WarnContext(ctx, "left daily nets without exchange rate out of the balances", slog.String("account", account), slog.Int("unconverted", unconverted))
+		}
+
+		balances := accumulateBalances(opening, nets)
+		if len(balances) > 0 {
+			inserts := make([]string, 0, len(balances))
+			params := make([]interface{}, 0, 3*len(balances))
+			for _, balance := range balances {
+				inserts = append(inserts, "(?, ?, ?)")
+				params = append(params, account, balance.Date, balance.Balance)
+			}
+
+			if _, err := tx.ExecContext(ctx, querySaveBalances+strings.Join(inserts, ",")+querySaveBalancesOn, params...); err != nil {
+				return fail("can't save balances", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fail("can't commit balances", err)
+		}
+		span.SetAttributes(attribute.Int("db.rows_inserted", len(balances)), attribute.Int("balances.unconverted", unconverted))
+
+		return nil
+	}
+}
+
+// queryRower is implemented by *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// lastBalance returns the last snapshot of the account before the date, or opening when there is none
+func lastBalance(ctx context.Context, db queryRower, account string, before time.Time, opening float64) (float64, error) {
+	var balance float64
+	err := db.QueryRowContext(ctx, queryLastBalance, account, before).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return opening, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
+
+// scanDailyBalances reads rows of date and amount
+func scanDailyBalances(rows *sql.Rows) ([]DailyBalance, error) {
+	defer rows.Close()
+
+	var balances []DailyBalance
+	for rows.Next() {
+		var balance DailyBalance
+		if err := rows.Scan(&balance.Date, &balance.Balance); err != nil {
+			return nil, err
+		}
+		balance.Date = calendarDate(balance.Date)
+		balances = append(balances, balance)
+	}
+
+	return balances, rows.Err()
+}
+
+// scanDailyNets reads rows of date, currency and net amount, sorted by date, and returns the net of every date in
+// the given currency. Amounts without a currency are already in it, and the ones without an exchange rate are
+// counted as unconverted
+func scanDailyNets(rows *sql.Rows, currency string, rates ExchangeRates) ([]DailyBalance, int, error) {
+	defer rows.Close()
+
+	var nets []DailyBalance
+	var unconverted int
+	for rows.Next() {
+		var date time.Time
+		var from sql.NullString
+		var net float64
+		if err := rows.Scan(&date, &from, &net); err != nil {
+			return nil, 0, err
+		}
+
+		date = calendarDate(date)
+		amount, ok := net, true
+		if from.Valid && from.String != "" {
+			amount, ok = rates.Convert(net, from.String, currency, date)
+		}
+		if !ok {
+			unconverted++
+			continue
+		}
+
+		if n := len(nets); n > 0 && nets[n-1].Date.Equal(date) {
+			nets[n-1].Balance += amount
+			continue
+		}
+		nets = append(nets, DailyBalance{Date: date, Balance: amount})
+	}
+
+	return nets, unconverted, rows.Err()
+}
@@ -0,0 +1,135 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+const (
+	queryLastBalanceMock  string = "SELECT balance FROM stori.balance_snapshots WHERE account = \\? AND date < \\? ORDER BY date DESC LIMIT 1"
+	queryFindBalancesMock string = "SELECT date, balance FROM stori.balance_snapshots WHERE account = \\? AND date BETWEEN \\? AND \\? ORDER BY date"
+	queryDailyNetsMock    string = "SELECT date, currency, SUM\\(transaction\\) FROM stori.transactions WHERE account = \\? AND date >= \\? GROUP BY date, currency ORDER BY date, currency"
+	querySaveBalancesMock string = "INSERT INTO stori.balance_snapshots \\(account, date, balance\\) VALUES \\(\\?, \\?, \\?\\),\\(\\?, \\?, \\?\\) ON DUPLICATE KEY UPDATE"
+)
+
+func TestMySQLFindBalances_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(queryLastBalanceMock).WithArgs("1234", from).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(500.0))
+	mock.ExpectQuery(queryFindBalancesMock).WithArgs("1234", from, to).WillReturnRows(
+		sqlmock.NewRows([]string{"date", "balance"}).AddRow(time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), 650.0),
+	)
+	mysqlFindBalances := system.MakeMySQLFindBalances(db, system.OpeningBalances{"1234": 1000})
+
+	opening, snapshots, err := mysqlFindBalances(context.Background(), "1234", from, to)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 500.0, opening)
+	assert.Equal(t, []system.DailyBalance{{Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), Balance: 650}}, snapshots)
+}
+
+func TestMySQLFindBalances_startsWithOpeningBalance(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(queryLastBalanceMock).WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+	mock.ExpectQuery(queryFindBalancesMock).WillReturnRows(sqlmock.NewRows([]string{"date", "balance"}))
+	mysqlFindBalances := system.MakeMySQLFindBalances(db, system.OpeningBalances{"1234": 1000})
+
+	opening, snapshots, err := mysqlFindBalances(context.Background(), "1234", from, from)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1000.0, opening)
+	assert.Empty(t, snapshots)
+}
+
+func TestMySQLFindBalances_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(queryLastBalanceMock).WillReturnError(errors.New("connection refused"))
+	mysqlFindBalances := system.MakeMySQLFindBalances(db, nil)
+
+	want := system.ErrCantRunQuery
+	_, _, got := mysqlFindBalances(context.Background(), "1234", from, from)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLSnapshotBalances_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(queryLastBalanceMock).WithArgs("1234", from).WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+	mock.ExpectQuery(queryDailyNetsMock).WithArgs("1234", from).WillReturnRows(
+		sqlmock.NewRows([]string{"date", "currency", "sum"}).
+			AddRow(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "MXN", 75.0).
+			AddRow(time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), nil, -40.0),
+	)
+	mock.ExpectExec(querySaveBalancesMock).
+		WithArgs("1234", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 1075.0, "1234", time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), 1035.0).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mysqlSnapshotBalances := system.MakeMySQLSnapshotBalances(db, system.OpeningBalances{"1234": 1000}, system.Currencies{})
+
+	got := mysqlSnapshotBalances(context.Background(), "1234", from.Add(15*time.Hour), system.ExchangeRates{})
+
+	assert.Nil(t, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLSnapshotBalances_convertsEveryCurrencyToTheAccountCurrency(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	rates := system.NewExchangeRates([]system.ExchangeRate{
+		{Date: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), From: "USD", To: "MXN", Rate: 17},
+		{Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), From: "USD", To: "MXN", Rate: 18},
+	})
+	mock.ExpectBegin()
+	mock.ExpectQuery(queryLastBalanceMock).WillReturnRows(sqlmock.NewRows([]string{"balance"}))
+	// the euros have no rate to pesos, so they are left out of the balance
+	mock.ExpectQuery(queryDailyNetsMock).WillReturnRows(
+		sqlmock.NewRows([]string{"date", "currency", "sum"}).
+			AddRow(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "EUR", 5.0).
+			AddRow(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "MXN", 100.0).
+			AddRow(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "USD", 10.0).
+			AddRow(time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), "USD", -2.0),
+	)
+	mock.ExpectExec(querySaveBalancesMock).
+		WithArgs("1234", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), 270.0, "1234", time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), 234.0).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mysqlSnapshotBalances := system.MakeMySQLSnapshotBalances(db, nil, system.Currencies{Default: "MXN"})
+
+	got := mysqlSnapshotBalances(context.Background(), "1234", from, rates)
+
+	assert.Nil(t, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLSnapshotBalances_failsWhenSaveFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(queryLastBalanceMock).WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(10.0))
+	mock.ExpectQuery(queryDailyNetsMock).WillReturnRows(
+		sqlmock.NewRows([]string{"date", "currency", "sum"}).
+			AddRow(time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "MXN", 75.0).
+			AddRow(time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), nil, -40.0),
+	)
+	mock.ExpectExec(querySaveBalancesMock).WillReturnError(errors.New("deadlock"))
+	mock.ExpectRollback()
+	mysqlSnapshotBalances := system.MakeMySQLSnapshotBalances(db, nil, system.Currencies{})
+
+	want := system.ErrCantRunQuery
+	got := mysqlSnapshotBalances(context.Background(), "1234", from, system.ExchangeRates{})
+
+	assert.Equal(t, want, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
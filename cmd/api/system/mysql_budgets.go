@@ -0,0 +1,231 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	// mySQLDuplicateEntry is the error number of MySQL when a row breaks a unique key
+	mySQLDuplicateEntry uint16 = 1062
+
+	budgetColumns = "id, account, category, period, amount, rollover, created_at"
+
+	queryCreateBudget    = "INSERT INTO stori.budgets (account, category, period, amount, rollover, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	queryFindBudget      = "SELECT " + budgetColumns + " FROM stori.budgets WHERE account = ? AND id = ?"
+	queryListBudgets     = "SELECT " + budgetColumns + " FROM stori.budgets WHERE account = ? ORDER BY category, period"
+	queryUpdateBudget    = "UPDATE stori.budgets SET category = ?, period = ?, amount = ?, rollover = ? WHERE account = ? AND id = ?"
+	queryDeleteBudget    = "DELETE FROM stori.budgets WHERE account = ? AND id = ?"
+	queryBudgetAlertSent = "SELECT COUNT(*) FROM stori.budget_alerts WHERE budget_id = ? AND period_key = ? AND alert = ?"
+	querySaveBudgetAlert = "INSERT IGNORE INTO stori.budget_alerts (budget_id, period_key, alert, sent_at) VALUES (?, ?, ?, ?)"
+)
+
+type (
+	// MySQLCreateBudget is a function that saves a new budget and returns it with its id
+	MySQLCreateBudget func(ctx context.Context, budget Budget) (Budget, error)
+
+	// MySQLFindBudget is a function that finds a budget of an account
+	MySQLFindBudget func(ctx context.Context, account string, id int64) (Budget, error)
+
+	// MySQLListBudgets is a function that lists the budgets of an account
+	MySQLListBudgets func(ctx context.Context, account string) ([]Budget, error)
+
+	// MySQLUpdateBudget is a function that changes the category, period, amount and rollover of a budget
+	MySQLUpdateBudget func(ctx context.Context, budget Budget) error
+
+	// MySQLDeleteBudget is a function that deletes a budget of an account
+	MySQLDeleteBudget func(ctx context.Context, account string, id int64) error
+
+	// MySQLBudgetAlertSent is a function that reports whether the alert of a budget status was already sent for
+	// its period
+	MySQLBudgetAlertSent func(ctx context.Context, status BudgetStatus) (bool, error)
+
+	// MySQLSaveBudgetAlert is a function that records that the alert of a budget status was sent for its period
+	MySQLSaveBudgetAlert func(ctx context.Context, status BudgetStatus) error
+)
+
+// MakeMySQLCreateBudget creates a new MySQLCreateBudget. It returns ErrBudgetExists when the account already has a
+// budget for the category and period
+func MakeMySQLCreateBudget(db *sql.DB) MySQLCreateBudget {
+	return func(ctx context.Context, budget Budget) (Budget, error) {
+		ctx, span := startSpan(ctx, "MySQLCreateBudget", semconv.DBSystemMySQL, semconv.DBStatement(queryCreateBudget))
+		defer span.End()
+
+		budget.CreatedAt = time.Now().UTC()
+		result, err := db.ExecContext(ctx, queryCreateBudget, budget.Account, budget.Category, budget.Period, budget.Amount, budget.Rollover, budget.CreatedAt)
+		if isDuplicateEntry(err) {
+			return Budget{}, ErrBudgetExists
+		}
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't create budget", slog.String("account", budget.Account), slog.Any("error", err))
+			spanError(span, err)
+			return Budget{}, ErrCantRunQuery
+		}
+
+		budget.ID, err = result.LastInsertId()
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't get budget id", slog.Any("error", err))
+			spanError(span, err)
+			return Budget{}, ErrCantGetLastID
+		}
+		span.SetAttributes(attribute.Int64("budget.id", budget.ID))
+
+		return budget, nil
+	}
+}
+
+// MakeMySQLFindBudget creates a new MySQLFindBudget. It returns ErrBudgetNotFound when the account doesn't have a
+// budget with that id
+func MakeMySQLFindBudget(db *sql.DB) MySQLFindBudget {
+	return func(ctx context.Context, account string, id int64) (Budget, error) {
+		ctx, span := startSpan(ctx, "MySQLFindBudget", semconv.DBSystemMySQL, semconv.DBStatement(queryFindBudget))
+		defer span.End()
+
+		budget, err := scanBudget(db.QueryRowContext(ctx, queryFindBudget, account, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return Budget{}, ErrBudgetNotFound
+		}
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find budget", slog.String("account", account), slog.Int64("id", id), slog.Any("error", err))
+			spanError(span, err)
+			return Budget{}, ErrCantRunQuery
+		}
+
+		return budget, nil
+	}
+}
+
+// MakeMySQLListBudgets creates a new MySQLListBudgets
+func MakeMySQLListBudgets(db *sql.DB) MySQLListBudgets {
+	return func(ctx context.Context, account string) ([]Budget, error) {
+		ctx, span := startSpan(ctx, "MySQLListBudgets", semconv.DBSystemMySQL, semconv.DBStatement(queryListBudgets))
+		defer span.End()
+
+		rows, err := db.QueryContext(ctx, queryListBudgets, account)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't list budgets", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantRunQuery
+		}
+		defer rows.Close()
+
+		budgets := make([]Budget, 0)
+		for rows.Next() {
+			budget, err := scanBudget(rows)
+			if err != nil {
+				LoggerFrom(ctx).ErrorContext(ctx, "can't read budget", slog.String("account", account), slog.Any("error", err))
+				spanError(span, err)
+				return nil, ErrCantRunQuery
+			}
+			budgets = append(budgets, budget)
+		}
+		if err := rows.Err(); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read budgets", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantRunQuery
+		}
+
+		return budgets, nil
+	}
+}
+
+// MakeMySQLUpdateBudget creates a new MySQLUpdateBudget. It returns ErrBudgetExists when the account already has
+// another budget for the new category and period
+func MakeMySQLUpdateBudget(db *sql.DB) MySQLUpdateBudget {
+	return func(ctx context.Context, budget Budget) error {
+		ctx, span := startSpan(ctx, "MySQLUpdateBudget", semconv.DBSystemMySQL, semconv.DBStatement(queryUpdateBudget))
+		defer span.End()
+
+		_, err := db.ExecContext(ctx, queryUpdateBudget, budget.Category, budget.Period, budget.Amount, budget.Rollover, budget.Account, budget.ID)
+		if isDuplicateEntry(err) {
+			return ErrBudgetExists
+		}
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't update budget", slog.String("account", budget.Account), slog.Int64("id", budget.ID), slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+
+		return nil
+	}
+}
+
+// MakeMySQLDeleteBudget creates a new MySQLDeleteBudget. It returns ErrBudgetNotFound when the account doesn't have
+// a budget with that id
+func MakeMySQLDeleteBudget(db *sql.DB) MySQLDeleteBudget {
+	return func(ctx context.Context, account string, id int64) error {
+		ctx, span := startSpan(ctx, "MySQLDeleteBudget", semconv.DBSystemMySQL, semconv.DBStatement(queryDeleteBudget))
+		defer span.End()
+
+		result, err := db.ExecContext(ctx, queryDeleteBudget, account, id)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't delete budget", slog.String("account", account), slog.Int64("id", id), slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't get deleted budgets", slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+		if deleted == 0 {
+			return ErrBudgetNotFound
+		}
+
+		return nil
+	}
+}
+
+// MakeMySQLBudgetAlertSent creates a new MySQLBudgetAlertSent
+func MakeMySQLBudgetAlertSent(db *sql.DB) MySQLBudgetAlertSent {
+	return func(ctx context.Context, status BudgetStatus) (bool, error) {
+		ctx, span := startSpan(ctx, "MySQLBudgetAlertSent", semconv.DBSystemMySQL, semconv.DBStatement(queryBudgetAlertSent))
+		defer span.End()
+
+		var count int
+		if err := db.QueryRowContext(ctx, queryBudgetAlertSent, status.BudgetID, status.Key, status.Alert).Scan(&count); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find budget alert", slog.Int64("budget", status.BudgetID), slog.Any("error", err))
+			spanError(span, err)
+			return false, ErrCantRunQuery
+		}
+
+		return count > 0, nil
+	}
+}
+
+// MakeMySQLSaveBudgetAlert creates a new MySQLSaveBudgetAlert
+func MakeMySQLSaveBudgetAlert(db *sql.DB) MySQLSaveBudgetAlert {
+	return func(ctx context.Context, status BudgetStatus) error {
+		ctx, span := startSpan(ctx, "MySQLSaveBudgetAlert", semconv.DBSystemMySQL, semconv.DBStatement(querySaveBudgetAlert))
+		defer span.End()
+
+		if _, err := db.ExecContext(ctx, querySaveBudgetAlert, status.BudgetID, status.Key, status.Alert, time.Now().UTC()); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't save budget alert", slog.Int64("budget", status.BudgetID), slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+
+		return nil
+	}
+}
+
+// scanBudget reads a row of budgetColumns
+func scanBudget(row rowScanner) (Budget, error) {
+	var budget Budget
+	err := row.Scan(&budget.ID, &budget.Account, &budget.Category, &budget.Period, &budget.Amount, &budget.Rollover, &budget.CreatedAt)
+	return budget, err
+}
+
+// isDuplicateEntry reports whether err is the MySQL error of a row that breaks a unique key
+func isDuplicateEntry(err error) bool {
+	var mySQLErr *mysql.MySQLError
+	return errors.As(err, &mySQLErr) && mySQLErr.Number == mySQLDuplicateEntry
+}
@@ -0,0 +1,199 @@
+package system_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+const (
+	queryCreateBudgetMock    string = "INSERT INTO stori.budgets \\(account, category, period, amount, rollover, created_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?\\)"
+	queryFindBudgetMock      string = "SELECT .+ FROM stori.budgets WHERE account = \\? AND id = \\?"
+	queryListBudgetsMock     string = "SELECT .+ FROM stori.budgets WHERE account = \\? ORDER BY category, period"
+	queryUpdateBudgetMock    string = "UPDATE stori.budgets SET category = \\?, period = \\?, amount = \\?, rollover = \\? WHERE account = \\? AND id = \\?"
+	queryDeleteBudgetMock    string = "DELETE FROM stori.budgets WHERE account = \\? AND id = \\?"
+	queryBudgetAlertSentMock string = "SELECT COUNT\\(\\*\\) FROM stori.budget_alerts WHERE budget_id = \\? AND period_key = \\? AND alert = \\?"
+	querySaveBudgetAlertMock string = "INSERT IGNORE INTO stori.budget_alerts \\(budget_id, period_key, alert, sent_at\\) VALUES \\(\\?, \\?, \\?, \\?\\)"
+)
+
+var budgetRowColumns = []string{"id", "account", "category", "period", "amount", "rollover", "created_at"}
+
+func mockBudget() system.Budget {
+	return system.Budget{
+		ID:        3,
+		Account:   system.DefaultAccountID,
+		Category:  "groceries",
+		Period:    system.GroupByMonth,
+		Amount:    4000,
+		Rollover:  true,
+		CreatedAt: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func mockBudgetRow(rows *sqlmock.Rows, budget system.Budget) *sqlmock.Rows {
+	return rows.AddRow(budget.ID, budget.Account, budget.Category, budget.Period, budget.Amount, budget.Rollover, budget.CreatedAt)
+}
+
+func TestMySQLCreateBudget_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	budget := mockBudget()
+	mock.ExpectExec(queryCreateBudgetMock).
+		WithArgs(budget.Account, budget.Category, budget.Period, budget.Amount, budget.Rollover, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mysqlCreateBudget := system.MakeMySQLCreateBudget(db)
+
+	got, err := mysqlCreateBudget(context.Background(), budget)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), got.ID)
+	assert.False(t, got.CreatedAt.IsZero())
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLCreateBudget_fails(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"duplicate budget", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, system.ErrBudgetExists},
+		{"query fails", errors.New("connection refused"), system.ErrCantRunQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, _ := sqlmock.New()
+			mock.ExpectExec(queryCreateBudgetMock).WillReturnError(tt.err)
+			mysqlCreateBudget := system.MakeMySQLCreateBudget(db)
+
+			_, got := mysqlCreateBudget(context.Background(), mockBudget())
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQLFindBudget_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	budget := mockBudget()
+	mock.ExpectQuery(queryFindBudgetMock).WithArgs(budget.Account, budget.ID).WillReturnRows(mockBudgetRow(sqlmock.NewRows(budgetRowColumns), budget))
+	mysqlFindBudget := system.MakeMySQLFindBudget(db)
+
+	got, err := mysqlFindBudget(context.Background(), budget.Account, budget.ID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, budget, got)
+}
+
+func TestMySQLFindBudget_failsWhenBudgetDoesntExist(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindBudgetMock).WillReturnError(sql.ErrNoRows)
+	mysqlFindBudget := system.MakeMySQLFindBudget(db)
+
+	want := system.ErrBudgetNotFound
+	_, got := mysqlFindBudget(context.Background(), system.DefaultAccountID, 3)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLListBudgets_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	budget := mockBudget()
+	mock.ExpectQuery(queryListBudgetsMock).WithArgs(budget.Account).WillReturnRows(mockBudgetRow(sqlmock.NewRows(budgetRowColumns), budget))
+	mysqlListBudgets := system.MakeMySQLListBudgets(db)
+
+	got, err := mysqlListBudgets(context.Background(), budget.Account)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []system.Budget{budget}, got)
+}
+
+func TestMySQLListBudgets_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryListBudgetsMock).WillReturnError(errors.New("connection refused"))
+	mysqlListBudgets := system.MakeMySQLListBudgets(db)
+
+	want := system.ErrCantRunQuery
+	_, got := mysqlListBudgets(context.Background(), system.DefaultAccountID)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLUpdateBudget_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	budget := mockBudget()
+	mock.ExpectExec(queryUpdateBudgetMock).
+		WithArgs(budget.Category, budget.Period, budget.Amount, budget.Rollover, budget.Account, budget.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mysqlUpdateBudget := system.MakeMySQLUpdateBudget(db)
+
+	err := mysqlUpdateBudget(context.Background(), budget)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLUpdateBudget_failsWhenBudgetExists(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectExec(queryUpdateBudgetMock).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+	mysqlUpdateBudget := system.MakeMySQLUpdateBudget(db)
+
+	want := system.ErrBudgetExists
+	got := mysqlUpdateBudget(context.Background(), mockBudget())
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLDeleteBudget_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectExec(queryDeleteBudgetMock).WithArgs(system.DefaultAccountID, int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mysqlDeleteBudget := system.MakeMySQLDeleteBudget(db)
+
+	err := mysqlDeleteBudget(context.Background(), system.DefaultAccountID, 3)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLDeleteBudget_failsWhenBudgetDoesntExist(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectExec(queryDeleteBudgetMock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mysqlDeleteBudget := system.MakeMySQLDeleteBudget(db)
+
+	want := system.ErrBudgetNotFound
+	got := mysqlDeleteBudget(context.Background(), system.DefaultAccountID, 3)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLBudgetAlertSent_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	status := system.BudgetStatus{BudgetID: 3, Key: "2023-12", Alert: 80}
+	mock.ExpectQuery(queryBudgetAlertSentMock).WithArgs(int64(3), "2023-12", 80.0).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mysqlBudgetAlertSent := system.MakeMySQLBudgetAlertSent(db)
+
+	got, err := mysqlBudgetAlertSent(context.Background(), status)
+
+	assert.Nil(t, err)
+	assert.True(t, got)
+}
+
+func TestMySQLSaveBudgetAlert_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	status := system.BudgetStatus{BudgetID: 3, Key: "2023-12", Alert: 80}
+	mock.ExpectExec(querySaveBudgetAlertMock).WithArgs(int64(3), "2023-12", 80.0, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mysqlSaveBudgetAlert := system.MakeMySQLSaveBudgetAlert(db)
+
+	err := mysqlSaveBudgetAlert(context.Background(), status)
+
+	assert.Nil(t, err)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
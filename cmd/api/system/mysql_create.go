@@ -3,74 +3,121 @@ package system
 import (
 	"context"
 	"database/sql"
+	"log/slog"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 const (
-	queryCreate = "INSERT INTO stori.transactions (id, date, transaction, type) VALUES "
-	queryFind   = "SELECT MAX(id) FROM stori.transactions"
+	queryCreate           = "INSERT INTO stori.transactions (date, transaction, type, external_id, account, description, merchant, category, currency) VALUES "
+	queryCreateDuplicates = " ON DUPLICATE KEY UPDATE id = id"
+
+	queryFindTransactions = "SELECT id, date, transaction, type, external_id, account, description, merchant, category, currency FROM stori.transactions " +
+		"WHERE account = ? AND date BETWEEN ? AND ? ORDER BY date, id"
 )
 
 type (
-	// MySQLCreate is a function that creates a transaction in the database
-	MySQLCreate func(ctx context.Context, transactions []Transaction) error
+	// MySQLCreate is a function that creates transactions in the database and returns how many were inserted
+	MySQLCreate func(ctx context.Context, transactions []Transaction) (int64, error)
 
-	// MySQLFind is a function that finds the last id in the database
-	MySQLFind func(ctx context.Context) (int64, error)
+	// MySQLFindTransactions is a function that finds the transactions of an account between two dates
+	MySQLFindTransactions func(ctx context.Context, account string, from time.Time, to time.Time) ([]Transaction, error)
 )
 
-// MakeMySQLCreate creates a new MySQLCreate
-func MakeMySQLCreate(db *sql.DB, mySQLFind MySQLFind) MySQLCreate {
-	return func(ctx context.Context, transactions []Transaction) error {
-		lastID, err := mySQLFind(ctx)
-		if err != nil {
-			return ErrCantGetLastID
+// MakeMySQLCreate creates a new MySQLCreate. The database generates the id of every transaction, and a transaction
+// whose external id was already imported in its account is skipped, so importing a statement again doesn't
+// duplicate it. Skipped transactions aren't counted as inserted
+func MakeMySQLCreate(db *sql.DB, metrics *Metrics) MySQLCreate {
+	return func(ctx context.Context, transactions []Transaction) (int64, error) {
+		ctx, span := startSpan(ctx, "MySQLCreate", semconv.DBSystemMySQL, attribute.Int("db.rows", len(transactions)))
+		defer span.End()
+
+		if len(transactions) == 0 {
+			return 0, nil
 		}
 
-		if lastID < transactions[0].ID {
-			var inserts []string
-			var params []interface{}
+		var inserts []string
+		var params []interface{}
 
-			for _, t := range transactions {
-				inserts = append(inserts, "(?, ?, ?, ?)")
-				params = append(params, t.ID, t.Date, t.Transaction, t.Type)
-			}
+		for _, t := range transactions {
+			inserts = append(inserts, "(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			params = append(params, t.Date, t.Transaction, t.Type, nullString(t.ExternalID), t.Account, nullString(t.Description), nullString(t.Merchant), nullString(t.Category), nullString(t.Currency))
+		}
 
-			queryVals := strings.Join(inserts, ",")
-			query := queryCreate + queryVals
+		queryVals := strings.Join(inserts, ",")
+		query := queryCreate + queryVals + queryCreateDuplicates
 
-			stmt, err := db.PrepareContext(ctx, query)
-			if err != nil {
-				return ErrCantPrepareStatement
-			}
-			defer stmt.Close()
+		stmt, err := db.PrepareContext(ctx, query)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't prepare insert statement", slog.Any("error", err))
+			spanError(span, err)
+			return 0, ErrCantPrepareStatement
+		}
+		defer stmt.Close()
 
-			_, err = stmt.ExecContext(ctx, params...)
-			if err != nil {
-				return ErrCantRunQuery
-			}
+		start := time.Now()
+		result, err := stmt.ExecContext(ctx, params...)
+		metrics.DBInsertDuration.Observe(time.Since(start).Seconds())
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't insert transactions", slog.Int("rows", len(transactions)), slog.Any("error", err))
+			spanError(span, err)
+			return 0, ErrCantRunQuery
 		}
 
-		return nil
+		// duplicates are left as they are, so they don't count as affected rows
+		inserted, _ := result.RowsAffected()
+		metrics.DBRowsInserted.Add(float64(inserted))
+		span.SetAttributes(attribute.Int64("db.rows_inserted", inserted))
+
+		return inserted, nil
 	}
 }
 
-// MakeMySQLFind creates a new MySQLFind
-func MakeMySQLFind(db *sql.DB) MySQLFind {
-	return func(ctx context.Context) (int64, error) {
-		var lastID sql.NullInt64
-		err := db.QueryRow(queryFind).Scan(&lastID)
+// nullString stores empty strings as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// MakeMySQLFindTransactions creates a new MySQLFindTransactions
+func MakeMySQLFindTransactions(db *sql.DB) MySQLFindTransactions {
+	return func(ctx context.Context, account string, from time.Time, to time.Time) ([]Transaction, error) {
+		ctx, span := startSpan(ctx, "MySQLFindTransactions", semconv.DBSystemMySQL, semconv.DBStatement(queryFindTransactions))
+		defer span.End()
+
+		fail := func(msg string, err error) ([]Transaction, error) {
+			LoggerFrom(ctx).ErrorContext(ctx, msg, slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantRunQuery
+		}
+
+		rows, err := db.QueryContext(ctx, queryFindTransactions, account, from, to)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return -1, nil
-			}
+			return fail("can't find transactions", err)
+		}
+		defer rows.Close()
 
-			return -1, ErrCantRunQuery
+		transactions := make([]Transaction, 0)
+		for rows.Next() {
+			var t Transaction
+			var externalID, description, merchant, category, currency sql.NullString
+			if err := rows.Scan(&t.ID, &t.Date, &t.Transaction, &t.Type, &externalID, &t.Account, &description, &merchant, &category, &currency); err != nil {
+				return fail("can't read transaction", err)
+			}
+			t.ExternalID = externalID.String
+			t.Description = description.String
+			t.Merchant = merchant.String
+			t.Category = category.String
+			t.Currency = currency.String
+			transactions = append(transactions, t)
 		}
-		if lastID.Valid {
-			return lastID.Int64, nil
+		if err := rows.Err(); err != nil {
+			return fail("can't read transactions", err)
 		}
+		span.SetAttributes(attribute.Int("db.rows", len(transactions)))
 
-		return -1, nil
+		return transactions, nil
 	}
 }
@@ -2,147 +2,208 @@ package system_test
 
 import (
 	"context"
-	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/rromero96/stori/cmd/api/system"
 )
 
 const (
-	queryCreateMock string = "INSERT INTO stori.transactions \\(id, date, transaction, type\\) VALUES \\(\\?, \\?, \\?, \\?\\)"
-	queryFindMock   string = "SELECT MAX\\(id\\) FROM stori.transactions"
+	queryCreateMock string = "INSERT INTO stori.transactions \\(date, transaction, type, external_id, account, description, merchant, category, currency\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\).* ON DUPLICATE KEY UPDATE id = id"
+
+	queryFindTransactionsMock string = "SELECT id, date, transaction, type, external_id, account, description, merchant, category, currency FROM stori.transactions WHERE account = \\? AND date BETWEEN \\? AND \\? ORDER BY date, id"
 )
 
 func TestMakeMySQLCreate_success(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mysqlFindMock := system.MockMySQLFind(-1, nil)
 	mock.ExpectPrepare(queryCreateMock)
 	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 2))
 
-	got := system.MakeMySQLCreate(db, mysqlFindMock)
+	got := system.MakeMySQLCreate(db, system.NewMetricsNop())
 
 	assert.NotNil(t, got)
 }
 
 func TestMySQLCreate_success(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mysqlFindMock := system.MockMySQLFind(-1, nil)
 	mock.ExpectPrepare(queryCreateMock)
 	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 2))
 	transactions := []system.Transaction{system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5)}
 
-	mysqlCreate := system.MakeMySQLCreate(db, mysqlFindMock)
+	mysqlCreate := system.MakeMySQLCreate(db, system.NewMetricsNop())
 	ctx := context.Background()
 
-	got := mysqlCreate(ctx, transactions)
+	inserted, got := mysqlCreate(ctx, transactions)
 
 	assert.Nil(t, got)
+	assert.Equal(t, int64(2), inserted)
 }
 
-func TestMySQLCreate_failsWhenMySQLFindThrowsError(t *testing.T) {
+func TestMySQLCreate_recordsInsertMetrics(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mysqlFindMock := system.MockMySQLFind(0, system.ErrCantRunQuery)
 	mock.ExpectPrepare(queryCreateMock)
-	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 1))
 	transactions := []system.Transaction{system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5)}
+	metrics := system.NewMetricsNop()
 
-	mysqlCreate := system.MakeMySQLCreate(db, mysqlFindMock)
+	mysqlCreate := system.MakeMySQLCreate(db, metrics)
 	ctx := context.Background()
 
-	want := system.ErrCantGetLastID
-	got := mysqlCreate(ctx, transactions)
+	_, err := mysqlCreate(ctx, transactions)
 
-	assert.Equal(t, want, got)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.DBRowsInserted))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.DBInsertDuration))
+}
+
+// insertArgs returns the arguments of the insert of transactions with the given external ids in an account
+func insertArgs(account string, externalIDs ...string) []driver.Value {
+	var args []driver.Value
+	for _, externalID := range externalIDs {
+		args = append(args, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), externalID, account, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg())
+	}
+	return args
+}
+
+func TestMySQLCreate_insertsOFXStatementsIntoATableWithRows(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	// the first statement was imported before, so its transactions are duplicates and nothing is inserted
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("000123456789", "202312010001", "202312020001")...).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("4111111111111111", "CC-0001", "CC-0002")...).WillReturnResult(sqlmock.NewResult(42, 2))
+	metrics := system.NewMetricsNop()
+	readStatement := system.MakeReadStatement(system.MakeStreamCSV(metrics, system.MockImportProfiles()), system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, system.MakeMySQLCreate(db, metrics), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+
+	for _, name := range []string{"statement_v1.ofx", "statement_v2.qfx"} {
+		_, err := importStatement(context.Background(), filepath.Join("testdata", name), system.MockImportOptions())
+
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.DBRowsInserted))
+}
+
+func TestMySQLCreate_insertsCamt053StatementsIntoATableWithRows(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	// the table has rows from earlier imports, the second import of the same document only finds duplicates
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("ES9121000418450200051332", "INV-2023-118", "REF-0002")...).WillReturnResult(sqlmock.NewResult(57, 2))
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("ES9121000418450200051332", "INV-2023-118", "REF-0002")...).WillReturnResult(sqlmock.NewResult(0, 0))
+	metrics := system.NewMetricsNop()
+	readStatement := system.MakeReadStatement(system.MakeStreamCSV(metrics, system.MockImportProfiles()), system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, system.MakeMySQLCreate(db, metrics), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+
+	for i := 0; i < 2; i++ {
+		_, err := importStatement(context.Background(), filepath.Join("testdata", "statement.camt053.xml"), system.MockImportOptions())
+
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.DBRowsInserted))
+}
+
+func TestMySQLCreate_reimportsCamt053EntriesWithoutReferencesOnce(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	// the entries have no end-to-end id nor servicer reference, both imports send the same derived keys
+	keys := []string{"STMT-20231205-1/1/2023-12-05/-10.00/1", "STMT-20231205-1//2023-12-05/-10.00/2"}
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("ES9121000418450200051332", keys...)...).WillReturnResult(sqlmock.NewResult(58, 2))
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WithArgs(insertArgs("ES9121000418450200051332", keys...)...).WillReturnResult(sqlmock.NewResult(0, 0))
+	metrics := system.NewMetricsNop()
+	readStatement := system.MakeReadStatement(system.MakeStreamCSV(metrics, system.MockImportProfiles()), system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, system.MakeMySQLCreate(db, metrics), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+
+	for i := 0; i < 2; i++ {
+		_, err := importStatement(context.Background(), filepath.Join("testdata", "statement_without_references.camt053.xml"), system.MockImportOptions())
+
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, mock.ExpectationsWereMet())
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.DBRowsInserted))
 }
 
 func TestMySQLCreate_failsWhenCantPrepareStatement(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mysqlFindMock := system.MockMySQLFind(-1, nil)
 	mock.ExpectPrepare("invalid statement")
 	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 2))
 	transactions := []system.Transaction{system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5)}
 
-	mysqlCreate := system.MakeMySQLCreate(db, mysqlFindMock)
+	mysqlCreate := system.MakeMySQLCreate(db, system.NewMetricsNop())
 	ctx := context.Background()
 
 	want := system.ErrCantPrepareStatement
-	got := mysqlCreate(ctx, transactions)
+	_, got := mysqlCreate(ctx, transactions)
 
 	assert.Equal(t, want, got)
 }
 
 func TestMySQLCreate_failsWhenCantRunQuery(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mysqlFindMock := system.MockMySQLFind(-1, nil)
 	mock.ExpectPrepare(queryCreateMock)
 	mock.ExpectExec(queryCreateMock).WillReturnError(errors.New("some error"))
 	transactions := []system.Transaction{system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", +60.5)}
 
-	mysqlCreate := system.MakeMySQLCreate(db, mysqlFindMock)
+	mysqlCreate := system.MakeMySQLCreate(db, system.NewMetricsNop())
 	ctx := context.Background()
 
 	want := system.ErrCantRunQuery
-	got := mysqlCreate(ctx, transactions)
+	_, got := mysqlCreate(ctx, transactions)
 
 	assert.Equal(t, want, got)
 }
 
-func TestMakeMySQLFind_success(t *testing.T) {
+func TestMySQLCreate_successWithoutTransactions(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mock.ExpectPrepare(queryFindMock)
-	mock.ExpectExec(queryFindMock).WillReturnResult(sqlmock.NewResult(1, 2))
 
-	got := system.MakeMySQLFind(db)
-
-	assert.NotNil(t, got)
-}
-
-func TestMySQLFind_success(t *testing.T) {
-	db, mock, _ := sqlmock.New()
-	rows := mock.NewRows([]string{"MAX(id)"}).AddRow(10)
-	mock.ExpectQuery(queryFindMock).WillReturnRows(rows)
+	mysqlCreate := system.MakeMySQLCreate(db, system.NewMetricsNop())
 	ctx := context.Background()
 
-	mysqlFind := system.MakeMySQLFind(db)
-
-	want := int64(10)
-	got, err := mysqlFind(ctx)
+	inserted, got := mysqlCreate(ctx, nil)
 
-	assert.Nil(t, err)
-	assert.Equal(t, got, want)
+	assert.Nil(t, got)
+	assert.Zero(t, inserted)
+	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
-func TestMySQLFind_successWithNoRows(t *testing.T) {
+func TestMySQLFindTransactions_success(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	rows := mock.NewRows([]string{"MAX(id)"}).AddRow(-1)
-	mock.ExpectQuery(queryFindMock).WillReturnRows(rows)
-	mock.ExpectExec(queryCreateMock).WillReturnError(sql.ErrNoRows)
-	ctx := context.Background()
-
-	mysqlFind := system.MakeMySQLFind(db)
-
-	want := int64(-1)
-	got, err := mysqlFind(ctx)
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(queryFindTransactionsMock).WithArgs(system.DefaultAccountID, from, to).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "date", "transaction", "type", "external_id", "account", "description", "merchant", "category", "currency"}).
+			AddRow(1, from, -219.0, "debit", nil, system.DefaultAccountID, "NETFLIX.COM", nil, "subscriptions", "USD"),
+	)
+	mysqlFindTransactions := system.MakeMySQLFindTransactions(db)
+
+	want := system.MockTransaction(1, from, "debit", -219)
+	want.Account = system.DefaultAccountID
+	want.Description = "NETFLIX.COM"
+	want.Category = "subscriptions"
+	want.Currency = "USD"
+	got, err := mysqlFindTransactions(context.Background(), system.DefaultAccountID, from, to)
 
 	assert.Nil(t, err)
-	assert.Equal(t, got, want)
+	assert.Equal(t, []system.Transaction{want}, got)
 }
 
-func TestMySQLFind_failsWhenCantRunQuery(t *testing.T) {
+func TestMySQLFindTransactions_failsWhenQueryFails(t *testing.T) {
 	db, mock, _ := sqlmock.New()
-	mock.ExpectPrepare(queryFindMock)
-	mock.ExpectExec(queryFindMock).WillReturnError(errors.New("some error"))
-	ctx := context.Background()
-
-	mysqlFind := system.MakeMySQLFind(db)
+	mock.ExpectQuery(queryFindTransactionsMock).WillReturnError(errors.New("connection refused"))
+	mysqlFindTransactions := system.MakeMySQLFindTransactions(db)
 
 	want := system.ErrCantRunQuery
-	_, got := mysqlFind(ctx)
+	_, got := mysqlFindTransactions(context.Background(), system.DefaultAccountID, time.Now(), time.Now())
 
-	assert.Equal(t, got, want)
+	assert.Equal(t, want, got)
 }
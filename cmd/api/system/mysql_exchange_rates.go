@@ -0,0 +1,47 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const queryFindExchangeRates = "SELECT date, from_currency, to_currency, rate FROM stori.exchange_rates ORDER BY date"
+
+// MakeMySQLGetExchangeRates creates a GetExchangeRates function that reads the rates from the exchange_rates table
+func MakeMySQLGetExchangeRates(db *sql.DB) GetExchangeRates {
+	return func(ctx context.Context) (ExchangeRates, error) {
+		ctx, span := startSpan(ctx, "MySQLGetExchangeRates", semconv.DBSystemMySQL, semconv.DBStatement(queryFindExchangeRates))
+		defer span.End()
+
+		fail := func(msg string, err error) (ExchangeRates, error) {
+			LoggerFrom(ctx).ErrorContext(ctx, msg, slog.Any("error", err))
+			spanError(span, err)
+			return ExchangeRates{}, ErrCantGetExchangeRates
+		}
+
+		rows, err := db.QueryContext(ctx, queryFindExchangeRates)
+		if err != nil {
+			return fail("can't find exchange rates", err)
+		}
+		defer rows.Close()
+
+		var rates []ExchangeRate
+		for rows.Next() {
+			var rate ExchangeRate
+			if err := rows.Scan(&rate.Date, &rate.From, &rate.To, &rate.Rate); err != nil {
+				return fail("can't read exchange rate", err)
+			}
+			rates = append(rates, rate)
+		}
+		if err := rows.Err(); err != nil {
+			return fail("can't read exchange rates", err)
+		}
+		span.SetAttributes(attribute.Int("db.rows", len(rates)))
+
+		return NewExchangeRates(rates), nil
+	}
+}
@@ -0,0 +1,45 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+const queryFindExchangeRatesMock string = "SELECT date, from_currency, to_currency, rate FROM stori.exchange_rates ORDER BY date"
+
+func TestMySQLGetExchangeRates_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	date := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(queryFindExchangeRatesMock).WillReturnRows(
+		sqlmock.NewRows([]string{"date", "from_currency", "to_currency", "rate"}).
+			AddRow(date, "USD", "MXN", 17.3).
+			AddRow(date, "EUR", "MXN", 18.9),
+	)
+	mysqlGetExchangeRates := system.MakeMySQLGetExchangeRates(db)
+
+	got, err := mysqlGetExchangeRates(context.Background())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, got.Len())
+	rate, ok := got.Rate("EUR", "MXN", date)
+	assert.True(t, ok)
+	assert.Equal(t, 18.9, rate)
+}
+
+func TestMySQLGetExchangeRates_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindExchangeRatesMock).WillReturnError(errors.New("connection refused"))
+	mysqlGetExchangeRates := system.MakeMySQLGetExchangeRates(db)
+
+	want := system.ErrCantGetExchangeRates
+	_, got := mysqlGetExchangeRates(context.Background())
+
+	assert.Equal(t, want, got)
+}
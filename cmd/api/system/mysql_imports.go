@@ -0,0 +1,92 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	ImportStatusImporting string = "importing"
+	ImportStatusProcessed string = "processed"
+	ImportStatusFailed    string = "failed"
+
+	queryFindImport = "SELECT filename, status, error, transactions, started_at, finished_at FROM stori.imports WHERE checksum = ?"
+	querySaveImport = "INSERT INTO stori.imports (checksum, filename, status, error, transactions, started_at, finished_at) VALUES (?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE filename = VALUES(filename), status = VALUES(status), error = VALUES(error), " +
+		"transactions = VALUES(transactions), started_at = VALUES(started_at), finished_at = VALUES(finished_at)"
+)
+
+type (
+	// ImportRecord is the result of importing a file of the drop folder
+	ImportRecord struct {
+		Checksum     string
+		Filename     string
+		Status       string
+		Error        string
+		Transactions int
+		StartedAt    time.Time
+		FinishedAt   time.Time
+	}
+
+	// MySQLFindImport is a function that finds the import of a file by the checksum of its content
+	MySQLFindImport func(ctx context.Context, checksum string) (ImportRecord, error)
+
+	// MySQLSaveImport is a function that creates or updates the import of a file
+	MySQLSaveImport func(ctx context.Context, record ImportRecord) error
+)
+
+// MakeMySQLFindImport creates a new MySQLFindImport. It returns ErrImportNotFound when the file wasn't imported
+func MakeMySQLFindImport(db *sql.DB) MySQLFindImport {
+	return func(ctx context.Context, checksum string) (ImportRecord, error) {
+		ctx, span := startSpan(ctx, "MySQLFindImport", semconv.DBSystemMySQL, semconv.DBStatement(queryFindImport))
+		defer span.End()
+
+		record := ImportRecord{Checksum: checksum}
+		var importError sql.NullString
+		var finishedAt sql.NullTime
+		err := db.QueryRowContext(ctx, queryFindImport, checksum).
+			Scan(&record.Filename, &record.Status, &importError, &record.Transactions, &record.StartedAt, &finishedAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ImportRecord{}, ErrImportNotFound
+		}
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find import", slog.String("checksum", checksum), slog.Any("error", err))
+			spanError(span, err)
+			return ImportRecord{}, ErrCantRunQuery
+		}
+		record.Error = importError.String
+		record.FinishedAt = finishedAt.Time
+
+		return record, nil
+	}
+}
+
+// MakeMySQLSaveImport creates a new MySQLSaveImport
+func MakeMySQLSaveImport(db *sql.DB) MySQLSaveImport {
+	return func(ctx context.Context, record ImportRecord) error {
+		ctx, span := startSpan(ctx, "MySQLSaveImport", semconv.DBSystemMySQL, semconv.DBStatement(querySaveImport))
+		defer span.End()
+
+		_, err := db.ExecContext(ctx, querySaveImport,
+			record.Checksum,
+			record.Filename,
+			record.Status,
+			nullString(record.Error),
+			record.Transactions,
+			record.StartedAt,
+			sql.NullTime{Time: record.FinishedAt, Valid: !record.FinishedAt.IsZero()},
+		)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't save import", slog.String("checksum", record.Checksum), slog.Any("error", err))
+			spanError(span, err)
+			return ErrCantRunQuery
+		}
+
+		return nil
+	}
+}
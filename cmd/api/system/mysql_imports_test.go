@@ -0,0 +1,95 @@
+package system_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+const (
+	queryFindImportMock string = "SELECT filename, status, error, transactions, started_at, finished_at FROM stori.imports WHERE checksum = \\?"
+	querySaveImportMock string = "INSERT INTO stori.imports \\(checksum, filename, status, error, transactions, started_at, finished_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?\\) ON DUPLICATE KEY UPDATE"
+)
+
+func TestMySQLFindImport_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	startedAt := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Minute)
+	mock.ExpectQuery(queryFindImportMock).WithArgs("abc").WillReturnRows(
+		sqlmock.NewRows([]string{"filename", "status", "error", "transactions", "started_at", "finished_at"}).
+			AddRow("data.csv", system.ImportStatusProcessed, nil, 21, startedAt, finishedAt),
+	)
+	mysqlFindImport := system.MakeMySQLFindImport(db)
+
+	want := system.ImportRecord{
+		Checksum:     "abc",
+		Filename:     "data.csv",
+		Status:       system.ImportStatusProcessed,
+		Transactions: 21,
+		StartedAt:    startedAt,
+		FinishedAt:   finishedAt,
+	}
+	got, err := mysqlFindImport(context.Background(), "abc")
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLFindImport_failsWhenImportDoesntExist(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindImportMock).WillReturnError(sql.ErrNoRows)
+	mysqlFindImport := system.MakeMySQLFindImport(db)
+
+	want := system.ErrImportNotFound
+	_, got := mysqlFindImport(context.Background(), "abc")
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLFindImport_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindImportMock).WillReturnError(errors.New("connection refused"))
+	mysqlFindImport := system.MakeMySQLFindImport(db)
+
+	want := system.ErrCantRunQuery
+	_, got := mysqlFindImport(context.Background(), "abc")
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLSaveImport_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	startedAt := time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(querySaveImportMock).
+		WithArgs("abc", "data.csv", system.ImportStatusImporting, sql.NullString{}, 0, startedAt, sql.NullTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mysqlSaveImport := system.MakeMySQLSaveImport(db)
+
+	got := mysqlSaveImport(context.Background(), system.ImportRecord{
+		Checksum:  "abc",
+		Filename:  "data.csv",
+		Status:    system.ImportStatusImporting,
+		StartedAt: startedAt,
+	})
+
+	assert.Nil(t, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLSaveImport_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectExec(querySaveImportMock).WillReturnError(errors.New("connection refused"))
+	mysqlSaveImport := system.MakeMySQLSaveImport(db)
+
+	want := system.ErrCantRunQuery
+	got := mysqlSaveImport(context.Background(), system.ImportRecord{Checksum: "abc"})
+
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,162 @@
+package system
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// MaxListedStatements is the number of statements returned by MySQLListStatements, the latest first
+const MaxListedStatements int = 100
+
+const (
+	statementColumns = "id, account, period_start, period_end, opening_balance, closing_balance, summary, issued_at"
+
+	queryIssueStatement = "INSERT IGNORE INTO stori.statements (account, period_start, period_end, opening_balance, closing_balance, summary, issued_at) " +
+		"VALUES (?, ?, ?, ?, ?, ?, ?)"
+	queryFindIssuedStatement = "SELECT " + statementColumns + " FROM stori.statements WHERE account = ? AND period_start = ? AND period_end = ?"
+	queryFindStatement       = "SELECT " + statementColumns + " FROM stori.statements WHERE account = ? AND id = ?"
+	queryListStatements      = "SELECT " + statementColumns + " FROM stori.statements WHERE account = ? ORDER BY period_end DESC, id DESC LIMIT ?"
+)
+
+type (
+	// MySQLIssueStatement is a function that saves the statement of an account for its period, unless one was
+	// already issued, and returns the statement stored in the database
+	MySQLIssueStatement func(ctx context.Context, statement Statement) (Statement, error)
+
+	// MySQLFindStatement is a function that finds an issued statement of an account
+	MySQLFindStatement func(ctx context.Context, account string, id int64) (Statement, error)
+
+	// MySQLListStatements is a function that lists the issued statements of an account, the latest first
+	MySQLListStatements func(ctx context.Context, account string) ([]Statement, error)
+)
+
+// MakeMySQLIssueStatement creates a new MySQLIssueStatement. Issued statements are never updated: issuing the
+// statement of the same account and period again returns the one issued first
+func MakeMySQLIssueStatement(db *sql.DB) MySQLIssueStatement {
+	return func(ctx context.Context, statement Statement) (Statement, error) {
+		ctx, span := startSpan(ctx, "MySQLIssueStatement", semconv.DBSystemMySQL, attribute.String("statement.account", statement.Account))
+		defer span.End()
+
+		summary, err := json.Marshal(statement.Summary)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't encode statement summary", slog.Any("error", err))
+			spanError(span, err)
+			return Statement{}, ErrCantIssueStatement
+		}
+
+		_, err = db.ExecContext(ctx, queryIssueStatement,
+			statement.Account,
+			statement.Period.Start,
+			statement.Period.End,
+			statement.OpeningBalance,
+			statement.ClosingBalance,
+			summary,
+			statement.IssuedAt,
+		)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't issue statement", slog.String("account", statement.Account), slog.Any("error", err))
+			spanError(span, err)
+			return Statement{}, ErrCantRunQuery
+		}
+
+		issued, err := scanStatement(db.QueryRowContext(ctx, queryFindIssuedStatement, statement.Account, statement.Period.Start, statement.Period.End))
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find issued statement", slog.String("account", statement.Account), slog.Any("error", err))
+			spanError(span, err)
+			return Statement{}, ErrCantRunQuery
+		}
+		span.SetAttributes(attribute.Int64("statement.id", issued.ID))
+
+		return issued, nil
+	}
+}
+
+// MakeMySQLFindStatement creates a new MySQLFindStatement. It returns ErrStatementNotFound when the account doesn't
+// have a statement with that id
+func MakeMySQLFindStatement(db *sql.DB) MySQLFindStatement {
+	return func(ctx context.Context, account string, id int64) (Statement, error) {
+		ctx, span := startSpan(ctx, "MySQLFindStatement", semconv.DBSystemMySQL, semconv.DBStatement(queryFindStatement))
+		defer span.End()
+
+		statement, err := scanStatement(db.QueryRowContext(ctx, queryFindStatement, account, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return Statement{}, ErrStatementNotFound
+		}
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find statement", slog.String("account", account), slog.Int64("id", id), slog.Any("error", err))
+			spanError(span, err)
+			return Statement{}, ErrCantRunQuery
+		}
+
+		return statement, nil
+	}
+}
+
+// MakeMySQLListStatements creates a new MySQLListStatements
+func MakeMySQLListStatements(db *sql.DB) MySQLListStatements {
+	return func(ctx context.Context, account string) ([]Statement, error) {
+		ctx, span := startSpan(ctx, "MySQLListStatements", semconv.DBSystemMySQL, semconv.DBStatement(queryListStatements))
+		defer span.End()
+
+		rows, err := db.QueryContext(ctx, queryListStatements, account, MaxListedStatements)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't list statements", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantRunQuery
+		}
+		defer rows.Close()
+
+		statements := make([]Statement, 0)
+		for rows.Next() {
+			statement, err := scanStatement(rows)
+			if err != nil {
+				LoggerFrom(ctx).ErrorContext(ctx, "can't read statement", slog.String("account", account), slog.Any("error", err))
+				spanError(span, err)
+				return nil, ErrCantRunQuery
+			}
+			statements = append(statements, statement)
+		}
+		if err := rows.Err(); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read statements", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantRunQuery
+		}
+
+		return statements, nil
+	}
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanStatement reads a row of statementColumns
+func scanStatement(row rowScanner) (Statement, error) {
+	var statement Statement
+	var summary []byte
+	err := row.Scan(
+		&statement.ID,
+		&statement.Account,
+		&statement.Period.Start,
+		&statement.Period.End,
+		&statement.OpeningBalance,
+		&statement.ClosingBalance,
+		&summary,
+		&statement.IssuedAt,
+	)
+	if err != nil {
+		return Statement{}, err
+	}
+	if err := json.Unmarshal(summary, &statement.Summary); err != nil {
+		return Statement{}, err
+	}
+
+	return statement, nil
+}
@@ -0,0 +1,148 @@
+package system_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+const (
+	queryIssueStatementMock      string = "INSERT IGNORE INTO stori.statements \\(account, period_start, period_end, opening_balance, closing_balance, summary, issued_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?\\)"
+	queryFindIssuedStatementMock string = "SELECT .+ FROM stori.statements WHERE account = \\? AND period_start = \\? AND period_end = \\?"
+	queryFindStatementMock       string = "SELECT .+ FROM stori.statements WHERE account = \\? AND id = \\?"
+	queryListStatementsMock      string = "SELECT .+ FROM stori.statements WHERE account = \\? ORDER BY period_end DESC, id DESC LIMIT \\?"
+)
+
+var statementRowColumns = []string{"id", "account", "period_start", "period_end", "opening_balance", "closing_balance", "summary", "issued_at"}
+
+func mockStatement() system.Statement {
+	return system.Statement{
+		ID:             7,
+		Account:        system.DefaultAccountID,
+		Period:         system.NewStatementPeriod(time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)),
+		OpeningBalance: 1000,
+		ClosingBalance: 1264.7,
+		Summary:        system.Email{Balance: 264.7, OpeningBalance: 1000, ClosingBalance: 1264.7, Periods: []system.Period{}, Spending: []system.CategorySpend{}, Recurring: []system.Recurring{}, Anomalies: []system.Anomaly{}, CountSpikes: []system.CountSpike{}, Budgets: []system.BudgetStatus{}},
+		IssuedAt:       time.Date(2023, 12, 31, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func mockStatementRow(rows *sqlmock.Rows, statement system.Statement, summary string) *sqlmock.Rows {
+	return rows.AddRow(statement.ID, statement.Account, statement.Period.Start, statement.Period.End, statement.OpeningBalance, statement.ClosingBalance, []byte(summary), statement.IssuedAt)
+}
+
+const mockStatementSummary string = `{"balance":264.7,"opening_balance":1000,"closing_balance":1264.7,"average_debit":0,"average_credit":0,"periods":[],"spending":[],"recurring":[],"anomalies":[],"count_spikes":[],"budgets":[]}`
+
+func TestMySQLIssueStatement_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	statement := mockStatement()
+	mock.ExpectExec(queryIssueStatementMock).
+		WithArgs(statement.Account, statement.Period.Start, statement.Period.End, 1000.0, 1264.7, []byte(mockStatementSummary), statement.IssuedAt).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectQuery(queryFindIssuedStatementMock).
+		WithArgs(statement.Account, statement.Period.Start, statement.Period.End).
+		WillReturnRows(mockStatementRow(sqlmock.NewRows(statementRowColumns), statement, mockStatementSummary))
+	mysqlIssueStatement := system.MakeMySQLIssueStatement(db)
+
+	got, err := mysqlIssueStatement(context.Background(), statement)
+
+	assert.Nil(t, err)
+	assert.Equal(t, statement, got)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLIssueStatement_returnsTheStatementIssuedFirst(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	issued := mockStatement()
+	reissued := mockStatement()
+	reissued.ID = 0
+	reissued.ClosingBalance = 1300
+	reissued.Summary.ClosingBalance = 1300
+	mock.ExpectExec(queryIssueStatementMock).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(queryFindIssuedStatementMock).
+		WillReturnRows(mockStatementRow(sqlmock.NewRows(statementRowColumns), issued, mockStatementSummary))
+	mysqlIssueStatement := system.MakeMySQLIssueStatement(db)
+
+	got, err := mysqlIssueStatement(context.Background(), reissued)
+
+	assert.Nil(t, err)
+	assert.Equal(t, issued, got)
+}
+
+func TestMySQLIssueStatement_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectExec(queryIssueStatementMock).WillReturnError(errors.New("connection refused"))
+	mysqlIssueStatement := system.MakeMySQLIssueStatement(db)
+
+	want := system.ErrCantRunQuery
+	_, got := mysqlIssueStatement(context.Background(), mockStatement())
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLFindStatement_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	statement := mockStatement()
+	mock.ExpectQuery(queryFindStatementMock).WithArgs(statement.Account, statement.ID).
+		WillReturnRows(mockStatementRow(sqlmock.NewRows(statementRowColumns), statement, mockStatementSummary))
+	mysqlFindStatement := system.MakeMySQLFindStatement(db)
+
+	got, err := mysqlFindStatement(context.Background(), statement.Account, statement.ID)
+
+	assert.Nil(t, err)
+	assert.Equal(t, statement, got)
+}
+
+func TestMySQLFindStatement_failsWhenStatementDoesntExist(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindStatementMock).WillReturnError(sql.ErrNoRows)
+	mysqlFindStatement := system.MakeMySQLFindStatement(db)
+
+	want := system.ErrStatementNotFound
+	_, got := mysqlFindStatement(context.Background(), system.DefaultAccountID, 7)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLFindStatement_failsWhenSummaryIsCorrupt(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryFindStatementMock).
+		WillReturnRows(mockStatementRow(sqlmock.NewRows(statementRowColumns), mockStatement(), "{"))
+	mysqlFindStatement := system.MakeMySQLFindStatement(db)
+
+	want := system.ErrCantRunQuery
+	_, got := mysqlFindStatement(context.Background(), system.DefaultAccountID, 7)
+
+	assert.Equal(t, want, got)
+}
+
+func TestMySQLListStatements_success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	statement := mockStatement()
+	mock.ExpectQuery(queryListStatementsMock).WithArgs(statement.Account, system.MaxListedStatements).
+		WillReturnRows(mockStatementRow(sqlmock.NewRows(statementRowColumns), statement, mockStatementSummary))
+	mysqlListStatements := system.MakeMySQLListStatements(db)
+
+	got, err := mysqlListStatements(context.Background(), statement.Account)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []system.Statement{statement}, got)
+}
+
+func TestMySQLListStatements_failsWhenQueryFails(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	mock.ExpectQuery(queryListStatementsMock).WillReturnError(errors.New("connection refused"))
+	mysqlListStatements := system.MakeMySQLListStatements(db)
+
+	want := system.ErrCantRunQuery
+	_, got := mysqlListStatements(context.Background(), system.DefaultAccountID)
+
+	assert.Equal(t, want, got)
+}
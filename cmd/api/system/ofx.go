@@ -0,0 +1,267 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	ofxDateLayout string = "20060102"
+	ofxTimeLayout string = "20060102150405"
+)
+
+type (
+	// ReadOFX is a function that reads OFX or QFX content and returns a slice of transactions
+	ReadOFX func(ctx context.Context, r io.Reader, opts ImportOptions) ([]Transaction, error)
+
+	// ofxAggregate is an OFX element that contains other elements. Seq tells apart aggregates with the same name
+	ofxAggregate struct {
+		name string
+		seq  int
+	}
+
+	// ofxElement is a leaf element of an OFX document, with the aggregates that contain it
+	ofxElement struct {
+		parents []ofxAggregate
+		name    string
+		value   string
+	}
+)
+
+// MakeReadOFX creates a ReadOFX function that accepts OFX 1.x (SGML) and 2.x (XML) statements. Transactions get
+// their position in the file as ID and the FITID as external id. Transactions that can't be parsed are skipped
+func MakeReadOFX() ReadOFX {
+	return func(ctx context.Context, r io.Reader, opts ImportOptions) ([]Transaction, error) {
+		ctx, span := startSpan(ctx, "ReadOFX")
+		defer span.End()
+
+		content, err := io.ReadAll(r)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read ofx", slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrReadingStatement
+		}
+
+		elements, err := parseOFX(content)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read ofx", slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrReadingStatement
+		}
+
+		var account, currency string
+		var transactions []Transaction
+		var current map[string]string
+		currentSeq := -1
+		var rejected int
+		flush := func() {
+			if current == nil {
+				return
+			}
+			transaction, err := ofxTransaction(current, opts.Location)
+			current = nil
+			if err != nil {
+				LoggerFrom(ctx).WarnContext(ctx, "rejected ofx transaction", slog.String("fitid", transaction.ExternalID), slog.Any("error", err))
+				rejected++
+				return
+			}
+			transactions = append(transactions, transaction)
+		}
+
+		for _, element := range elements {
+			if trn, ok := element.within("STMTTRN"); ok {
+				if trn.seq != currentSeq {
+					flush()
+					current = map[string]string{}
+					currentSeq = trn.seq
+				}
+				current[element.name] = element.value
+				continue
+			}
+
+			_, inBankAccount := element.within("BANKACCTFROM")
+			_, inCardAccount := element.within("CCACCTFROM")
+			switch {
+			case element.name == "CURDEF":
+				currency = element.value
+			case element.name == "ACCTID" && (inBankAccount || inCardAccount):
+				account = element.value
+			}
+		}
+		flush()
+
+		for i := range transactions {
+			transactions[i].Account = account
+			transactions[i].Currency = currency
+		}
+
+		span.SetAttributes(
+			attribute.String("ofx.account", account),
+			attribute.Int("ofx.rows_parsed", len(transactions)),
+			attribute.Int("ofx.rows_rejected", rejected),
+		)
+		return transactions, nil
+	}
+}
+
+// ofxTransaction turns the fields of a STMTTRN aggregate into a transaction
+func ofxTransaction(fields map[string]string, loc *time.Location) (Transaction, error) {
+	date, err := parseOFXDate(fields["DTPOSTED"], loc)
+	if err != nil {
+		return Transaction{ExternalID: fields["FITID"]}, err
+	}
+
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(fields["TRNAMT"], ",", "."), 64)
+	if err != nil {
+		return Transaction{ExternalID: fields["FITID"]}, ErrInvalidAmount
+	}
+
+	description := fields["MEMO"]
+	if description == "" {
+		description = fields["NAME"]
+	}
+
+	transaction := Transaction{
+		Date:        date,
+		Transaction: amount,
+		ExternalID:  fields["FITID"],
+		Description: description,
+		Merchant:    fields["NAME"],
+	}
+
+	transaction.Type = "credit"
+	if amount < 0 {
+		transaction.Type = "debit"
+	}
+
+	return transaction, nil
+}
+
+// parseOFXDate parses an OFX datetime such as 20230104, 20230104120000 or 20230104120000.000[-5:EST] and
+// returns its calendar date in loc as midnight UTC. Datetimes without offset are in GMT, as the spec says
+func parseOFXDate(value string, loc *time.Location) (time.Time, error) {
+	if len(value) < len(ofxDateLayout) {
+		return time.Time{}, ErrInvalidDate
+	}
+
+	offset := 0
+	if i := strings.IndexByte(value, '['); i >= 0 {
+		tz := strings.TrimSuffix(value[i+1:], "]")
+		if j := strings.IndexByte(tz, ':'); j >= 0 {
+			tz = tz[:j]
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		offset = int(hours * 60 * 60)
+		value = value[:i]
+	}
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		value = value[:i]
+	}
+
+	if len(value) == len(ofxDateLayout) {
+		date, err := time.Parse(ofxDateLayout, value)
+		if err != nil {
+			return time.Time{}, ErrInvalidDate
+		}
+		return date, nil
+	}
+
+	if len(value) != len(ofxTimeLayout) {
+		return time.Time{}, ErrInvalidDate
+	}
+	date, err := time.ParseInLocation(ofxTimeLayout, value, time.FixedZone("", offset))
+	if err != nil {
+		return time.Time{}, ErrInvalidDate
+	}
+	date = date.In(loc)
+
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseOFX returns the leaf elements of an OFX document. It accepts the SGML syntax of OFX 1.x, where leaf
+// elements aren't closed, as well as the XML syntax of OFX 2.x
+func parseOFX(content []byte) ([]ofxElement, error) {
+	start := bytes.Index(bytes.ToUpper(content), []byte("<OFX>"))
+	if start < 0 {
+		return nil, ErrUnknownStatementFormat
+	}
+	body := string(content[start:])
+
+	var elements []ofxElement
+	var stack []ofxAggregate
+	seq := 0
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end < 0 {
+			return nil, ErrReadingStatement
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+
+		if strings.HasPrefix(tag, "/") {
+			name := tag[1:]
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") || strings.HasSuffix(tag, "/") {
+			continue
+		}
+
+		next := strings.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(body[:next])
+		if value == "" {
+			stack = append(stack, ofxAggregate{name: tag, seq: seq})
+			seq++
+			continue
+		}
+
+		parents := make([]ofxAggregate, len(stack))
+		copy(parents, stack)
+		elements = append(elements, ofxElement{parents: parents, name: tag, value: html.UnescapeString(value)})
+	}
+
+	if len(elements) == 0 {
+		return nil, ErrReadingStatement
+	}
+
+	return elements, nil
+}
+
+// within returns the innermost aggregate with the given name that contains the element
+func (e ofxElement) within(name string) (ofxAggregate, bool) {
+	for i := len(e.parents) - 1; i >= 0; i-- {
+		if e.parents[i].name == name {
+			return e.parents[i], true
+		}
+	}
+	return ofxAggregate{}, false
+}
+
+// isOFX reports whether the beginning of a file looks like an OFX or QFX statement
+func isOFX(head []byte) bool {
+	upper := bytes.ToUpper(head)
+	return bytes.Contains(upper, []byte("OFXHEADER")) || bytes.Contains(upper, []byte("<OFX>"))
+}
@@ -0,0 +1,88 @@
+package system_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func TestReadOFX_successWithSGML(t *testing.T) {
+	file := openTestdata(t, "statement_v1.ofx")
+	readOFX := system.MakeReadOFX()
+	ctx := context.Background()
+
+	want := []system.Transaction{
+		{Date: time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), Transaction: 15000.5, Type: "credit", ExternalID: "202312010001", Description: "Salary December", Merchant: "PAYROLL", Account: "000123456789", Currency: "MXN"},
+		{Date: time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), Transaction: -8500, Type: "debit", ExternalID: "202312020001", Description: "RENT & SERVICES", Merchant: "RENT & SERVICES", Account: "000123456789", Currency: "MXN"},
+	}
+	got, err := readOFX(ctx, file, system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadOFX_successWithXML(t *testing.T) {
+	file := openTestdata(t, "statement_v2.qfx")
+	readOFX := system.MakeReadOFX()
+	ctx := context.Background()
+
+	want := []system.Transaction{
+		{Date: time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), Transaction: -42.99, Type: "debit", ExternalID: "CC-0001", Description: "Monthly subscription", Merchant: "STREAMING CO", Account: "4111111111111111", Currency: "USD"},
+		{Date: time.Date(2023, 12, 20, 0, 0, 0, 0, time.UTC), Transaction: 100, Type: "credit", ExternalID: "CC-0002", Description: "PAYMENT", Merchant: "PAYMENT", Account: "4111111111111111", Currency: "USD"},
+	}
+	got, err := readOFX(ctx, file, system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadOFX_convertsPostedTimeToAccountTimezone(t *testing.T) {
+	file := openTestdata(t, "statement_v1.ofx")
+	readOFX := system.MakeReadOFX()
+	opts := system.MockImportOptions()
+	opts.Location = time.FixedZone("UTC-10", -10*60*60)
+
+	got, err := readOFX(context.Background(), file, opts)
+
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), got[1].Date)
+}
+
+func TestReadOFX_failsWhenCantRead(t *testing.T) {
+	readOFX := system.MakeReadOFX()
+
+	want := system.ErrReadingStatement
+	_, got := readOFX(context.Background(), iotest.ErrReader(errors.New("disk failure")), system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadOFX_failsWhenFileIsNotOFX(t *testing.T) {
+	content := strings.NewReader("OFXHEADER:100\r\n\r\nnothing here")
+	readOFX := system.MakeReadOFX()
+
+	want := system.ErrReadingStatement
+	_, got := readOFX(context.Background(), content, system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+// openTestdata opens a file of the testdata folder, closing it when the test ends
+func openTestdata(t *testing.T, name string) *os.File {
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
@@ -0,0 +1,100 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GroupByWeek    string = "week"
+	GroupByMonth   string = "month"
+	GroupByQuarter string = "quarter"
+	GroupByYear    string = "year"
+
+	DefaultGrouping = GroupByMonth
+)
+
+type (
+	// Period holds the aggregates of the transactions of a week, month, quarter or year. Key identifies the period
+	// as 2024-W05, 2024-01, 2024-Q1 or 2024, and Start and End are its first and last calendar dates
+	Period struct {
+		Key     string    `json:"key"`
+		Start   time.Time `json:"start"`
+		End     time.Time `json:"end"`
+		Count   int       `json:"count"`
+		Credit  float64   `json:"credit"`
+		Debit   float64   `json:"debit"`
+		Net     float64   `json:"net"`
+		Average float64   `json:"average"`
+	}
+)
+
+// ParseGrouping returns the grouping of the periods of a summary. An empty name is a grouping by month
+func ParseGrouping(name string) (string, error) {
+	switch grouping := strings.ToLower(strings.TrimSpace(name)); grouping {
+	case "":
+		return DefaultGrouping, nil
+	case GroupByWeek, GroupByMonth, GroupByQuarter, GroupByYear:
+		return grouping, nil
+	default:
+		return "", ErrUnknownGrouping
+	}
+}
+
+// newPeriod returns the empty period of the given grouping that contains the calendar date of t. ISO 8601 weeks
+// start on Monday and belong to the year of their Thursday
+func newPeriod(t time.Time, grouping string) Period {
+	date := calendarDate(t)
+
+	var period Period
+	switch grouping {
+	case GroupByWeek:
+		period.Start = date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+		period.End = period.Start.AddDate(0, 0, 6)
+		year, week := period.Start.ISOWeek()
+		period.Key = fmt.Sprintf("%d-W%02d", year, week)
+	case GroupByQuarter:
+		quarter := (int(date.Month()) - 1) / 3
+		period.Start = time.Date(date.Year(), time.Month(quarter*3+1), 1, 0, 0, 0, 0, time.UTC)
+		period.End = period.Start.AddDate(0, 3, -1)
+		period.Key = fmt.Sprintf("%d-Q%d", date.Year(), quarter+1)
+	case GroupByYear:
+		period.Start = time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		period.End = period.Start.AddDate(1, 0, -1)
+		period.Key = period.Start.Format("2006")
+	default:
+		period.Start = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		period.End = period.Start.AddDate(0, 1, -1)
+		period.Key = period.Start.Format("2006-01")
+	}
+
+	return period
+}
+
+// add accumulates the transaction in the period
+func (p *Period) add(t Transaction) {
+	p.Count++
+	p.Net += t.Transaction
+	if t.Type == "debit" {
+		p.Debit += t.Transaction
+	}
+	if t.Type == "credit" {
+		p.Credit += t.Transaction
+	}
+	p.Average = p.Net / float64(p.Count)
+}
+
+// sortedPeriods returns the periods in chronological order
+func sortedPeriods(periods map[string]Period) []Period {
+	sorted := make([]Period, 0, len(periods))
+	for _, period := range periods {
+		sorted = append(sorted, period)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	return sorted
+}
@@ -0,0 +1,125 @@
+package system_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockPeriodTransactions() []system.Transaction {
+	return []system.Transaction{
+		system.MockTransaction(0, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), "credit", 100),
+		system.MockTransaction(1, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), "debit", -30),
+		system.MockTransaction(2, time.Date(2023, 2, 10, 0, 0, 0, 0, time.UTC), "credit", 50),
+		system.MockTransaction(3, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", 20),
+	}
+}
+
+func summarizePeriods(grouping string, transactions []system.Transaction) []system.Period {
+	summary := system.Summary{Grouping: grouping}
+	for _, t := range transactions {
+		summary.Add(t)
+	}
+	return summary.Email().Periods
+}
+
+func TestSummary_Periods_byMonthInCalendarOrder(t *testing.T) {
+	want := []system.Period{
+		{
+			Key:     "2023-01",
+			Start:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC),
+			Count:   2,
+			Credit:  20,
+			Debit:   -30,
+			Net:     -10,
+			Average: -5,
+		},
+		{
+			Key:     "2023-02",
+			Start:   time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC),
+			Count:   1,
+			Credit:  50,
+			Net:     50,
+			Average: 50,
+		},
+		{
+			Key:     "2024-01",
+			Start:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			Count:   1,
+			Credit:  100,
+			Net:     100,
+			Average: 100,
+		},
+	}
+
+	got := summarizePeriods("", mockPeriodTransactions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestSummary_Periods_byGrouping(t *testing.T) {
+	tests := []struct {
+		grouping string
+		want     []string
+	}{
+		{system.GroupByWeek, []string{"2022-W52", "2023-W01", "2023-W06", "2024-W03"}},
+		{system.GroupByQuarter, []string{"2023-Q1", "2024-Q1"}},
+		{system.GroupByYear, []string{"2023", "2024"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.grouping, func(t *testing.T) {
+			var got []string
+			for _, period := range summarizePeriods(tt.grouping, mockPeriodTransactions()) {
+				got = append(got, period.Key)
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSummary_Periods_weekStartsOnMonday(t *testing.T) {
+	transactions := []system.Transaction{
+		system.MockTransaction(0, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "credit", 20),
+	}
+
+	got := summarizePeriods(system.GroupByWeek, transactions)
+
+	assert.Equal(t, time.Date(2022, 12, 26, 0, 0, 0, 0, time.UTC), got[0].Start)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), got[0].End)
+}
+
+func TestParseGrouping(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", system.GroupByMonth},
+		{"Week", system.GroupByWeek},
+		{" quarter ", system.GroupByQuarter},
+		{"year", system.GroupByYear},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := system.ParseGrouping(tt.name)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseGrouping_failsWhenGroupingIsUnknown(t *testing.T) {
+	want := system.ErrUnknownGrouping
+	_, got := system.ParseGrouping("fortnight")
+
+	assert.Equal(t, want, got)
+}
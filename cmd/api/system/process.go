@@ -1,74 +1,131 @@
 package system
 
 import (
+	"bytes"
 	"context"
-	"html/template"
-	"os"
-	"path/filepath"
-	"runtime"
-	"strings"
+	"embed"
+	"io/fs"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
-	path         string = "api/system/data"
-	file         string = "data.csv"
-	HtmlFolder   string = "api/system/html"
-	htmlFile     string = "account_info.html"
-	StoriLogo    string = "stori_logo.jpeg"
-	templateFile string = "template.html"
+	DataFile  string = "data.csv"
+	StoriLogo string = "stori_logo.jpeg"
 )
 
+//go:embed data/data.csv
+var sampleData embed.FS
+
 type (
 	// HTMLProcessTransactions renders an HTML from the data recieved in the CSV file
 	HTMLProcessTransactions func(ctx context.Context) ([]byte, error)
 )
 
-// MakeHTMLProcessTransactions creates an HTMLProcessTransactions function
-func MakeHTMLProcessTransactions(readCSV ReadCSV, mySQLCreate MySQLCreate) HTMLProcessTransactions {
+// SampleData returns the sample statement built into the binary, as DataFile
+func SampleData() fs.FS {
+	data, _ := fs.Sub(sampleData, "data")
+	return data
+}
+
+// MakeHTMLProcessTransactions creates an HTMLProcessTransactions function. The DataFile of dataFiles is imported,
+// and the email shows the last closed cycle of the default account: the summary of the transactions stored in it,
+// the balances at its start and its end, its recurring transactions, its unusual activity, the forecast of the
+// balance after it and the budgets at its end. The email is issued as the statement of the cycle: once issued, the
+// same statement is rendered until the next cycle closes, even if its transactions change. It is rendered with the
+// AccountInfoTemplate page of the registry in the locale of the account. The budget alerts are notified before
+// rendering; failing to notify them doesn't stop the email
+func MakeHTMLProcessTransactions(importStatement ImportStatement, getStatementSummary GetStatementSummary, getBalances GetBalances, getRecurring GetRecurring, getAnomalies GetAnomalies, getForecast GetForecast, getBudgetReport GetBudgetReport, alertBudgets AlertBudgets, mySQLIssueStatement MySQLIssueStatement, metrics *Metrics, timezones Timezones, cycle StatementCycle, localizer Localizer, templates *TemplateRegistry, dataFiles fs.FS) HTMLProcessTransactions {
 	return func(ctx context.Context) ([]byte, error) {
-		var email Email
+		ctx, span := startSpan(ctx, "HTMLProcessTransactions")
+		defer span.End()
 
-		transactions, err := readCSV(ctx, GetFileName(path, file))
+		now := time.Now().In(timezones.For(DefaultAccountID))
+		opts := NewImportOptions(now, now.Location())
+		opts.Files = dataFiles
+		_, err := importStatement(ctx, DataFile, opts)
+		if err == ErrCantCreateTransactions {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't create transactions", slog.Any("error", err))
+			spanError(span, err)
+			return []byte{}, ErrCantCreateTransactions
+		}
 		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't get csv file", slog.Any("error", err))
+			spanError(span, err)
 			return []byte{}, ErrCantGetCsvFile
 		}
 
-		err = mySQLCreate(ctx, transactions)
+		period := cycle.LastClosed(now)
+		span.SetAttributes(attribute.String("statement.period_start", period.Start.Format(time.DateOnly)), attribute.String("statement.period_end", period.End.Format(time.DateOnly)))
+		summary, err := getStatementSummary(ctx, DefaultAccountID, period)
 		if err != nil {
-			return []byte{}, ErrCantCreateTransactions
+			spanError(span, err)
+			return []byte{}, err
 		}
 
-		email.Balance, email.AverageDebit, email.AverageCredit = getBalanceInfo(transactions)
-		email.WorkingMonths = transactionsPerMonth(transactions)
-
-		templateFile := GetFileName(HtmlFolder, templateFile)
-		tmplBytes, err := os.ReadFile(templateFile)
+		balances, err := getBalances(ctx, DefaultAccountID, period.Start, period.End)
+		if err != nil {
+			spanError(span, err)
+			return []byte{}, err
+		}
+		recurring, err := getRecurring(ctx, DefaultAccountID, period.Start, period.End)
 		if err != nil {
-			return []byte{}, ErrReadTemplateFile
+			spanError(span, err)
+			return []byte{}, err
 		}
+		anomalies, err := getAnomalies(ctx, DefaultAccountID, period.Start, period.End)
+		if err != nil {
+			spanError(span, err)
+			return []byte{}, err
+		}
+		forecast, err := getForecast(ctx, DefaultAccountID, period.End.AddDate(0, 0, 1), 0)
+		if err != nil {
+			spanError(span, err)
+			return []byte{}, err
+		}
+		budgets, err := getBudgetReport(ctx, DefaultAccountID, period.End)
+		if err != nil {
+			spanError(span, err)
+			return []byte{}, err
+		}
+		if err := alertBudgets(ctx, budgets); err != nil {
+			LoggerFrom(ctx).WarnContext(ctx, "can't alert budgets", slog.Any("error", err))
+		}
+		email := summary.Email()
+		email.OpeningBalance = balances.Opening
+		email.ClosingBalance = balances.Closing
+		email.Recurring = recurring
+		email.Anomalies = anomalies.Transactions
+		email.CountSpikes = anomalies.CountSpikes
+		email.Forecast = &forecast
+		email.Budgets = budgets
 
-		var buf strings.Builder
-		templateName := "accountInfo"
-		tmpl, err := template.New(templateName).Parse(string(tmplBytes))
+		statement, err := mySQLIssueStatement(ctx, Statement{
+			Account:        DefaultAccountID,
+			Period:         period,
+			OpeningBalance: balances.Opening,
+			ClosingBalance: balances.Closing,
+			Summary:        email,
+			IssuedAt:       time.Now().UTC(),
+		})
 		if err != nil {
-			return []byte{}, ErrTemplateParse
+			LoggerFrom(ctx).ErrorContext(ctx, "can't issue statement", slog.Any("error", err))
+			spanError(span, err)
+			return []byte{}, ErrCantIssueStatement
 		}
+		span.SetAttributes(attribute.Int64("statement.id", statement.ID))
 
-		err = tmpl.Execute(&buf, email)
+		start := time.Now()
+		var html bytes.Buffer
+		err = templates.Render(ctx, &html, AccountInfoTemplate, localizer.For(DefaultAccountID).Locale, statement.Summary)
+		metrics.TemplateRenderTime.Observe(time.Since(start).Seconds())
 		if err != nil {
-			return []byte{}, ErrTemplateExecute
+			spanError(span, err)
+			return []byte{}, err
 		}
 
-		htmlBytes := []byte(buf.String())
-		return htmlBytes, nil
+		return html.Bytes(), nil
 	}
 }
-
-// GetFileName returns the absolute file path of a file
-func GetFileName(folder string, file string) string {
-	_, filename, _, _ := runtime.Caller(0)
-	currentDir := filepath.Dir(filename)
-	rootDir := filepath.Join(currentDir, "..", "..")
-
-	return filepath.Join(rootDir, folder, file)
-}
@@ -2,38 +2,49 @@ package system_test
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/rromero96/stori/cmd/api/system"
 )
 
+// mockGetStatementSummary summarizes MockTransactions as the transactions stored in the statement period
+func mockGetStatementSummary() system.GetStatementSummary {
+	return system.MakeGetStatementSummary(system.MockMySQLFindTransactions(system.MockTransactions(), nil), system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.Currencies{}, system.DefaultGrouping)
+}
+
 func TestMakeHTMLProcessTransactions_success(t *testing.T) {
-	readCSVmock := system.MockReadCSV(system.MockTransactions(), nil)
+	readCSVmock := system.MockReadStatement(system.MockTransactions(), nil)
 	mysqlCreateMock := system.MockMySQLCreate(nil)
 
-	got := system.MakeHTMLProcessTransactions(readCSVmock, mysqlCreateMock)
+	got := system.MakeHTMLProcessTransactions(system.MakeImportStatement(readCSVmock, mysqlCreateMock, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize), mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
 
 	assert.NotNil(t, got)
 }
 
 func TestHTMLProcessTransactions_success(t *testing.T) {
-	readCSVmock := system.MockReadCSV(system.MockTransactions(), nil)
+	readCSVmock := system.MockReadStatement(system.MockTransactions(), nil)
 	mysqlCreateMock := system.MockMySQLCreate(nil)
-	htmlProcessTransactions := system.MakeHTMLProcessTransactions(readCSVmock, mysqlCreateMock)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(system.MakeImportStatement(readCSVmock, mysqlCreateMock, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize), mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{Opening: 1000, Closing: 1264.7}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
 	ctx := context.Background()
 
 	got, err := htmlProcessTransactions(ctx)
 
 	assert.Nil(t, err)
-	assert.NotNil(t, got)
+	assert.Contains(t, string(got), "Total Balance is: $264.70")
+	assert.Contains(t, string(got), "Opening balance of the period: $1,000.00")
+	assert.Contains(t, string(got), "Closing balance of the period: $1,264.70")
+	assert.Contains(t, string(got), "<td>January 2023</td>")
+	assert.Less(t, strings.Index(string(got), "<td>January 2023</td>"), strings.Index(string(got), "<td>February 2023</td>"))
 }
 
 func TestHTMLProcessTransactions_failsWhenReadCSVThrowsError(t *testing.T) {
-	readCSVmock := system.MockReadCSV(nil, system.ErrOpeningCsv)
+	readCSVmock := system.MockReadStatement(nil, system.ErrOpeningCsv)
 	mysqlCreateMock := system.MockMySQLCreate(nil)
-	htmlProcessTransactions := system.MakeHTMLProcessTransactions(readCSVmock, mysqlCreateMock)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(system.MakeImportStatement(readCSVmock, mysqlCreateMock, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize), mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
 	ctx := context.Background()
 
 	want := system.ErrCantGetCsvFile
@@ -43,9 +54,9 @@ func TestHTMLProcessTransactions_failsWhenReadCSVThrowsError(t *testing.T) {
 }
 
 func TestHTMLProcessTransactions_failsWhenMySQLCreateThworsError(t *testing.T) {
-	readCSVmock := system.MockReadCSV(system.MockTransactions(), nil)
+	readCSVmock := system.MockReadStatement(system.MockTransactions(), nil)
 	mysqlCreateMock := system.MockMySQLCreate(system.ErrCantPrepareStatement)
-	htmlProcessTransactions := system.MakeHTMLProcessTransactions(readCSVmock, mysqlCreateMock)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(system.MakeImportStatement(readCSVmock, mysqlCreateMock, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize), mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
 	ctx := context.Background()
 
 	want := system.ErrCantCreateTransactions
@@ -53,3 +64,181 @@ func TestHTMLProcessTransactions_failsWhenMySQLCreateThworsError(t *testing.T) {
 
 	assert.Equal(t, want, got)
 }
+
+func TestHTMLProcessTransactions_failsWhenGetBalancesThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, system.ErrCantGetBalances), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetBalances
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_failsWhenGetStatementSummaryThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, system.MockGetStatementSummary(system.Summary{}, system.ErrCantGetStatementSummary), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetStatementSummary
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_rendersTheIssuedStatement(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	var issued system.Statement
+	mysqlIssueStatement := func(_ context.Context, statement system.Statement) (system.Statement, error) {
+		issued = statement
+		return system.Statement{ID: 7, Summary: system.Email{Balance: 100}}, nil
+	}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{Opening: 1000, Closing: 1264.7}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), mysqlIssueStatement, system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "Total Balance is: $100.00")
+	assert.Equal(t, system.DefaultAccountID, issued.Account)
+	assert.Equal(t, system.StatementCycle{}.LastClosed(time.Now()), issued.Period)
+	assert.Equal(t, 1000.0, issued.OpeningBalance)
+	assert.Equal(t, 1264.7, issued.ClosingBalance)
+	assert.Equal(t, 264.69999999999993, issued.Summary.Balance)
+}
+
+func TestHTMLProcessTransactions_failsWhenIssueStatementThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(system.ErrCantRunQuery), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantIssueStatement
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_listsRecurringTransactions(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	recurring := []system.Recurring{{Name: "NETFLIX.COM", Type: "debit", Cadence: system.CadenceMonthly, Amount: -219, NextDate: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)}}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(recurring, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "NETFLIX.COM: -$219.00 monthly, next expected on January 10, 2024")
+}
+
+func TestHTMLProcessTransactions_failsWhenGetRecurringThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, system.ErrCantGetRecurring), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetRecurring
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_listsAnomalies(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	anomalies := system.Anomalies{
+		Transactions: []system.Anomaly{{Date: time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), Name: "Liverpool", Amount: -4500, Typical: -800, Reasons: []string{system.AnomalyLargeDebit, system.AnomalyNewMerchant}}},
+		CountSpikes:  []system.CountSpike{{Key: "2023-11", Count: 78, Typical: 18}},
+	}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(anomalies, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "December 15, 2023 Liverpool: -$4,500.00, much larger than usual, first purchase at this merchant (typically -$800.00)")
+	assert.Contains(t, string(got), "November 2023: 78 transactions, typically 18")
+}
+
+func TestHTMLProcessTransactions_failsWhenGetAnomaliesThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, system.ErrCantGetAnomalies), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetAnomalies
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_warnsOfNegativeForecast(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	negative := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	forecast := system.Forecast{To: time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC), Closing: 2300, Lowest: -6200, LowestDate: negative, NegativeDate: &negative}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(forecast, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "Projected balance on January 30, 2024: $2,300.00, lowest -$6,200.00 on January 1, 2024")
+	assert.Contains(t, string(got), "Your balance is projected to go below zero on January 1, 2024")
+}
+
+func TestHTMLProcessTransactions_failsWhenGetForecastThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, system.ErrCantGetForecast), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetForecast
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_showsBudgetsWhenAlertsFail(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	budgets := []system.BudgetStatus{{Category: "groceries", Key: "2023-12", Budget: 4000, Actual: 3300, Remaining: 700, PercentUsed: 82.5, Alert: 80}}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(budgets, nil), system.MockAlertBudgets(system.ErrCantAlertBudget), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "Groceries (December 2023): $3,300.00 of $4,000.00 used (82%), $700.00 left, over 80% of the budget")
+}
+
+func TestHTMLProcessTransactions_failsWhenGetBudgetReportThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, system.ErrCantGetBudgetReport), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	want := system.ErrCantGetBudgetReport
+	_, got := htmlProcessTransactions(context.Background())
+
+	assert.Equal(t, want, got)
+}
+
+func TestHTMLProcessTransactions_formatsAmountsInTheCurrencyOfTheStatement(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	issued := system.Email{
+		Currency: "EUR",
+		Balance:  1234.5,
+		Currencies: []system.CurrencyTotal{
+			{Currency: "EUR", Count: 3, Total: 1216.1, Converted: 1216.1},
+			{Currency: "JPY", Count: 1, Total: -3000, Converted: -18.4},
+			{Currency: "USD", Count: 1, Total: 12, Unconverted: 1},
+		},
+	}
+	mysqlIssueStatement := func(context.Context, system.Statement) (system.Statement, error) {
+		return system.Statement{ID: 7, Summary: issued}, nil
+	}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), mysqlIssueStatement, system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), "Total Balance is: €1,234.50")
+	assert.Contains(t, string(got), "JPY: -¥3,000 in 1 transactions (-€18.40)")
+	assert.Contains(t, string(got), "USD: $12.00 in 1 transactions, 1 without an exchange rate left out of the balance")
+}
+
+func TestHTMLProcessTransactions_writesTheLocaleOfTheAccount(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	localizer := system.MockLocalizer()
+	localizer.Accounts = map[string]string{system.DefaultAccountID: "es"}
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{Opening: 1000, Closing: 1264.7}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), system.NewMetricsNop(), system.Timezones{}, system.StatementCycle{}, localizer, system.MockTemplateRegistry(), system.SampleData())
+
+	got, err := htmlProcessTransactions(context.Background())
+
+	assert.Nil(t, err)
+	assert.Contains(t, string(got), `<html lang="es">`)
+	assert.Contains(t, string(got), "Saldo al inicio del periodo: $1.000,00")
+	assert.Contains(t, string(got), "<td>enero de 2023</td>")
+}
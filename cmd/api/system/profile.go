@@ -0,0 +1,226 @@
+package system
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SignNegativeDebit means negative amounts are debits, as in the Stori export
+	SignNegativeDebit string = "negative_debit"
+	// SignPositiveDebit means positive amounts are debits, as in most credit card exports
+	SignPositiveDebit string = "positive_debit"
+
+	defaultProfileName string = "default"
+)
+
+type (
+	// ColumnMapping holds the header names of the fields of a CSV export. Amount can be replaced by separate
+	// Debit and Credit columns. Description, Merchant and Currency are optional: files without them still match the
+	// profile
+	ColumnMapping struct {
+		ID          string
+		Date        string
+		Amount      string
+		Debit       string
+		Credit      string
+		Description string
+		Merchant    string
+		Currency    string
+	}
+
+	// ImportProfile describes the CSV export of a bank
+	ImportProfile struct {
+		Name             string
+		Delimiter        rune
+		DecimalSeparator rune
+		Sign             string
+		Columns          ColumnMapping
+		Dates            DateParser
+	}
+
+	// columnIndexes holds the position of every mapped column of a CSV file, -1 for the ones not mapped
+	columnIndexes struct {
+		id          int
+		date        int
+		amount      int
+		debit       int
+		credit      int
+		description int
+		merchant    int
+		currency    int
+	}
+)
+
+// DefaultImportProfile returns the profile of the Id,Date,Amount export, with optional Description, Merchant and
+// Currency columns
+func DefaultImportProfile() ImportProfile {
+	return ImportProfile{
+		Name:             defaultProfileName,
+		Delimiter:        ',',
+		DecimalSeparator: '.',
+		Sign:             SignNegativeDebit,
+		Columns:          ColumnMapping{ID: "Id", Date: "Date", Amount: "Amount", Description: "Description", Merchant: "Merchant", Currency: "Currency"},
+		Dates:            DefaultDateParser(),
+	}
+}
+
+// Validate checks the profile can be used to read a file
+func (p ImportProfile) Validate() error {
+	if p.Delimiter == 0 || p.Delimiter == p.DecimalSeparator {
+		return fmt.Errorf("profile %s: invalid delimiter %q", p.Name, p.Delimiter)
+	}
+	if p.DecimalSeparator != '.' && p.DecimalSeparator != ',' {
+		return fmt.Errorf("profile %s: invalid decimal separator %q", p.Name, p.DecimalSeparator)
+	}
+	if p.Sign != SignNegativeDebit && p.Sign != SignPositiveDebit {
+		return fmt.Errorf("profile %s: invalid sign convention %q", p.Name, p.Sign)
+	}
+	if p.Columns.ID == "" || p.Columns.Date == "" {
+		return fmt.Errorf("profile %s: id and date columns are required", p.Name)
+	}
+	hasAmount := p.Columns.Amount != ""
+	hasDebitCredit := p.Columns.Debit != "" && p.Columns.Credit != ""
+	if hasAmount == hasDebitCredit {
+		return fmt.Errorf("profile %s: either an amount column or debit and credit columns are required", p.Name)
+	}
+
+	return nil
+}
+
+// DetectImportProfile returns the profile whose columns are all in the header row. When several profiles match,
+// the one that maps more columns wins, and ties are resolved by the order of the profiles
+func DetectImportProfile(header string, profiles []ImportProfile) (ImportProfile, error) {
+	profile, _, err := detectImportProfile(header, profiles)
+	return profile, err
+}
+
+// detectImportProfile works like DetectImportProfile and also returns the position of the mapped columns
+func detectImportProfile(header string, profiles []ImportProfile) (ImportProfile, columnIndexes, error) {
+	var best ImportProfile
+	var bestIndexes columnIndexes
+	bestMapped := 0
+
+	for _, profile := range profiles {
+		reader := csv.NewReader(strings.NewReader(header))
+		reader.Comma = profile.Delimiter
+		reader.LazyQuotes = true
+		names, err := reader.Read()
+		if err != nil {
+			continue
+		}
+
+		indexes, mapped, ok := profile.Columns.indexes(names)
+		if ok && mapped > bestMapped {
+			best, bestIndexes, bestMapped = profile, indexes, mapped
+		}
+	}
+
+	if bestMapped == 0 {
+		return ImportProfile{}, columnIndexes{}, ErrUnknownCSVFormat
+	}
+
+	return best, bestIndexes, nil
+}
+
+// indexes finds the mapped columns in the header names, ignoring case and surrounding spaces. Only the required
+// columns count as mapped
+func (m ColumnMapping) indexes(names []string) (columnIndexes, int, bool) {
+	positions := make(map[string]int, len(names))
+	for i, name := range names {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	mapped := 0
+	find := func(column string) (int, bool) {
+		if column == "" {
+			return -1, true
+		}
+		i, ok := positions[strings.ToLower(column)]
+		if !ok {
+			return -1, false
+		}
+		mapped++
+		return i, true
+	}
+	optional := func(column string) int {
+		if i, ok := positions[strings.ToLower(column)]; ok && column != "" {
+			return i
+		}
+		return -1
+	}
+
+	var idx columnIndexes
+	var okID, okDate, okAmount, okDebit, okCredit bool
+	idx.id, okID = find(m.ID)
+	idx.date, okDate = find(m.Date)
+	idx.amount, okAmount = find(m.Amount)
+	idx.debit, okDebit = find(m.Debit)
+	idx.credit, okCredit = find(m.Credit)
+	idx.description = optional(m.Description)
+	idx.merchant = optional(m.Merchant)
+	idx.currency = optional(m.Currency)
+
+	return idx, mapped, okID && okDate && okAmount && okDebit && okCredit
+}
+
+// field returns the value at position i of the record, or false if the record is too short
+func field(record []string, i int) (string, bool) {
+	if i < 0 || i >= len(record) {
+		return "", false
+	}
+	return strings.TrimSpace(record[i]), true
+}
+
+// parseAmount parses the amount of a record following the decimal separator and sign convention of the profile.
+// Credits are positive and debits negative in the returned value
+func (p ImportProfile) parseAmount(record []string, idx columnIndexes) (float64, error) {
+	if idx.amount >= 0 {
+		value, ok := field(record, idx.amount)
+		if !ok {
+			return 0, ErrInvalidAmount
+		}
+		amount, err := p.parseNumber(value)
+		if err != nil {
+			return 0, err
+		}
+		if p.Sign == SignPositiveDebit {
+			amount = -amount
+		}
+		return amount, nil
+	}
+
+	debitValue, okDebit := field(record, idx.debit)
+	creditValue, okCredit := field(record, idx.credit)
+	if !okDebit || !okCredit || (debitValue == "") == (creditValue == "") {
+		return 0, ErrInvalidAmount
+	}
+	if debitValue != "" {
+		debit, err := p.parseNumber(debitValue)
+		return -math.Abs(debit), err
+	}
+	credit, err := p.parseNumber(creditValue)
+	return math.Abs(credit), err
+}
+
+// parseNumber parses a number written with the decimal separator of the profile, ignoring thousands separators
+// and spaces
+func (p ImportProfile) parseNumber(value string) (float64, error) {
+	thousands := ","
+	if p.DecimalSeparator == ',' {
+		thousands = "."
+	}
+
+	value = strings.NewReplacer(thousands, "", " ", "", " ", "").Replace(value)
+	value = strings.Replace(value, string(p.DecimalSeparator), ".", 1)
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,95 @@
+package system_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockBanorteProfile() system.ImportProfile {
+	return system.ImportProfile{
+		Name:             "banorte",
+		Delimiter:        ';',
+		DecimalSeparator: ',',
+		Sign:             system.SignNegativeDebit,
+		Columns:          system.ColumnMapping{ID: "Referencia", Date: "Fecha", Debit: "Cargo", Credit: "Abono"},
+		Dates:            system.DefaultDateParser(),
+	}
+}
+
+func mockCardProfile() system.ImportProfile {
+	return system.ImportProfile{
+		Name:             "card",
+		Delimiter:        '\t',
+		DecimalSeparator: '.',
+		Sign:             system.SignPositiveDebit,
+		Columns:          system.ColumnMapping{ID: "Id", Date: "Date", Amount: "Amount"},
+		Dates:            system.DefaultDateParser(),
+	}
+}
+
+func TestDetectImportProfile_success(t *testing.T) {
+	profiles := []system.ImportProfile{system.DefaultImportProfile(), mockBanorteProfile(), mockCardProfile()}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"comma separated", "Id,Date,Amount", "default"},
+		{"semicolon separated in another order", "Fecha;Concepto;Cargo;Abono;Referencia", "banorte"},
+		{"tab separated", "Id\tDate\tAmount\tDescription", "card"},
+		{"case and spaces are ignored", " id , DATE ,amount", "default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := system.DetectImportProfile(tt.header, profiles)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got.Name)
+		})
+	}
+}
+
+func TestDetectImportProfile_prefersTheProfileThatMapsMoreColumns(t *testing.T) {
+	debitCredit := system.DefaultImportProfile()
+	debitCredit.Name = "debit_credit"
+	debitCredit.Columns = system.ColumnMapping{ID: "Id", Date: "Date", Debit: "Debit", Credit: "Credit"}
+
+	got, err := system.DetectImportProfile("Id,Date,Amount,Debit,Credit", []system.ImportProfile{system.DefaultImportProfile(), debitCredit})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "debit_credit", got.Name)
+}
+
+func TestDetectImportProfile_failsWhenNoProfileMatches(t *testing.T) {
+	want := system.ErrUnknownCSVFormat
+	_, got := system.DetectImportProfile("Fecha,Monto", []system.ImportProfile{system.DefaultImportProfile()})
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportProfile_Validate_success(t *testing.T) {
+	assert.Nil(t, system.DefaultImportProfile().Validate())
+	assert.Nil(t, mockBanorteProfile().Validate())
+	assert.Nil(t, mockCardProfile().Validate())
+}
+
+func TestImportProfile_Validate_fails(t *testing.T) {
+	sameSeparators := mockBanorteProfile()
+	sameSeparators.Delimiter = ','
+	invalidSign := system.DefaultImportProfile()
+	invalidSign.Sign = "unknown"
+	amountAndDebitCredit := mockBanorteProfile()
+	amountAndDebitCredit.Columns.Amount = "Monto"
+	missingDate := system.DefaultImportProfile()
+	missingDate.Columns.Date = ""
+
+	assert.NotNil(t, sameSeparators.Validate())
+	assert.NotNil(t, invalidSign.Validate())
+	assert.NotNil(t, amountAndDebitCredit.Validate())
+	assert.NotNil(t, missingDate.Validate())
+}
@@ -1,57 +1,147 @@
 package system
 
 import (
+	"bufio"
 	"context"
 	"encoding/csv"
-	"os"
+	"errors"
+	"io"
+	"log/slog"
 	"strconv"
-	"time"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type (
+	// StreamCSV is a function that reads CSV content one record at a time and calls yield with every transaction.
+	// It stops at the first error returned by yield
+	StreamCSV func(ctx context.Context, r io.Reader, opts ImportOptions, yield func(Transaction) error) error
 )
 
-// ReadCSV is a function that reads a CSV file and returns a slice of transactions
-type ReadCSV func(ctx context.Context, filename string) ([]Transaction, error)
+// MakeStreamCSV creates a StreamCSV function. Only the current record is kept in memory, so the content size isn't
+// bounded. The import profile is detected from the header row. Rows that can't be parsed are skipped and counted
+// in metrics. Reading stops when ctx is done
+func MakeStreamCSV(metrics *Metrics, profiles []ImportProfile) StreamCSV {
+	return func(ctx context.Context, r io.Reader, opts ImportOptions, yield func(Transaction) error) error {
+		ctx, span := startSpan(ctx, "ReadCSV")
+		defer span.End()
 
-// MakeReadCSV creates a ReadCSV function
-func MakeReadCSV() ReadCSV {
-	return func(ctx context.Context, filename string) ([]Transaction, error) {
-		file, err := os.Open(filename)
-		if err != nil {
-			return nil, ErrOpeningCsv
+		buffered := bufio.NewReader(r)
+		header, err := buffered.ReadString('\n')
+		if err != nil && err != io.EOF {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read csv", slog.Any("error", err))
+			spanError(span, err)
+			return readError(err)
 		}
-		defer file.Close()
 
-		reader := csv.NewReader(file)
-		records, err := reader.ReadAll()
+		profile, indexes, err := detectImportProfile(strings.TrimRight(header, "\r\n"), profiles)
 		if err != nil {
-			return nil, ErrReadingCsv
+			LoggerFrom(ctx).ErrorContext(ctx, "can't detect csv profile", slog.String("header", header), slog.Any("error", err))
+			spanError(span, err)
+			return ErrUnknownCSVFormat
 		}
+		span.SetAttributes(attribute.String("csv.profile", profile.Name))
 
-		var transactions []Transaction
-		for i, record := range records {
-			if i == 0 {
-				continue
+		reader := csv.NewReader(buffered)
+		reader.Comma = profile.Delimiter
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+
+		var parsed, rejected int
+		for row := 1; ; row++ {
+			if err := ctx.Err(); err != nil {
+				LoggerFrom(ctx).WarnContext(ctx, "csv read canceled", slog.Int("row", row), slog.Any("error", err))
+				spanError(span, err)
+				return err
 			}
 
-			id, _ := strconv.ParseInt(record[0], 10, 64)
-			date, _ := time.Parse("2/1", record[1])
-			currentYear := time.Now().Year()
-			date = time.Date(currentYear, date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
-			amount, _ := strconv.ParseFloat(record[2], 64)
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				LoggerFrom(ctx).ErrorContext(ctx, "can't read csv", slog.Int("row", row), slog.Any("error", err))
+				spanError(span, err)
+				return readError(err)
+			}
 
-			transaction := Transaction{
-				ID:          int64(id),
-				Date:        date,
-				Transaction: float64(amount),
+			transaction, reason, err := parseRecord(record, profile, indexes, opts)
+			if err != nil {
+				LoggerFrom(ctx).WarnContext(ctx, "rejected csv row", slog.Int("row", row), slog.String("reason", reason), slog.Any("error", err))
+				metrics.CSVRowsRejected.WithLabelValues(reason).Inc()
+				rejected++
+				continue
 			}
 
-			transaction.Type = "credit"
-			if amount < 0 {
-				transaction.Type = "debit"
+			if err := yield(transaction); err != nil {
+				spanError(span, err)
+				return err
 			}
+			metrics.CSVRowsParsed.Inc()
+			parsed++
+		}
 
-			transactions = append(transactions, transaction)
+		span.SetAttributes(attribute.Int("csv.rows_parsed", parsed), attribute.Int("csv.rows_rejected", rejected))
+		return nil
+	}
+}
+
+// readError returns the error of a CSV read that failed, which tells when the content isn't valid in its encoding
+func readError(err error) error {
+	if errors.Is(err, ErrInvalidUTF8) {
+		return ErrInvalidUTF8
+	}
+	return ErrReadingCsv
+}
+
+// parseRecord turns a CSV record into a transaction. When the record is invalid it returns the rejection reason
+func parseRecord(record []string, profile ImportProfile, indexes columnIndexes, opts ImportOptions) (Transaction, string, error) {
+	idValue, okID := field(record, indexes.id)
+	dateValue, okDate := field(record, indexes.date)
+	if !okID || !okDate {
+		return Transaction{}, RejectReasonFormat, ErrUnknownCSVFormat
+	}
+
+	id, err := strconv.ParseInt(idValue, 10, 64)
+	if err != nil {
+		return Transaction{}, RejectReasonID, err
+	}
+
+	date, err := profile.Dates.Parse(dateValue, opts.Location, opts.Period)
+	if err != nil {
+		return Transaction{}, RejectReasonDate, err
+	}
+
+	amount, err := profile.parseAmount(record, indexes)
+	if err != nil {
+		return Transaction{}, RejectReasonAmount, err
+	}
+
+	currency, _ := field(record, indexes.currency)
+	if currency != "" {
+		currency, err = ParseCurrency(currency)
+		if err != nil {
+			return Transaction{}, RejectReasonCurrency, err
 		}
+	}
 
-		return transactions, nil
+	description, _ := field(record, indexes.description)
+	merchant, _ := field(record, indexes.merchant)
+	transaction := Transaction{
+		ID:          id,
+		ExternalID:  strconv.FormatInt(id, 10),
+		Date:        date,
+		Transaction: amount,
+		Description: description,
+		Merchant:    merchant,
+		Currency:    currency,
 	}
+
+	transaction.Type = "credit"
+	if amount < 0 {
+		transaction.Type = "debit"
+	}
+
+	return transaction, "", nil
 }
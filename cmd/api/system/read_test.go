@@ -2,31 +2,163 @@ package system_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/rromero96/stori/cmd/api/system"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
+// makeReadCSVStatement returns a ReadStatement that reads CSV files with the given profiles
+func makeReadCSVStatement(metrics *system.Metrics, profiles []system.ImportProfile) system.ReadStatement {
+	return system.MakeReadStatement(system.MakeStreamCSV(metrics, profiles), system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+}
+
 func TestReadCSV_success(t *testing.T) {
-	filename := system.GetFileName("api/system/data", "data.csv")
-	readFiles := system.MakeReadCSV()
+	filename := filepath.Join("data", system.DataFile)
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), system.MockImportProfiles())
 	ctx := context.Background()
 
 	want := system.MockTransactions()
-	got, err := readFiles(ctx, filename)
+	got, err := collect(ctx, readStatement, filename)
 
 	assert.Nil(t, err)
 	assert.Equal(t, got, want)
 }
 
 func TestReadCSV_failsWhenCantOpenCsvFile(t *testing.T) {
-	readFiles := system.MakeReadCSV()
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), system.MockImportProfiles())
 	ctx := context.Background()
 
-	want := system.ErrOpeningCsv
-	_, got := readFiles(ctx, "")
+	want := system.ErrOpeningStatement
+	_, got := collect(ctx, readStatement, "")
 
 	assert.Equal(t, got, want)
 }
+
+func TestReadCSV_countsParsedAndRejectedRows(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	content := "Id,Date,Amount\n0,1/1,60.5\nx,2/1,-10.3\n2,31/2,-20.46\n3,4/1,ten\n4,5/1,10.0\n"
+	_ = os.WriteFile(filename, []byte(content), 0o600)
+	metrics := system.NewMetricsNop()
+	readStatement := makeReadCSVStatement(metrics, system.MockImportProfiles())
+	ctx := context.Background()
+
+	got, err := collect(ctx, readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Len(t, got, 2)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.CSVRowsParsed))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonID)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonDate)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonAmount)))
+}
+
+func TestReadCSV_successWithImportProfile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "banorte.csv")
+	content := "Fecha;Concepto;Cargo;Abono;Referencia\r\n" +
+		"01/12/2023;Nomina;;15.000,50;100\r\n" +
+		"02/12/2023;Renta;\"8.500,00\";;101\r\n" +
+		"03/12/2023;Cafe;-45,90;;102\r\n" +
+		"04/12/2023;Sin monto;;;103\r\n"
+	_ = os.WriteFile(filename, []byte(content), 0o600)
+	profiles := []system.ImportProfile{system.DefaultImportProfile(), mockBanorteProfile()}
+	metrics := system.NewMetricsNop()
+	readStatement := makeReadCSVStatement(metrics, profiles)
+	ctx := context.Background()
+
+	want := []system.Transaction{
+		system.MockCSVTransaction(100, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "credit", 15000.5),
+		system.MockCSVTransaction(101, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), "debit", -8500),
+		system.MockCSVTransaction(102, time.Date(2023, 12, 3, 0, 0, 0, 0, time.UTC), "debit", -45.9),
+	}
+	got, err := collect(ctx, readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonAmount)))
+}
+
+func TestReadCSV_successWithPositiveDebitProfile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "card.csv")
+	content := "Id\tDate\tAmount\n1\t2023-11-05\t1,250.00\n2\t2023-11-06\t-300\n"
+	_ = os.WriteFile(filename, []byte(content), 0o600)
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), []system.ImportProfile{mockCardProfile()})
+	ctx := context.Background()
+
+	want := []system.Transaction{
+		system.MockCSVTransaction(1, time.Date(2023, 11, 5, 0, 0, 0, 0, time.UTC), "debit", -1250),
+		system.MockCSVTransaction(2, time.Date(2023, 11, 6, 0, 0, 0, 0, time.UTC), "credit", 300),
+	}
+	got, err := collect(ctx, readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadCSV_successWithDescriptionAndMerchant(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	content := "Id,Date,Amount,Description,Merchant\n1,1/12/2023,-45.9,Weekly groceries,Soriana\n2,2/12/2023,1500,,\n"
+	_ = os.WriteFile(filename, []byte(content), 0o600)
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), system.MockImportProfiles())
+	ctx := context.Background()
+
+	groceries := system.MockCSVTransaction(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "debit", -45.9)
+	groceries.Description = "Weekly groceries"
+	groceries.Merchant = "Soriana"
+	want := []system.Transaction{
+		groceries,
+		system.MockCSVTransaction(2, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), "credit", 1500),
+	}
+	got, err := collect(ctx, readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestReadCSV_successWithCurrency(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.csv")
+	content := "Id,Date,Amount,Currency\n1,1/12/2023,-45.9,usd\n2,2/12/2023,1500,\n3,3/12/2023,-10,dollars\n"
+	_ = os.WriteFile(filename, []byte(content), 0o600)
+	metrics := system.NewMetricsNop()
+	readStatement := makeReadCSVStatement(metrics, system.MockImportProfiles())
+	ctx := context.Background()
+
+	usd := system.MockCSVTransaction(1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "debit", -45.9)
+	usd.Currency = "USD"
+	want := []system.Transaction{
+		usd,
+		system.MockCSVTransaction(2, time.Date(2023, 12, 2, 0, 0, 0, 0, time.UTC), "credit", 1500),
+	}
+	got, err := collect(ctx, readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, want, got)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CSVRowsRejected.WithLabelValues(system.RejectReasonCurrency)))
+}
+
+func TestReadCSV_failsWhenFormatIsUnknown(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "unknown.csv")
+	_ = os.WriteFile(filename, []byte("Fecha,Monto\n01/12/2023,10\n"), 0o600)
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), system.MockImportProfiles())
+	ctx := context.Background()
+
+	want := system.ErrUnknownCSVFormat
+	_, got := collect(ctx, readStatement, filename)
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadCSV_successWithCompressedFile(t *testing.T) {
+	filename := writeGzip(t, "data.csv.gz", readDataCSV(t))
+	readStatement := makeReadCSVStatement(system.NewMetricsNop(), system.MockImportProfiles())
+
+	got, err := collect(context.Background(), readStatement, filename)
+
+	assert.Nil(t, err)
+	assert.Equal(t, system.MockTransactions(), got)
+}
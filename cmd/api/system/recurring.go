@@ -0,0 +1,349 @@
+package system
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	CadenceWeekly    string = "weekly"
+	CadenceBiweekly  string = "biweekly"
+	CadenceMonthly   string = "monthly"
+	CadenceQuarterly string = "quarterly"
+	CadenceYearly    string = "yearly"
+
+	DefaultRecurringMinOccurrences    int     = 4
+	DefaultRecurringAmountTolerance   float64 = 0.2
+	DefaultRecurringMinRegularity     float64 = 0.75
+	DefaultRecurringMinNameSimilarity float64 = 0.5
+)
+
+type (
+	// RecurringConfig tunes the detection of recurring transactions. A series needs MinOccurrences transactions
+	// whose amounts are within AmountTolerance (a fraction) of their median, and at least MinRegularity (a fraction)
+	// of the intervals between them must match its cadence. Descriptions are the same when the Jaccard similarity of
+	// their words is at least MinNameSimilarity
+	RecurringConfig struct {
+		MinOccurrences    int
+		AmountTolerance   float64
+		MinRegularity     float64
+		MinNameSimilarity float64
+	}
+
+	// Recurring is a series of transactions that repeat with a cadence, such as a salary, the rent or a subscription
+	Recurring struct {
+		Name        string    `json:"name"`
+		Type        string    `json:"type"`
+		Category    string    `json:"category,omitempty"`
+		Cadence     string    `json:"cadence"`
+		Amount      float64   `json:"amount"`
+		Occurrences int       `json:"occurrences"`
+		LastDate    time.Time `json:"last_date"`
+		NextDate    time.Time `json:"next_date"`
+	}
+
+	// GetRecurring is a function that detects the recurring transactions of an account between two dates
+	GetRecurring func(ctx context.Context, account string, from time.Time, to time.Time) ([]Recurring, error)
+
+	// cadence is a candidate interval between the transactions of a series, of months months or, when months is
+	// zero, of days days. Dates up to tolerance days away from the expected one match the cadence
+	cadence struct {
+		name      string
+		days      float64
+		tolerance float64
+		months    int
+	}
+
+	// recurringGroup holds the transactions with a similar description
+	recurringGroup struct {
+		words        map[string]bool
+		transactions []Transaction
+	}
+)
+
+var cadences = []cadence{
+	{name: CadenceWeekly, days: 7, tolerance: 2},
+	{name: CadenceBiweekly, days: 14, tolerance: 3},
+	{name: CadenceMonthly, days: 30.44, tolerance: 4, months: 1},
+	{name: CadenceQuarterly, days: 91.31, tolerance: 10, months: 3},
+	{name: CadenceYearly, days: 365.25, tolerance: 15, months: 12},
+}
+
+// cadenceNamed returns the cadence with the given name
+func cadenceNamed(name string) (cadence, bool) {
+	for _, c := range cadences {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return cadence{}, false
+}
+
+// DefaultRecurringConfig returns the default RecurringConfig
+func DefaultRecurringConfig() RecurringConfig {
+	return RecurringConfig{
+		MinOccurrences:    DefaultRecurringMinOccurrences,
+		AmountTolerance:   DefaultRecurringAmountTolerance,
+		MinRegularity:     DefaultRecurringMinRegularity,
+		MinNameSimilarity: DefaultRecurringMinNameSimilarity,
+	}
+}
+
+// DetectRecurring finds the recurring series in a transaction history. Transactions are grouped by type and by the
+// similarity of their merchant (or description when there is no merchant), the amounts far from the median of the
+// group are discarded as noise, and the cadence that matches most intervals is chosen, allowing skipped periods.
+// The series are sorted by their next expected date
+func DetectRecurring(transactions []Transaction, config RecurringConfig) []Recurring {
+	recurring, _ := detectRecurring(transactions, config)
+	return recurring
+}
+
+// detectRecurring returns the recurring series of DetectRecurring and the set of transactions that belong to them
+func detectRecurring(transactions []Transaction, config RecurringConfig) ([]Recurring, map[Transaction]bool) {
+	var groups []*recurringGroup
+	for _, t := range transactions {
+		words := nameWords(recurringName(t))
+		if len(words) == 0 || (t.Type != "debit" && t.Type != "credit") {
+			continue
+		}
+
+		var group *recurringGroup
+		for _, g := range groups {
+			if g.transactions[0].Type == t.Type && jaccard(g.words, words) >= config.MinNameSimilarity {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &recurringGroup{words: words}
+			groups = append(groups, group)
+		}
+		group.transactions = append(group.transactions, t)
+	}
+
+	recurring := make([]Recurring, 0)
+	members := make(map[Transaction]bool)
+	for _, group := range groups {
+		if series, transactions, ok := detectSeries(group.transactions, config); ok {
+			recurring = append(recurring, series)
+			for _, t := range transactions {
+				members[t] = true
+			}
+		}
+	}
+	sort.Slice(recurring, func(i, j int) bool {
+		if !recurring[i].NextDate.Equal(recurring[j].NextDate) {
+			return recurring[i].NextDate.Before(recurring[j].NextDate)
+		}
+		return recurring[i].Name < recurring[j].Name
+	})
+
+	return recurring, members
+}
+
+// MakeGetRecurring creates a GetRecurring function that analyzes the transactions stored for the account
+func MakeGetRecurring(mySQLFindTransactions MySQLFindTransactions, config RecurringConfig) GetRecurring {
+	return func(ctx context.Context, account string, from time.Time, to time.Time) ([]Recurring, error) {
+		ctx, span := startSpan(ctx, "GetRecurring", attribute.String("recurring.account", account))
+		defer span.End()
+
+		from, to = calendarDate(from), calendarDate(to)
+		if account == "" || to.Before(from) {
+			return nil, ErrInvalidDateRange
+		}
+
+		transactions, err := mySQLFindTransactions(ctx, account, from, to)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't find transactions", slog.String("account", account), slog.Any("error", err))
+			spanError(span, err)
+			return nil, ErrCantGetRecurring
+		}
+
+		recurring := DetectRecurring(transactions, config)
+		span.SetAttributes(attribute.Int("recurring.transactions", len(transactions)), attribute.Int("recurring.series", len(recurring)))
+		return recurring, nil
+	}
+}
+
+// detectSeries reports whether the transactions of a group repeat with a cadence, and returns the transactions of
+// the series
+func detectSeries(transactions []Transaction, config RecurringConfig) (Recurring, []Transaction, bool) {
+	if len(transactions) < config.MinOccurrences {
+		return Recurring{}, nil, false
+	}
+
+	amount := median(seriesAmounts(transactions))
+
+	series := make([]Transaction, 0, len(transactions))
+	for _, t := range transactions {
+		if math.Abs(t.Transaction-amount) <= config.AmountTolerance*math.Abs(amount) {
+			series = append(series, t)
+		}
+	}
+	if len(series) < config.MinOccurrences {
+		return Recurring{}, nil, false
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].Date.Before(series[j].Date)
+	})
+
+	best, bestRegularity, bestSkipped := cadence{}, 0.0, 0
+	for _, c := range cadences {
+		regular, skipped := 0, 0
+		for i := 1; i < len(series); i++ {
+			if periods, ok := c.periodsBetween(series[i-1].Date, series[i].Date); ok {
+				regular++
+				skipped += periods - 1
+			}
+		}
+		if 2*skipped > len(series) {
+			// Too many missing periods for the occurrences: the transactions are too sparse for this cadence
+			continue
+		}
+		regularity := float64(regular) / float64(len(series)-1)
+		if regularity > bestRegularity || (regularity == bestRegularity && regularity > 0 && skipped < bestSkipped) {
+			best, bestRegularity, bestSkipped = c, regularity, skipped
+		}
+	}
+	if best.name == "" || bestRegularity < config.MinRegularity {
+		return Recurring{}, nil, false
+	}
+
+	last := series[len(series)-1]
+	next := best.add(calendarDate(last.Date), 1)
+
+	return Recurring{
+		Name:        seriesName(series),
+		Type:        last.Type,
+		Category:    last.Category,
+		Cadence:     best.name,
+		Amount:      median(seriesAmounts(series)),
+		Occurrences: len(series),
+		LastDate:    calendarDate(last.Date),
+		NextDate:    next,
+	}, series, true
+}
+
+// add returns the date the given number of periods after date. Cadences of months keep the day of the month, or
+// the last day of the month when it is shorter, so a series on the 31st falls on February 28th or 29th
+func (c cadence) add(date time.Time, periods int) time.Time {
+	if c.months > 0 {
+		first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location()).AddDate(0, periods*c.months, 0)
+		day := date.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return time.Date(first.Year(), first.Month(), day, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	}
+	return date.AddDate(0, 0, periods*int(c.days))
+}
+
+// periodsBetween returns the number of periods of the cadence between two dates, and whether the second date is
+// within the tolerance of the date expected after them
+func (c cadence) periodsBetween(previous time.Time, current time.Time) (int, bool) {
+	previous, current = calendarDate(previous), calendarDate(current)
+	periods := int(math.Round(current.Sub(previous).Hours() / 24 / c.days))
+	if periods < 1 {
+		return 0, false
+	}
+
+	deviation := math.Abs(current.Sub(c.add(previous, periods)).Hours() / 24)
+	return periods, deviation <= c.tolerance
+}
+
+// recurringName is the merchant of the transaction, or its description when it doesn't have one
+func recurringName(t Transaction) string {
+	if t.Merchant != "" {
+		return t.Merchant
+	}
+	return t.Description
+}
+
+// seriesName returns the name of the last transaction of the series without the words that don't appear in most of
+// its transactions, such as months or reference numbers
+func seriesName(series []Transaction) string {
+	counts := make(map[string]int)
+	for _, t := range series {
+		for word := range nameWords(recurringName(t)) {
+			counts[word]++
+		}
+	}
+
+	name := recurringName(series[len(series)-1])
+	var kept []string
+	for _, field := range strings.Fields(name) {
+		words := nameWords(field)
+		common := len(words) > 0
+		for word := range words {
+			common = common && 2*counts[word] > len(series)
+		}
+		if common {
+			kept = append(kept, field)
+		}
+	}
+	if len(kept) == 0 {
+		return name
+	}
+	return strings.Join(kept, " ")
+}
+
+// nameWords returns the lowercase words of a name, without the words that have digits, such as dates or reference
+// numbers
+func nameWords(name string) map[string]bool {
+	words := make(map[string]bool)
+	for _, field := range strings.Fields(strings.ToLower(name)) {
+		if strings.IndexFunc(field, unicode.IsDigit) >= 0 {
+			continue
+		}
+		for _, word := range strings.FieldsFunc(field, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			words[word] = true
+		}
+	}
+
+	return words
+}
+
+// jaccard returns the number of words in both sets divided by the number of words in any of them
+func jaccard(a map[string]bool, b map[string]bool) float64 {
+	both := 0
+	for word := range a {
+		if b[word] {
+			both++
+		}
+	}
+	if union := len(a) + len(b) - both; union > 0 {
+		return float64(both) / float64(union)
+	}
+	return 0
+}
+
+// seriesAmounts returns the amounts of the transactions
+func seriesAmounts(series []Transaction) []float64 {
+	amounts := make([]float64, 0, len(series))
+	for _, t := range series {
+		amounts = append(amounts, t.Transaction)
+	}
+	return amounts
+}
+
+// median returns the middle value, or the mean of the two middle values
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
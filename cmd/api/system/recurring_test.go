@@ -0,0 +1,186 @@
+package system_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+// syntheticSeries returns count transactions that repeat every months months (or days days when months is 0),
+// starting at start, with the amount shifted up to 3% and the date up to jitter days. The occurrences in skip are
+// left out
+func syntheticSeries(r *rand.Rand, name string, trType string, amount float64, start time.Time, months int, days int, jitter int, count int, skip ...int) []system.Transaction {
+	skipped := make(map[int]bool)
+	for _, i := range skip {
+		skipped[i] = true
+	}
+
+	var transactions []system.Transaction
+	for i := 0; i < count; i++ {
+		if skipped[i] {
+			continue
+		}
+		date := start.AddDate(0, i*months, i*days).AddDate(0, 0, r.Intn(2*jitter+1)-jitter)
+		t := system.MockTransaction(int64(i), date, trType, amount*(1+(r.Float64()-0.5)*0.06))
+		t.Description = fmt.Sprintf("%s %s REF%04d", name, date.Format("Jan"), r.Intn(10000))
+		transactions = append(transactions, t)
+	}
+
+	return transactions
+}
+
+func syntheticHistory() []system.Transaction {
+	r := rand.New(rand.NewSource(42))
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var history []system.Transaction
+	history = append(history, syntheticSeries(r, "PAYROLL ACME", "credit", 15000, start.AddDate(0, 0, 14), 1, 0, 2, 12)...)
+	history = append(history, syntheticSeries(r, "Rent Polanco", "debit", -8500, start, 1, 0, 2, 12, 4)...)
+	history = append(history, syntheticSeries(r, "NETFLIX.COM", "debit", -219, start.AddDate(0, 0, 9), 1, 0, 2, 12, 2, 7, 8)...)
+	history = append(history, syntheticSeries(r, "Gym Sport City", "debit", -150, start.AddDate(0, 0, 3), 0, 7, 1, 40, 11)...)
+	history = append(history, syntheticSeries(r, "Car insurance", "debit", -3200, start.AddDate(0, 0, 20), 3, 0, 5, 4)...)
+
+	// One-off purchases: the same shop with different amounts and a store visited at random
+	for i := 0; i < 25; i++ {
+		date := start.AddDate(0, 0, r.Intn(360))
+		t := system.MockTransaction(int64(100+i), date, "debit", -float64(10+r.Intn(900)))
+		t.Merchant = []string{"Soriana", "Amazon", "Oxxo"}[r.Intn(3)]
+		history = append(history, t)
+	}
+	// A one-off charge of the same streaming service, far from its usual amount
+	extra := system.MockTransaction(200, time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), "debit", -1299)
+	extra.Description = "NETFLIX.COM GIFT CARD"
+	history = append(history, extra)
+
+	r.Shuffle(len(history), func(i, j int) { history[i], history[j] = history[j], history[i] })
+	return history
+}
+
+func TestDetectRecurring_findsSeriesInSyntheticHistory(t *testing.T) {
+	got := system.DetectRecurring(syntheticHistory(), system.DefaultRecurringConfig())
+
+	cadences := make(map[string]string)
+	for _, recurring := range got {
+		cadences[recurring.Type+" "+recurring.Cadence] = recurring.Name
+	}
+	assert.Len(t, got, 5)
+	assert.Equal(t, "PAYROLL ACME", cadences["credit monthly"])
+	assert.Contains(t, cadences, "debit weekly")
+	assert.Contains(t, cadences, "debit quarterly")
+	assert.NotContains(t, cadences, "debit biweekly")
+	for _, recurring := range got {
+		assert.NotContains(t, []string{"Soriana", "Amazon", "Oxxo"}, recurring.Name)
+	}
+}
+
+func TestDetectRecurring_allowsSkippedMonths(t *testing.T) {
+	got := system.DetectRecurring(syntheticHistory(), system.DefaultRecurringConfig())
+
+	var netflix system.Recurring
+	for _, recurring := range got {
+		if recurring.Cadence == system.CadenceMonthly && recurring.Type == "debit" && recurring.Amount > -300 {
+			netflix = recurring
+		}
+	}
+	assert.Equal(t, "NETFLIX.COM", netflix.Name)
+	assert.Equal(t, 9, netflix.Occurrences)
+	assert.InDelta(t, -219, netflix.Amount, 219*0.03)
+	assert.Equal(t, netflix.LastDate.AddDate(0, 1, 0), netflix.NextDate)
+}
+
+func TestDetectRecurring_estimatesNextDate(t *testing.T) {
+	var transactions []system.Transaction
+	for i := 0; i < 4; i++ {
+		t := system.MockTransaction(int64(i), time.Date(2023, 9, 5, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 14*i), "debit", -99)
+		t.Merchant = "Spotify"
+		t.Category = "subscriptions"
+		transactions = append(transactions, t)
+	}
+
+	want := []system.Recurring{{
+		Name:        "Spotify",
+		Type:        "debit",
+		Category:    "subscriptions",
+		Cadence:     system.CadenceBiweekly,
+		Amount:      -99,
+		Occurrences: 4,
+		LastDate:    time.Date(2023, 10, 17, 0, 0, 0, 0, time.UTC),
+		NextDate:    time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC),
+	}}
+	got := system.DetectRecurring(transactions, system.DefaultRecurringConfig())
+
+	assert.Equal(t, want, got)
+}
+
+func TestDetectRecurring_keepsSeriesOnTheLastDayOfTheMonth(t *testing.T) {
+	var transactions []system.Transaction
+	for i, date := range []time.Time{
+		time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		t := system.MockTransaction(int64(i), date, "debit", -8500)
+		t.Merchant = "Rent Polanco"
+		transactions = append(transactions, t)
+	}
+
+	got := system.DetectRecurring(transactions, system.DefaultRecurringConfig())
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, system.CadenceMonthly, got[0].Cadence)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), got[0].NextDate)
+}
+
+func TestDetectRecurring_ignoresIrregularTransactions(t *testing.T) {
+	var transactions []system.Transaction
+	for i, day := range []int{1, 4, 30, 33, 90, 200} {
+		t := system.MockTransaction(int64(i), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day), "debit", -50)
+		t.Merchant = "Uber"
+		transactions = append(transactions, t)
+	}
+
+	got := system.DetectRecurring(transactions, system.DefaultRecurringConfig())
+
+	assert.Empty(t, got)
+}
+
+func TestGetRecurring_success(t *testing.T) {
+	getRecurring := system.MakeGetRecurring(system.MockMySQLFindTransactions(syntheticHistory(), nil), system.DefaultRecurringConfig())
+	ctx := context.Background()
+
+	got, err := getRecurring(ctx, system.DefaultAccountID, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+
+	assert.Nil(t, err)
+	assert.Len(t, got, 5)
+}
+
+func TestGetRecurring_fails(t *testing.T) {
+	from := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                  string
+		mySQLFindTransactions system.MySQLFindTransactions
+		to                    time.Time
+		want                  error
+	}{
+		{"to before from", system.MockMySQLFindTransactions(nil, nil), from.AddDate(0, 0, -1), system.ErrInvalidDateRange},
+		{"can't find transactions", system.MockMySQLFindTransactions(nil, system.ErrCantRunQuery), from, system.ErrCantGetRecurring},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getRecurring := system.MakeGetRecurring(tt.mySQLFindTransactions, system.DefaultRecurringConfig())
+
+			_, got := getRecurring(context.Background(), system.DefaultAccountID, from, tt.to)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	sniffSize int = 512
+
+	// DefaultBatchSize is the number of transactions inserted in the database at once
+	DefaultBatchSize int = 1000
+)
+
+type (
+	// ReadStatement is a function that reads a statement file in any of the supported formats and calls yield with
+	// every transaction. It stops at the first error returned by yield
+	ReadStatement func(ctx context.Context, filename string, opts ImportOptions, yield func(Transaction) error) error
+
+	// ImportStatement is a function that reads a statement file, stores its transactions in the database and
+	// returns their summary
+	ImportStatement func(ctx context.Context, filename string, opts ImportOptions) (Summary, error)
+)
+
+// MakeReadStatement creates a ReadStatement function that picks the reader of the file by looking at its content.
+// gzip, zstd and zip files are decompressed within the limits of archives, and every file inside is read as a
+// statement of its own. Files are attributed to an account by their name, and their dates are interpreted in the
+// timezone of that account. Content is transcoded from the source encoding, or the one detected when it is
+// EncodingAuto, to UTF-8. CSV files are streamed, OFX and camt.053 files are read whole. The camt.053 statements
+// must reconcile with their opening and closing balances
+func MakeReadStatement(streamCSV StreamCSV, readOFX ReadOFX, readCamt053 ReadCamt053, archives ArchiveConfig, timezones Timezones, sourceEncoding string) ReadStatement {
+	readMember := makeReadMember(streamCSV, readOFX, readCamt053, sourceEncoding)
+
+	return func(ctx context.Context, filename string, opts ImportOptions, yield func(Transaction) error) error {
+		return walkStatement(ctx, opts.Files, filename, archives, func(member statementMember) error {
+			memberOpts := opts
+			if member.account != "" {
+				memberOpts.Account = member.account
+				memberOpts.Location = timezones.For(member.account)
+			}
+			LoggerFrom(ctx).DebugContext(ctx, "reading statement", slog.String("member", member.name), slog.String("account", memberOpts.Account))
+
+			return readMember(ctx, member, memberOpts, func(t Transaction) error {
+				if t.Account == "" {
+					t.Account = memberOpts.Account
+				}
+				return yield(t)
+			})
+		})
+	}
+}
+
+// makeReadMember returns a function that reads a single statement with the reader of its format
+func makeReadMember(streamCSV StreamCSV, readOFX ReadOFX, readCamt053 ReadCamt053, sourceEncoding string) func(ctx context.Context, member statementMember, opts ImportOptions, yield func(Transaction) error) error {
+	return func(ctx context.Context, member statementMember, opts ImportOptions, yield func(Transaction) error) error {
+		raw := bufio.NewReaderSize(member.reader, detectSize)
+		head, err := raw.Peek(len(zstdMagic))
+		if err != nil && err != io.EOF {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read statement", slog.String("member", member.name), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+
+		if isCompressed(head) {
+			LoggerFrom(ctx).ErrorContext(ctx, "nested compressed statement", slog.String("member", member.name))
+			return ErrNestedArchive
+		}
+
+		decoded, encoding, err := newDecodingReader(raw, sourceEncoding)
+		if err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read statement", slog.String("member", member.name), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+		LoggerFrom(ctx).DebugContext(ctx, "statement encoding", slog.String("member", member.name), slog.String("encoding", encoding))
+
+		buffered := bufio.NewReader(decoded)
+		head, err = buffered.Peek(sniffSize)
+		if err != nil && err != io.EOF {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't read statement", slog.String("member", member.name), slog.Any("error", err))
+			return ErrReadingStatement
+		}
+
+		if isOFX(head) {
+			transactions, err := readOFX(ctx, buffered, opts)
+			if err != nil {
+				return err
+			}
+			return yieldAll(transactions, yield)
+		}
+
+		if isCamt053(head) {
+			statements, err := readCamt053(ctx, buffered, opts)
+			if err != nil {
+				return err
+			}
+
+			for _, statement := range statements {
+				if err := ReconcileStatement(statement); err != nil {
+					LoggerFrom(ctx).ErrorContext(ctx, "can't reconcile statement",
+						slog.String("member", member.name),
+						slog.String("account", statement.Account),
+						slog.Any("opening_balance", statement.OpeningBalance),
+						slog.Any("closing_balance", statement.ClosingBalance),
+						slog.Any("error", err),
+					)
+					return err
+				}
+			}
+			for _, statement := range statements {
+				if err := yieldAll(statement.Transactions, yield); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		return streamCSV(ctx, buffered, opts, yield)
+	}
+}
+
+// MakeImportStatement creates an ImportStatement function that categorizes the transactions and inserts them in
+// batches of batchSize while the file is read, and computes their summary on the fly, with periods of the given
+// grouping, so memory doesn't grow with the file size. Transactions without a currency get the one of their account,
+// and the summary is converted to the currency of the imported account with the current exchange rates. Once
+// inserted, the balance snapshots of every account are recomputed from its earliest imported date, converted to
+// its currency with the same rates
+func MakeImportStatement(readStatement ReadStatement, mySQLCreate MySQLCreate, mySQLSnapshotBalances MySQLSnapshotBalances, categorizer Categorizer, currencies Currencies, getExchangeRates GetExchangeRates, grouping string, batchSize int) ImportStatement {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return func(ctx context.Context, filename string, opts ImportOptions) (Summary, error) {
+		ctx, span := startSpan(ctx, "ImportStatement", attribute.String("import.filename", filename))
+		defer span.End()
+
+		rates, err := getExchangeRates(ctx)
+		if err != nil {
+			spanError(span, err)
+			return Summary{}, err
+		}
+		account := opts.Account
+		if account == "" {
+			account = DefaultAccountID
+		}
+
+		summary := Summary{Grouping: grouping, Currency: currencies.For(account), Rates: rates}
+		firstDates := make(map[string]time.Time)
+		var batches int
+		batch := make([]Transaction, 0, batchSize)
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			inserted, err := mySQLCreate(ctx, batch)
+			if err != nil {
+				LoggerFrom(ctx).ErrorContext(ctx, "can't create transactions", slog.Int("batch", batches), slog.Any("error", err))
+				return ErrCantCreateTransactions
+			}
+			summary.Inserted += int(inserted)
+			batches++
+			batch = batch[:0]
+			return nil
+		}
+		snapshot := func() error {
+			for account, first := range firstDates {
+				if err := mySQLSnapshotBalances(ctx, account, first, rates); err != nil {
+					LoggerFrom(ctx).ErrorContext(ctx, "can't snapshot balances", slog.String("account", account), slog.Any("error", err))
+					return ErrCantSnapshotBalances
+				}
+			}
+			return nil
+		}
+
+		err = readStatement(ctx, filename, opts, func(t Transaction) error {
+			if t.Account == "" {
+				t.Account = DefaultAccountID
+			}
+			if t.Currency == "" {
+				t.Currency = currencies.For(t.Account)
+			}
+			if first, ok := firstDates[t.Account]; !ok || t.Date.Before(first) {
+				firstDates[t.Account] = t.Date
+			}
+			t.Category = categorizer.Categorize(t)
+			summary.Add(t)
+			batch = append(batch, t)
+			if len(batch) == batchSize {
+				return flush()
+			}
+			return nil
+		})
+		if err == nil {
+			err = flush()
+		}
+		if err == nil {
+			err = snapshot()
+		}
+		if err != nil {
+			spanError(span, err)
+			return Summary{}, err
+		}
+
+		span.SetAttributes(attribute.Int("import.transactions", summary.Count), attribute.Int("import.inserted", summary.Inserted), attribute.Int("import.batches", batches))
+		return summary, nil
+	}
+}
+
+// yieldAll calls yield with every transaction
+func yieldAll(transactions []Transaction, yield func(Transaction) error) error {
+	for _, t := range transactions {
+		if err := yield(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
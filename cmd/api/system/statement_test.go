@@ -0,0 +1,213 @@
+package system_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func collect(ctx context.Context, readStatement system.ReadStatement, filename string) ([]system.Transaction, error) {
+	var transactions []system.Transaction
+	err := readStatement(ctx, filename, system.MockImportOptions(), func(t system.Transaction) error {
+		transactions = append(transactions, t)
+		return nil
+	})
+	return transactions, err
+}
+
+func TestReadStatement_success(t *testing.T) {
+	csvTransactions := []system.Transaction{system.MockTransaction(0, system.MockTransactions()[0].Date, "credit", 60.5)}
+	ofxTransactions := []system.Transaction{{ExternalID: "CC-0001"}}
+	readStatement := system.MakeReadStatement(system.MockStreamCSV(csvTransactions, nil), system.MockReadOFX(ofxTransactions, nil), system.MockReadCamt053(nil, nil), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		filename string
+		want     []system.Transaction
+	}{
+		{"csv", filepath.Join("data", system.DataFile), csvTransactions},
+		{"ofx sgml", filepath.Join("testdata", "statement_v1.ofx"), ofxTransactions},
+		{"ofx xml", filepath.Join("testdata", "statement_v2.qfx"), ofxTransactions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(ctx, readStatement, tt.filename)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadStatement_readsFromTheFilesOfTheOptions(t *testing.T) {
+	transactions := []system.Transaction{system.MockTransaction(0, system.MockTransactions()[0].Date, "credit", 60.5)}
+	readStatement := system.MakeReadStatement(system.MockStreamCSV(transactions, nil), system.MockReadOFX(nil, nil), system.MockReadCamt053(nil, nil), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	opts := system.MockImportOptions()
+	opts.Files = system.SampleData()
+
+	var got []system.Transaction
+	err := readStatement(context.Background(), system.DataFile, opts, func(t system.Transaction) error {
+		got = append(got, t)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, transactions, got)
+}
+
+func TestReadStatement_failsWhenCantOpenFile(t *testing.T) {
+	readStatement := system.MakeReadStatement(system.MockStreamCSV(nil, nil), system.MockReadOFX(nil, nil), system.MockReadCamt053(nil, nil), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+
+	want := system.ErrOpeningStatement
+	_, got := collect(context.Background(), readStatement, "")
+
+	assert.Equal(t, want, got)
+}
+
+func TestReadStatement_successWithCamt053(t *testing.T) {
+	transactions := []system.Transaction{system.MockTransaction(0, system.MockTransactions()[0].Date, "credit", 60.5)}
+	statements := []system.BankStatement{{
+		OpeningBalance: &system.Balance{Amount: 100},
+		ClosingBalance: &system.Balance{Amount: 160.5},
+		Transactions:   transactions,
+	}}
+	readStatement := system.MakeReadStatement(system.MockStreamCSV(nil, nil), system.MockReadOFX(nil, nil), system.MockReadCamt053(statements, nil), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+
+	got, err := collect(context.Background(), readStatement, filepath.Join("testdata", "statement.camt053.xml"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, transactions, got)
+}
+
+func TestReadStatement_failsWhenCamt053DoesntReconcile(t *testing.T) {
+	statements := []system.BankStatement{{
+		OpeningBalance: &system.Balance{Amount: 100},
+		ClosingBalance: &system.Balance{Amount: 100},
+		Transactions:   []system.Transaction{system.MockTransaction(0, system.MockTransactions()[0].Date, "credit", 60.5)},
+	}}
+	readStatement := system.MakeReadStatement(system.MockStreamCSV(nil, nil), system.MockReadOFX(nil, nil), system.MockReadCamt053(statements, nil), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+
+	want := system.ErrStatementNotReconciled
+	_, got := collect(context.Background(), readStatement, filepath.Join("testdata", "statement.camt053.xml"))
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportStatement_success(t *testing.T) {
+	var batches [][]system.Transaction
+	mysqlCreate := func(_ context.Context, transactions []system.Transaction) (int64, error) {
+		batches = append(batches, append([]system.Transaction(nil), transactions...))
+		// the first batch was imported before
+		if len(batches) == 1 {
+			return 0, nil
+		}
+		return int64(len(transactions)), nil
+	}
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), mysqlCreate, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	got, err := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	wantEmail := system.MockEmail()
+	wantEmail.Currency = system.DefaultCurrency
+	wantEmail.Currencies = []system.CurrencyTotal{{
+		Currency:  system.DefaultCurrency,
+		Count:     21,
+		Credit:    2 * wantEmail.AverageCredit,
+		Debit:     2 * wantEmail.AverageDebit,
+		Total:     wantEmail.Balance,
+		Converted: wantEmail.Balance,
+	}}
+	assert.Nil(t, err)
+	assert.Equal(t, wantEmail, got.Email())
+	assert.Equal(t, 21, got.Count)
+	assert.Equal(t, 13, got.Inserted)
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[0], 8)
+	assert.Len(t, batches[2], 5)
+	want := system.MockTransactions()[20]
+	want.Category = system.DefaultCategory
+	want.Account = system.DefaultAccountID
+	want.Currency = system.DefaultCurrency
+	assert.Equal(t, want, batches[2][4])
+}
+
+func TestImportStatement_convertsToTheCurrencyOfTheAccount(t *testing.T) {
+	date := time.Date(2023, 12, 5, 0, 0, 0, 0, time.UTC)
+	usd := system.MockTransaction(1, date, "debit", -10)
+	usd.Currency = "USD"
+	eur := system.MockTransaction(2, date, "debit", -5)
+	eur.Currency = "EUR"
+	transactions := []system.Transaction{system.MockTransaction(0, date, "credit", 1000), usd, eur}
+	rates := system.NewExchangeRates([]system.ExchangeRate{{Date: date.AddDate(0, 0, -1), From: "USD", To: "MXN", Rate: 17.5}})
+	var saved []system.Transaction
+	mysqlCreate := func(_ context.Context, transactions []system.Transaction) (int64, error) {
+		saved = append(saved, transactions...)
+		return int64(len(transactions)), nil
+	}
+	importStatement := system.MakeImportStatement(system.MockReadStatement(transactions, nil), mysqlCreate, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{Default: "MXN"}, system.MockGetExchangeRates(rates, nil), system.DefaultGrouping, 8)
+
+	got, err := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 825.0, got.Total)
+	assert.Equal(t, 3, got.Count)
+	assert.Equal(t, system.CurrencyTotal{Currency: "USD", Count: 1, Debit: -10, Total: -10, Converted: -175}, got.Currencies["USD"])
+	assert.Equal(t, system.CurrencyTotal{Currency: "EUR", Count: 1, Debit: -5, Total: -5, Unconverted: 1}, got.Currencies["EUR"])
+	assert.Equal(t, "MXN", saved[0].Currency)
+	assert.Equal(t, -10.0, saved[1].Transaction)
+}
+
+func TestImportStatement_failsWhenGetExchangeRatesThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, system.ErrCantGetExchangeRates), system.DefaultGrouping, 8)
+
+	want := system.ErrCantGetExchangeRates
+	_, got := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportStatement_failsWhenReadStatementThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(nil, system.ErrReadingCsv), system.MockMySQLCreate(nil), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	want := system.ErrReadingCsv
+	_, got := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportStatement_failsWhenMySQLCreateThrowsError(t *testing.T) {
+	importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions(), nil), system.MockMySQLCreate(system.ErrCantRunQuery), system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 8)
+
+	want := system.ErrCantCreateTransactions
+	_, got := importStatement(context.Background(), "data.csv", system.MockImportOptions())
+
+	assert.Equal(t, want, got)
+}
+
+func TestImportStatement_stopsWhenContextIsCanceled(t *testing.T) {
+	filename := writeGeneratedCSV(t, 10000)
+	ctx, cancel := context.WithCancel(context.Background())
+	var inserted int
+	mysqlCreate := func(_ context.Context, transactions []system.Transaction) (int64, error) {
+		inserted += len(transactions)
+		if inserted == 2000 {
+			cancel()
+		}
+		return int64(len(transactions)), nil
+	}
+	streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+	readStatement := system.MakeReadStatement(streamCSV, system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, mysqlCreate, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, 1000)
+
+	_, err := importStatement(ctx, filename, system.MockImportOptions())
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 2000, inserted)
+}
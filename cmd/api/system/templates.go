@@ -0,0 +1,216 @@
+package system
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// AccountInfoTemplate is the page of the statement email
+	AccountInfoTemplate string = "account_info"
+
+	layoutsPattern  string = "layouts/*.html"
+	partialsPattern string = "partials/*.html"
+	pagesPattern    string = "pages/*.html"
+)
+
+//go:embed html/layouts html/partials html/pages
+var embeddedTemplates embed.FS
+
+// TemplateRegistry holds the HTML pages, each one parsed once together with every layout and partial. Pages are
+// named after their file without the .html extension, and every page is bound and escaped once for each catalog, so
+// rendering only executes it. With reload, the pages are parsed again from the source whenever one of its files
+// changes
+type TemplateRegistry struct {
+	source   fs.FS
+	catalogs map[string]Catalog
+	reload   bool
+
+	mu      sync.Mutex
+	pages   map[string]map[string]*template.Template
+	version string
+}
+
+// EmbeddedTemplates returns the templates built into the binary, laid out as layouts/, partials/ and pages/
+func EmbeddedTemplates() fs.FS {
+	templates, _ := fs.Sub(embeddedTemplates, "html")
+	return templates
+}
+
+// NewTemplateRegistry parses the templates of the source and binds them to the functions of every catalog, failing
+// on the first syntax or escaping error
+func NewTemplateRegistry(source fs.FS, catalogs map[string]Catalog, reload bool) (*TemplateRegistry, error) {
+	version, err := templatesVersion(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrReadTemplateFile, err)
+	}
+	pages, err := parseTemplates(source, catalogs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTemplateParse, err)
+	}
+
+	return &TemplateRegistry{source: source, catalogs: catalogs, reload: reload, pages: pages, version: version}, nil
+}
+
+// Names returns the names of the pages, sorted
+func (r *TemplateRegistry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.pages))
+	for name := range r.pages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the page with the data and the functions of the catalog of the locale. A message missing from the
+// catalog fails the execution
+func (r *TemplateRegistry) Render(ctx context.Context, w io.Writer, name string, locale string, data any) error {
+	ctx, span := startSpan(ctx, "RenderTemplate", attribute.String("template.name", name), attribute.String("template.locale", locale))
+	defer span.End()
+
+	pages, err := r.current()
+	if err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't reload templates", slog.Any("error", err))
+		spanError(span, err)
+		return ErrTemplateParse
+	}
+
+	locales, ok := pages[name]
+	if !ok {
+		LoggerFrom(ctx).ErrorContext(ctx, "unknown template", slog.String("template", name))
+		spanError(span, ErrUnknownTemplate)
+		return ErrUnknownTemplate
+	}
+	page, ok := locales[locale]
+	if !ok {
+		LoggerFrom(ctx).ErrorContext(ctx, "unknown locale", slog.String("template", name), slog.String("locale", locale))
+		spanError(span, ErrUnknownLocale)
+		return ErrUnknownLocale
+	}
+
+	if err := page.Execute(w, data); err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't execute template", slog.String("template", name), slog.Any("error", err))
+		spanError(span, err)
+		return ErrTemplateExecute
+	}
+
+	return nil
+}
+
+// current returns the pages of every locale, parsing them again first when reload is on and the source changed. A
+// source that doesn't parse anymore is an error until it is fixed
+func (r *TemplateRegistry) current() (map[string]map[string]*template.Template, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.reload {
+		return r.pages, nil
+	}
+
+	version, err := templatesVersion(r.source)
+	if err != nil {
+		return nil, err
+	}
+	if version == r.version {
+		return r.pages, nil
+	}
+
+	pages, err := parseTemplates(r.source, r.catalogs)
+	if err != nil {
+		return nil, err
+	}
+	r.pages, r.version = pages, version
+
+	return pages, nil
+}
+
+// parseTemplates parses every page of the source together with the layouts and the partials, and clones it for
+// each catalog. Since the files are named after their base name, pages, layouts and partials can't share one
+func parseTemplates(source fs.FS, catalogs map[string]Catalog) (map[string]map[string]*template.Template, error) {
+	var shared []string
+	for _, pattern := range []string{layoutsPattern, partialsPattern} {
+		filenames, err := fs.Glob(source, pattern)
+		if err != nil {
+			return nil, err
+		}
+		shared = append(shared, filenames...)
+	}
+
+	filenames, err := fs.Glob(source, pagesPattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("no pages match %s", pagesPattern)
+	}
+
+	pages := make(map[string]map[string]*template.Template, len(filenames))
+	for _, filename := range filenames {
+		base := path.Base(filename)
+		tmpl, err := template.New(base).Funcs(Catalog{}.Funcs()).ParseFS(source, append([]string{filename}, shared...)...)
+		if err != nil {
+			return nil, err
+		}
+
+		locales := make(map[string]*template.Template, len(catalogs))
+		for locale, catalog := range catalogs {
+			// the parsed page is never executed, so it can be cloned to bind the functions of each catalog
+			page, err := tmpl.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if err := escapeTemplate(page.Funcs(catalog.Funcs())); err != nil {
+				return nil, err
+			}
+			locales[locale] = page
+		}
+		pages[strings.TrimSuffix(base, ".html")] = locales
+	}
+
+	return pages, nil
+}
+
+// escapeTemplate escapes the page ahead of its first render by executing it without data. html/template escapes a
+// template once, before its first execution, so only escaping errors matter here
+func escapeTemplate(page *template.Template) error {
+	var escapeErr *template.Error
+	if err := page.Execute(io.Discard, nil); errors.As(err, &escapeErr) {
+		return err
+	}
+	return nil
+}
+
+// templatesVersion identifies the contents of the source by the name, size and modification time of its files
+func templatesVersion(source fs.FS) (string, error) {
+	var version strings.Builder
+	for _, pattern := range []string{layoutsPattern, partialsPattern, pagesPattern} {
+		filenames, err := fs.Glob(source, pattern)
+		if err != nil {
+			return "", err
+		}
+		for _, filename := range filenames {
+			info, err := fs.Stat(source, filename)
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&version, "%s:%d:%d;", filename, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	return version.String(), nil
+}
@@ -0,0 +1,168 @@
+package system_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func mockTemplates() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`{{define "base"}}<html lang="{{locale}}">{{template "content" .}}</html>{{end}}`)},
+		"partials/name.html": {Data: []byte(`{{define "name"}}<b>{{.}}</b>{{end}}`)},
+		"pages/hello.html":   {Data: []byte(`{{template "base" .}}{{define "content"}}{{t "greeting"}} {{template "name" .}}{{end}}`)},
+		"pages/bye.html":     {Data: []byte(`{{template "base" .}}{{define "content"}}{{t "thanks"}} {{template "name" .}}{{end}}`)},
+	}
+}
+
+func mockCatalogs() map[string]system.Catalog {
+	return map[string]system.Catalog{
+		"en": {Locale: "en", Messages: map[string]string{"greeting": "Hello", "thanks": "Thanks"}},
+		"es": {Locale: "es", Messages: map[string]string{"greeting": "Hola"}},
+	}
+}
+
+func render(t *testing.T, templates *system.TemplateRegistry, name string, locale string, data any) (string, error) {
+	t.Helper()
+	var got bytes.Buffer
+	err := templates.Render(context.Background(), &got, name, locale, data)
+	return got.String(), err
+}
+
+func TestNewTemplateRegistry_parsesTheEmbeddedTemplates(t *testing.T) {
+	templates, err := system.NewTemplateRegistry(system.EmbeddedTemplates(), system.MockLocalizer().Catalogs, false)
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{system.AccountInfoTemplate}, templates.Names())
+}
+
+func TestNewTemplateRegistry_failsWhenATemplateDoesntParse(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+	}{
+		{"page", "pages/hello.html", `{{if .}}`},
+		{"partial", "partials/name.html", `{{define "name"}}{{.}`},
+		{"unknown function", "pages/hello.html", `{{upper .}}`},
+		{"escaping", "pages/hello.html", `{{if .}}<a{{end}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := mockTemplates()
+			source[tt.file] = &fstest.MapFile{Data: []byte(tt.data)}
+
+			_, err := system.NewTemplateRegistry(source, mockCatalogs(), false)
+
+			assert.ErrorIs(t, err, system.ErrTemplateParse)
+		})
+	}
+}
+
+func TestTemplateRegistry_Render_sharesLayoutsAndPartials(t *testing.T) {
+	templates, err := system.NewTemplateRegistry(mockTemplates(), mockCatalogs(), false)
+	assert.Nil(t, err)
+
+	hello, errHello := render(t, templates, "hello", "en", "Ana")
+	bye, errBye := render(t, templates, "bye", "en", "Ana")
+
+	assert.Nil(t, errHello)
+	assert.Nil(t, errBye)
+	assert.Equal(t, `<html lang="en">Hello <b>Ana</b></html>`, hello)
+	assert.Equal(t, `<html lang="en">Thanks <b>Ana</b></html>`, bye)
+}
+
+func TestTemplateRegistry_Render_usesTheFunctionsOfEachCatalog(t *testing.T) {
+	templates, _ := system.NewTemplateRegistry(mockTemplates(), mockCatalogs(), false)
+
+	gotEn, _ := render(t, templates, "hello", "en", "Ana")
+	gotEs, _ := render(t, templates, "hello", "es", "Ana")
+
+	assert.Equal(t, `<html lang="en">Hello <b>Ana</b></html>`, gotEn)
+	assert.Equal(t, `<html lang="es">Hola <b>Ana</b></html>`, gotEs)
+}
+
+func TestTemplateRegistry_Render_fails(t *testing.T) {
+	templates, _ := system.NewTemplateRegistry(mockTemplates(), mockCatalogs(), false)
+
+	tests := []struct {
+		name     string
+		template string
+		locale   string
+		want     error
+	}{
+		{"unknown template", "welcome", "en", system.ErrUnknownTemplate},
+		{"unknown locale", "hello", "fr", system.ErrUnknownLocale},
+		{"missing translation", "bye", "es", system.ErrTemplateExecute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got := render(t, templates, tt.template, tt.locale, "Ana")
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplateRegistry_Render_reloadsChangedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range mockTemplates() {
+		_ = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o700)
+		_ = os.WriteFile(filepath.Join(dir, name), file.Data, 0o600)
+	}
+	page := filepath.Join(dir, "pages", "hello.html")
+	modified := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		reload bool
+		want   string
+	}{
+		{"with reload", true, `<html lang="en">Hi <i>Ana</i></html>`},
+		{"without reload", false, `<html lang="en">Hello <b>Ana</b></html>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = os.WriteFile(page, []byte(`{{template "base" .}}{{define "content"}}{{t "greeting"}} {{template "name" .}}{{end}}`), 0o600)
+			templates, err := system.NewTemplateRegistry(os.DirFS(dir), mockCatalogs(), tt.reload)
+			assert.Nil(t, err)
+
+			_ = os.WriteFile(page, []byte(`{{template "base" .}}{{define "content"}}Hi <i>{{.}}</i>{{end}}`), 0o600)
+			_ = os.Chtimes(page, modified, modified)
+			got, err := render(t, templates, "hello", "en", "Ana")
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplateRegistry_Render_failsUntilABrokenTemplateIsFixed(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range mockTemplates() {
+		_ = os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o700)
+		_ = os.WriteFile(filepath.Join(dir, name), file.Data, 0o600)
+	}
+	page := filepath.Join(dir, "pages", "bye.html")
+	templates, _ := system.NewTemplateRegistry(os.DirFS(dir), mockCatalogs(), true)
+
+	_ = os.WriteFile(page, []byte(`{{if .}}`), 0o600)
+	_, broken := render(t, templates, "hello", "en", "Ana")
+	_ = os.WriteFile(page, []byte(`bye`), 0o600)
+	got, fixed := render(t, templates, "bye", "en", "Ana")
+
+	assert.Equal(t, system.ErrTemplateParse, broken)
+	assert.Nil(t, fixed)
+	assert.Equal(t, "bye", got)
+}
@@ -0,0 +1,122 @@
+package system_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+func newSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func spansByName(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	byName := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+	return byName
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing_spanTree(t *testing.T) {
+	recorder := newSpanRecorder(t)
+	db, mock, _ := sqlmock.New()
+	mock.ExpectPrepare(queryCreateMock)
+	mock.ExpectExec(queryCreateMock).WillReturnResult(sqlmock.NewResult(1, 21))
+	metrics := system.NewMetricsNop()
+	mysqlCreate := system.MakeMySQLCreate(db, metrics)
+	readStatement := system.MakeReadStatement(system.MakeStreamCSV(metrics, system.MockImportProfiles()), system.MakeReadOFX(), system.MakeReadCamt053(), system.DefaultArchiveConfig(), system.Timezones{}, system.EncodingAuto)
+	importStatement := system.MakeImportStatement(readStatement, mysqlCreate, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+	htmlProcessTransactions := system.MakeHTMLProcessTransactions(importStatement, mockGetStatementSummary(), system.MockGetBalances(system.AccountBalances{}, nil), system.MockGetRecurring(nil, nil), system.MockGetAnomalies(system.Anomalies{}, nil), system.MockGetForecast(system.Forecast{}, nil), system.MockGetBudgetReport(nil, nil), system.MockAlertBudgets(nil), system.MockMySQLIssueStatement(nil), metrics, system.Timezones{}, system.StatementCycle{}, system.MockLocalizer(), system.MockTemplateRegistry(), system.SampleData())
+	app := gin.New()
+	app.ContextWithFallback = true
+	app.Use(system.TracingMiddleware())
+	app.GET("/system/html/v1", system.GetHTMLInfoV1(htmlProcessTransactions))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/system/html/v1", nil))
+
+	spans := spansByName(recorder.Ended())
+	root := spans["GET /system/html/v1"]
+	process := spans["HTMLProcessTransactions"]
+	importSpan := spans["ImportStatement"]
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, spans, 7)
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind())
+	assert.False(t, root.Parent().IsValid())
+	assert.Equal(t, int64(http.StatusOK), spanAttribute(root, "http.response.status_code").AsInt64())
+	assert.Equal(t, root.SpanContext().SpanID(), process.Parent().SpanID())
+	assert.Equal(t, process.SpanContext().SpanID(), importSpan.Parent().SpanID())
+	assert.Equal(t, importSpan.SpanContext().SpanID(), spans["ReadCSV"].Parent().SpanID())
+	assert.Equal(t, importSpan.SpanContext().SpanID(), spans["MySQLCreate"].Parent().SpanID())
+	assert.Equal(t, process.SpanContext().SpanID(), spans["GetStatementSummary"].Parent().SpanID())
+	assert.Equal(t, process.SpanContext().SpanID(), spans["RenderTemplate"].Parent().SpanID())
+	assert.Equal(t, int64(21), spanAttribute(spans["ReadCSV"], "csv.rows_parsed").AsInt64())
+	assert.Equal(t, int64(0), spanAttribute(spans["ReadCSV"], "csv.rows_rejected").AsInt64())
+	assert.Equal(t, int64(21), spanAttribute(spans["MySQLCreate"], "db.rows_inserted").AsInt64())
+	assert.Equal(t, int64(1), spanAttribute(importSpan, "import.batches").AsInt64())
+}
+
+func TestTracing_recordsErrors(t *testing.T) {
+	recorder := newSpanRecorder(t)
+	streamCSV := system.MakeStreamCSV(system.NewMetricsNop(), system.MockImportProfiles())
+
+	err := streamCSV(context.Background(), strings.NewReader("Reference,When,Value\n"), system.MockImportOptions(), func(system.Transaction) error { return nil })
+
+	spans := recorder.Ended()
+	assert.Equal(t, system.ErrUnknownCSVFormat, err)
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestTracingMiddleware_continuesIncomingTrace(t *testing.T) {
+	recorder := newSpanRecorder(t)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	app := gin.New()
+	app.Use(system.TracingMiddleware())
+	app.GET("/health/live", system.GetHealthLiveV1())
+
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	app.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestNewTracerProvider_failsWhenExporterIsInvalid(t *testing.T) {
+	_, err := system.NewTracerProvider(context.Background(), system.TracingConfig{Exporter: "zipkin"})
+
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,8 @@
 package system
 
 import (
+	"io/fs"
+	"sort"
 	"time"
 )
 
@@ -8,41 +10,216 @@ type (
 	Transaction struct {
 		ID          int64
 		Date        time.Time
+		ValueDate   time.Time
 		Transaction float64
 		Type        string
+		ExternalID  string
+		Description string
+		Merchant    string
+		Category    string
+		Account     string
+		Currency    string
 	}
 
+	// StatementPeriod is the range of calendar dates, both inclusive, covered by an import or by a statement
+	StatementPeriod struct {
+		Start time.Time `json:"start"`
+		End   time.Time `json:"end"`
+	}
+
+	// ImportOptions describe how the dates of an import are interpreted and which account the transactions belong
+	// to when the statement doesn't say it. The statement is opened from Files, or from the OS file system when it's
+	// nil
+	ImportOptions struct {
+		Period   StatementPeriod
+		Location *time.Location
+		Account  string
+		Files    fs.FS
+	}
+
+	// Summary holds the aggregates of a set of transactions. It is computed one transaction at a time, so it
+	// doesn't need to keep the transactions in memory. Periods are grouped by Grouping, by month when it's empty.
+	// With a Currency, the amounts are converted to it with Rates, transactions without a rate are only counted in
+	// Currencies, and transactions without a currency are taken as already in it. Inserted counts the transactions
+	// an import stored, which leaves out the ones already imported before
+	Summary struct {
+		Count      int
+		Inserted   int
+		Total      float64
+		Debit      float64
+		Credit     float64
+		Grouping   string
+		Periods    map[string]Period
+		Spending   map[string]float64
+		Currency   string
+		Rates      ExchangeRates
+		Currencies map[string]CurrencyTotal
+	}
+
+	// Email is the account information sent to the customer
 	Email struct {
-		Balance       float64
-		AverageDebit  float64
-		AverageCredit float64
-		WorkingMonths map[string]int
+		Currency       string          `json:"currency,omitempty"`
+		Balance        float64         `json:"balance"`
+		OpeningBalance float64         `json:"opening_balance"`
+		ClosingBalance float64         `json:"closing_balance"`
+		AverageDebit   float64         `json:"average_debit"`
+		AverageCredit  float64         `json:"average_credit"`
+		Currencies     []CurrencyTotal `json:"currencies,omitempty"`
+		Periods        []Period        `json:"periods"`
+		Spending       []CategorySpend `json:"spending"`
+		Recurring      []Recurring     `json:"recurring"`
+		Anomalies      []Anomaly       `json:"anomalies"`
+		CountSpikes    []CountSpike    `json:"count_spikes"`
+		Forecast       *Forecast       `json:"forecast,omitempty"`
+		Budgets        []BudgetStatus  `json:"budgets"`
+	}
+
+	// Statement is the email issued to an account for a statement period. Once issued it never changes, so it can
+	// be shown again exactly as it was sent even if its transactions are corrected later
+	Statement struct {
+		ID             int64           `json:"id"`
+		Account        string          `json:"account"`
+		Period         StatementPeriod `json:"period"`
+		OpeningBalance float64         `json:"opening_balance"`
+		ClosingBalance float64         `json:"closing_balance"`
+		Summary        Email           `json:"summary"`
+		IssuedAt       time.Time       `json:"issued_at"`
 	}
 )
 
-func getBalanceInfo(transactions []Transaction) (float64, float64, float64) {
-	var total, debit, credit float64
-	for _, t := range transactions {
-		total += t.Transaction
+// NewStatementPeriod returns the twelve months period that ends on the calendar date of end, where the dates
+// without a year of an import are placed
+func NewStatementPeriod(end time.Time) StatementPeriod {
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	return StatementPeriod{
+		Start: end.AddDate(-1, 0, 1),
+		End:   end,
+	}
+}
+
+// Contains reports whether the calendar date of t is inside the period
+func (p StatementPeriod) Contains(t time.Time) bool {
+	return !t.Before(p.Start) && !t.After(p.End)
+}
+
+// NewImportOptions returns the options of an import made at now by an account in the given location
+func NewImportOptions(now time.Time, loc *time.Location) ImportOptions {
+	return ImportOptions{
+		Period:   NewStatementPeriod(now.In(loc)),
+		Location: loc,
+	}
+}
 
-		if t.Type == "debit" {
-			debit += t.Transaction
+// Add accumulates the transaction in the summary
+func (s *Summary) Add(t Transaction) {
+	s.Count++
+	if s.Currency != "" {
+		if t.Currency == "" {
+			t.Currency = s.Currency
 		}
-		if t.Type == "credit" {
-			credit += t.Transaction
+		amount, ok := s.Rates.Convert(t.Transaction, t.Currency, s.Currency, t.Date)
+		if s.Currencies == nil {
+			s.Currencies = make(map[string]CurrencyTotal)
 		}
+		total := s.Currencies[t.Currency]
+		total.Currency = t.Currency
+		total.add(t, amount, ok)
+		s.Currencies[t.Currency] = total
+		if !ok {
+			return
+		}
+		t.Transaction = amount
+	}
+	s.Total += t.Transaction
+
+	if t.Type == "debit" {
+		s.Debit += t.Transaction
+		if t.Category != "" {
+			if s.Spending == nil {
+				s.Spending = make(map[string]float64)
+			}
+			s.Spending[t.Category] -= t.Transaction
+		}
+	}
+	if t.Type == "credit" {
+		s.Credit += t.Transaction
 	}
 
-	return total, debit / 2, credit / 2
+	if s.Periods == nil {
+		s.Periods = make(map[string]Period)
+	}
+	period := newPeriod(t.Date, s.Grouping)
+	if existing, ok := s.Periods[period.Key]; ok {
+		period = existing
+	}
+	period.add(t)
+	s.Periods[period.Key] = period
+}
+
+// Email returns the account information shown in the email
+func (s Summary) Email() Email {
+	return Email{
+		Currency:      s.Currency,
+		Balance:       s.Total,
+		AverageDebit:  s.Debit / 2,
+		AverageCredit: s.Credit / 2,
+		Currencies:    s.totalsByCurrency(),
+		Periods:       sortedPeriods(s.Periods),
+		Spending:      s.spendingByCategory(),
+	}
+}
+
+// totalsByCurrency returns the totals of every currency sorted by code
+func (s Summary) totalsByCurrency() []CurrencyTotal {
+	if len(s.Currencies) == 0 {
+		return nil
+	}
+
+	totals := make([]CurrencyTotal, 0, len(s.Currencies))
+	for _, total := range s.Currencies {
+		totals = append(totals, total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		return totals[i].Currency < totals[j].Currency
+	})
+
+	return totals
 }
 
-func transactionsPerMonth(transactions []Transaction) map[string]int {
-	monthCount := make(map[string]int)
+// spendingByCategory returns the money spent in every category, the largest first
+func (s Summary) spendingByCategory() []CategorySpend {
+	if len(s.Spending) == 0 {
+		return nil
+	}
+
+	spending := make([]CategorySpend, 0, len(s.Spending))
+	for category, amount := range s.Spending {
+		spending = append(spending, CategorySpend{Category: category, Amount: amount})
+	}
+	sort.Slice(spending, func(i, j int) bool {
+		if spending[i].Amount != spending[j].Amount {
+			return spending[i].Amount > spending[j].Amount
+		}
+		return spending[i].Category < spending[j].Category
+	})
+
+	return spending
+}
 
+func summarize(transactions []Transaction, grouping string) Summary {
+	s := Summary{Grouping: grouping}
 	for _, t := range transactions {
-		month := t.Date.Month().String()
-		monthCount[month]++
+		s.Add(t)
 	}
 
-	return monthCount
+	return s
+}
+
+func getBalanceInfo(transactions []Transaction) (float64, float64, float64) {
+	email := summarize(transactions, DefaultGrouping).Email()
+	return email.Balance, email.AverageDebit, email.AverageCredit
+}
+
+func transactionsPerPeriod(transactions []Transaction, grouping string) []Period {
+	return summarize(transactions, grouping).Email().Periods
 }
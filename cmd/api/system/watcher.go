@@ -0,0 +1,279 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	ProcessedFolder string = "processed"
+	FailedFolder    string = "failed"
+
+	// ErrorReportSuffix is appended to the name of a failed file to name its error report
+	ErrorReportSuffix string = ".error.json"
+
+	// DefaultPollInterval is how often the drop folder is scanned
+	DefaultPollInterval time.Duration = 10 * time.Second
+
+	// DefaultSettleTime is how long a file must stay unmodified before it is imported
+	DefaultSettleTime time.Duration = 2 * time.Second
+
+	errInterruptedImport string = "import interrupted before it finished"
+)
+
+type (
+	// WatcherConfig tells which folder is watched and how
+	WatcherConfig struct {
+		Dir          string
+		PollInterval time.Duration
+		SettleTime   time.Duration
+		Polling      bool
+	}
+
+	// IngestFile is a function that imports a file of the drop folder, records the result and moves the file to the
+	// processed or failed folder. It returns an error only when the file is left in the drop folder to be retried
+	IngestFile func(ctx context.Context, filename string) error
+
+	// WatchDirectory is a function that ingests the files dropped in a folder until ctx is done
+	WatchDirectory func(ctx context.Context) error
+
+	// ErrorReport is written next to a failed file to tell why it couldn't be imported
+	ErrorReport struct {
+		Filename string    `json:"filename"`
+		Checksum string    `json:"checksum"`
+		Error    string    `json:"error"`
+		FailedAt time.Time `json:"failed_at"`
+	}
+)
+
+// MakeIngestFile creates an IngestFile function. Files are identified by the checksum of their content, so a file is
+// imported only once, even when it is dropped again or the service restarts while moving it. A file whose import was
+// interrupted is moved to the failed folder instead of being imported twice; dropping it again retries it
+func MakeIngestFile(importStatement ImportStatement, mySQLFindImport MySQLFindImport, mySQLSaveImport MySQLSaveImport, metrics *Metrics, timezones Timezones) IngestFile {
+	return func(ctx context.Context, filename string) error {
+		logger := LoggerFrom(ctx).With(slog.String("filename", filename))
+
+		checksum, err := fileChecksum(filename)
+		if err != nil {
+			logger.ErrorContext(ctx, "can't read dropped file", slog.Any("error", err))
+			return ErrCantReadFile
+		}
+
+		record, err := mySQLFindImport(ctx, checksum)
+		switch {
+		case errors.Is(err, ErrImportNotFound):
+		case err != nil:
+			return err
+		case record.Status == ImportStatusProcessed:
+			logger.InfoContext(ctx, "file already imported", slog.String("checksum", checksum), slog.Time("imported_at", record.FinishedAt))
+			_, err := moveDroppedFile(filename, ProcessedFolder)
+			return err
+		case record.Status == ImportStatusImporting:
+			record.Status = ImportStatusFailed
+			record.Error = errInterruptedImport
+			record.FinishedAt = time.Now().UTC()
+			return finishIngest(ctx, logger, filename, record, mySQLSaveImport, metrics)
+		}
+
+		record = ImportRecord{
+			Checksum:  checksum,
+			Filename:  filepath.Base(filename),
+			Status:    ImportStatusImporting,
+			StartedAt: time.Now().UTC(),
+		}
+		if err := mySQLSaveImport(ctx, record); err != nil {
+			return err
+		}
+
+		logger.InfoContext(ctx, "importing dropped file", slog.String("checksum", checksum))
+		opts := NewImportOptions(time.Now(), timezones.For(DefaultAccountID))
+		summary, err := importStatement(ctx, filename, opts)
+		if ctx.Err() != nil {
+			// The service is stopping: the record stays importing and the next start reports it as interrupted
+			return ctx.Err()
+		}
+		record.FinishedAt = time.Now().UTC()
+		record.Status = ImportStatusProcessed
+		record.Transactions = summary.Inserted
+		if err != nil {
+			record.Status = ImportStatusFailed
+			record.Error = err.Error()
+		}
+
+		return finishIngest(ctx, logger, filename, record, mySQLSaveImport, metrics)
+	}
+}
+
+// finishIngest records the result of an import and moves the file out of the drop folder, with an error report when
+// it failed
+func finishIngest(ctx context.Context, logger *slog.Logger, filename string, record ImportRecord, mySQLSaveImport MySQLSaveImport, metrics *Metrics) error {
+	if err := mySQLSaveImport(ctx, record); err != nil {
+		return err
+	}
+	metrics.FilesIngested.WithLabelValues(record.Status).Inc()
+
+	if record.Status == ImportStatusProcessed {
+		logger.InfoContext(ctx, "dropped file imported", slog.Int("transactions", record.Transactions))
+		_, err := moveDroppedFile(filename, ProcessedFolder)
+		return err
+	}
+
+	logger.WarnContext(ctx, "dropped file failed", slog.String("reason", record.Error))
+	target, err := moveDroppedFile(filename, FailedFolder)
+	if err != nil {
+		return err
+	}
+
+	report, err := json.MarshalIndent(ErrorReport{
+		Filename: record.Filename,
+		Checksum: record.Checksum,
+		Error:    record.Error,
+		FailedAt: record.FinishedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(target+ErrorReportSuffix, report, 0o644); err != nil {
+		logger.ErrorContext(ctx, "can't write error report", slog.Any("error", err))
+	}
+
+	return nil
+}
+
+// MakeWatchDirectory creates a WatchDirectory function. The folder is scanned on start, every poll interval and
+// shortly after fsnotify reports a change. When fsnotify can't watch the folder, as on some network volumes, or
+// polling is forced, the folder is only scanned on the interval. Files are ingested one at a time, once they haven't
+// been modified for the settle time
+func MakeWatchDirectory(config WatcherConfig, ingestFile IngestFile) WatchDirectory {
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.SettleTime < 0 {
+		config.SettleTime = DefaultSettleTime
+	}
+
+	return func(ctx context.Context) error {
+		for _, folder := range []string{ProcessedFolder, FailedFolder} {
+			if err := os.MkdirAll(filepath.Join(config.Dir, folder), 0o755); err != nil {
+				return fmt.Errorf("can't create %s folder: %w", folder, err)
+			}
+		}
+
+		var events chan fsnotify.Event
+		var watchErrors chan error
+		if !config.Polling {
+			watcher, err := newFolderWatcher(config.Dir)
+			if err != nil {
+				LoggerFrom(ctx).WarnContext(ctx, "can't watch drop folder, polling instead", slog.String("dir", config.Dir), slog.Any("error", err))
+			} else {
+				defer watcher.Close()
+				events, watchErrors = watcher.Events, watcher.Errors
+			}
+		}
+		LoggerFrom(ctx).InfoContext(ctx, "watching drop folder", slog.String("dir", config.Dir), slog.Bool("fsnotify", events != nil))
+
+		ticker := time.NewTicker(config.PollInterval)
+		defer ticker.Stop()
+		settle := time.NewTimer(config.SettleTime)
+		defer settle.Stop()
+
+		scanDropFolder(ctx, config, ingestFile)
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				scanDropFolder(ctx, config, ingestFile)
+			case <-settle.C:
+				scanDropFolder(ctx, config, ingestFile)
+			case event := <-events:
+				if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) || event.Has(fsnotify.Rename) {
+					settle.Reset(config.SettleTime + 100*time.Millisecond)
+				}
+			case err := <-watchErrors:
+				LoggerFrom(ctx).WarnContext(ctx, "drop folder watcher error", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// newFolderWatcher watches the changes of the files directly inside dir
+func newFolderWatcher(dir string) (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	return watcher, nil
+}
+
+// scanDropFolder ingests the files of the drop folder that are settled, oldest first as returned by the file system
+func scanDropFolder(ctx context.Context, config WatcherConfig, ingestFile IngestFile) {
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		LoggerFrom(ctx).ErrorContext(ctx, "can't read drop folder", slog.String("dir", config.Dir), slog.Any("error", err))
+		return
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return
+		}
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < config.SettleTime {
+			continue
+		}
+
+		filename := filepath.Join(config.Dir, entry.Name())
+		if err := ingestFile(ctx, filename); err != nil {
+			LoggerFrom(ctx).ErrorContext(ctx, "can't ingest dropped file, it will be retried", slog.String("filename", filename), slog.Any("error", err))
+		}
+	}
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the content of a file
+func fileChecksum(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// moveDroppedFile moves a file of the drop folder to one of its subfolders and returns its new name. A file with the
+// same name that is already there is kept, and the moved file gets a timestamp suffix
+func moveDroppedFile(filename string, folder string) (string, error) {
+	target := filepath.Join(filepath.Dir(filename), folder, filepath.Base(filename))
+	if _, err := os.Stat(target); err == nil {
+		target = fmt.Sprintf("%s.%s", target, time.Now().UTC().Format("20060102T150405.000000000"))
+	}
+
+	return target, os.Rename(filename, target)
+}
@@ -0,0 +1,258 @@
+package system_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/cmd/api/system"
+)
+
+// importStore keeps the import records in memory, standing in for the imports table across restarts
+type importStore struct {
+	mu      sync.Mutex
+	records map[string]system.ImportRecord
+}
+
+func newImportStore() *importStore {
+	return &importStore{records: map[string]system.ImportRecord{}}
+}
+
+func (s *importStore) find(_ context.Context, checksum string) (system.ImportRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[checksum]
+	if !ok {
+		return system.ImportRecord{}, system.ErrImportNotFound
+	}
+	return record, nil
+}
+
+func (s *importStore) save(_ context.Context, record system.ImportRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Checksum] = record
+	return nil
+}
+
+func (s *importStore) statuses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var statuses []string
+	for _, record := range s.records {
+		statuses = append(statuses, record.Status)
+	}
+	return statuses
+}
+
+func makeDropFolder(t *testing.T) string {
+	dir := t.TempDir()
+	for _, folder := range []string{system.ProcessedFolder, system.FailedFolder} {
+		_ = os.MkdirAll(filepath.Join(dir, folder), 0o755)
+	}
+	return dir
+}
+
+func dropFile(t *testing.T, dir string, name string, content string) string {
+	filename := filepath.Join(dir, name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestIngestFile_success(t *testing.T) {
+	dir := makeDropFolder(t)
+	filename := dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n")
+	store := newImportStore()
+	metrics := system.NewMetricsNop()
+	ingestFile := system.MakeIngestFile(system.MockImportStatement(system.Summary{Count: 1, Inserted: 1}, nil), store.find, store.save, metrics, system.Timezones{})
+
+	err := ingestFile(context.Background(), filename)
+
+	assert.Nil(t, err)
+	assert.NoFileExists(t, filename)
+	assert.FileExists(t, filepath.Join(dir, system.ProcessedFolder, "data.csv"))
+	assert.Equal(t, []string{system.ImportStatusProcessed}, store.statuses())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.FilesIngested.WithLabelValues(system.ImportStatusProcessed)))
+}
+
+func TestIngestFile_recordsTheInsertedTransactions(t *testing.T) {
+	tests := []struct {
+		name     string
+		inserted int64
+		want     int
+	}{
+		{"new transactions", 3, 3},
+		{"transactions already stored", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := makeDropFolder(t)
+			filename := dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n1,2/1,-10.3\n2,3/1,-20.46\n")
+			store := newImportStore()
+			mysqlCreate := func(context.Context, []system.Transaction) (int64, error) {
+				return tt.inserted, nil
+			}
+			importStatement := system.MakeImportStatement(system.MockReadStatement(system.MockTransactions()[:3], nil), mysqlCreate, system.MockMySQLSnapshotBalances(nil), system.Categorizer{}, system.Currencies{}, system.MockGetExchangeRates(system.ExchangeRates{}, nil), system.DefaultGrouping, system.DefaultBatchSize)
+			ingestFile := system.MakeIngestFile(importStatement, store.find, store.save, system.NewMetricsNop(), system.Timezones{})
+
+			err := ingestFile(context.Background(), filename)
+
+			assert.Nil(t, err)
+			for _, record := range store.records {
+				assert.Equal(t, system.ImportStatusProcessed, record.Status)
+				assert.Equal(t, tt.want, record.Transactions)
+			}
+		})
+	}
+}
+
+func TestIngestFile_movesFailedFileWithErrorReport(t *testing.T) {
+	dir := makeDropFolder(t)
+	filename := dropFile(t, dir, "data.csv", "Reference,When,Value\n")
+	store := newImportStore()
+	ingestFile := system.MakeIngestFile(system.MockImportStatement(system.Summary{}, system.ErrUnknownCSVFormat), store.find, store.save, system.NewMetricsNop(), system.Timezones{})
+
+	err := ingestFile(context.Background(), filename)
+
+	assert.Nil(t, err)
+	assert.FileExists(t, filepath.Join(dir, system.FailedFolder, "data.csv"))
+	content, _ := os.ReadFile(filepath.Join(dir, system.FailedFolder, "data.csv"+system.ErrorReportSuffix))
+	var report system.ErrorReport
+	_ = json.Unmarshal(content, &report)
+	assert.Equal(t, "data.csv", report.Filename)
+	assert.Equal(t, system.ErrUnknownCSVFormat.Error(), report.Error)
+	assert.Equal(t, []string{system.ImportStatusFailed}, store.statuses())
+}
+
+func TestIngestFile_doesntImportTheSameFileTwice(t *testing.T) {
+	dir := makeDropFolder(t)
+	store := newImportStore()
+	var imports int
+	importStatement := func(context.Context, string, system.ImportOptions) (system.Summary, error) {
+		imports++
+		return system.Summary{Count: 1}, nil
+	}
+	ingestFile := system.MakeIngestFile(importStatement, store.find, store.save, system.NewMetricsNop(), system.Timezones{})
+
+	_ = ingestFile(context.Background(), dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n"))
+	err := ingestFile(context.Background(), dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n"))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, imports)
+	processed, _ := os.ReadDir(filepath.Join(dir, system.ProcessedFolder))
+	assert.Len(t, processed, 2)
+}
+
+func TestIngestFile_failsInterruptedImportAfterRestart(t *testing.T) {
+	dir := makeDropFolder(t)
+	filename := dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n")
+	store := newImportStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := func(context.Context, string, system.ImportOptions) (system.Summary, error) {
+		cancel()
+		return system.Summary{}, context.Canceled
+	}
+	var imports int
+	importStatement := func(context.Context, string, system.ImportOptions) (system.Summary, error) {
+		imports++
+		return system.Summary{}, nil
+	}
+
+	firstRun := system.MakeIngestFile(interrupted, store.find, store.save, system.NewMetricsNop(), system.Timezones{})
+	errFirstRun := firstRun(ctx, filename)
+	secondRun := system.MakeIngestFile(importStatement, store.find, store.save, system.NewMetricsNop(), system.Timezones{})
+	errSecondRun := secondRun(context.Background(), filename)
+
+	assert.ErrorIs(t, errFirstRun, context.Canceled)
+	assert.Nil(t, errSecondRun)
+	assert.Equal(t, 0, imports)
+	assert.FileExists(t, filepath.Join(dir, system.FailedFolder, "data.csv"+system.ErrorReportSuffix))
+	assert.Equal(t, []string{system.ImportStatusFailed}, store.statuses())
+}
+
+func TestIngestFile_leavesFileWhenDatabaseFails(t *testing.T) {
+	dir := makeDropFolder(t)
+	filename := dropFile(t, dir, "data.csv", "Id,Date,Amount\n0,1/1,60.5\n")
+	mysqlFindImport := system.MockMySQLFindImport(system.ImportRecord{}, system.ErrCantRunQuery)
+	ingestFile := system.MakeIngestFile(system.MockImportStatement(system.Summary{}, nil), mysqlFindImport, system.MockMySQLSaveImport(nil), system.NewMetricsNop(), system.Timezones{})
+
+	want := system.ErrCantRunQuery
+	got := ingestFile(context.Background(), filename)
+
+	assert.Equal(t, want, got)
+	assert.FileExists(t, filename)
+}
+
+func TestWatchDirectory_ingestsDroppedFiles(t *testing.T) {
+	tests := []struct {
+		name         string
+		pollInterval time.Duration
+		polling      bool
+	}{
+		{"fsnotify", time.Hour, false},
+		{"polling", 50 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ingested := make(chan string, 10)
+			ingestFile := func(_ context.Context, filename string) error {
+				ingested <- filepath.Base(filename)
+				_ = os.Remove(filename)
+				return nil
+			}
+			config := system.WatcherConfig{Dir: dir, PollInterval: tt.pollInterval, SettleTime: 0, Polling: tt.polling}
+			watchDirectory := system.MakeWatchDirectory(config, ingestFile)
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- watchDirectory(ctx) }()
+
+			time.Sleep(20 * time.Millisecond)
+			dropFile(t, dir, "data.csv", "Id,Date,Amount\n")
+			dropFile(t, dir, ".partial.csv", "Id,Date,Amount\n")
+
+			select {
+			case got := <-ingested:
+				assert.Equal(t, "data.csv", got)
+			case <-time.After(2 * time.Second):
+				t.Fatal("dropped file wasn't ingested")
+			}
+			cancel()
+			assert.Nil(t, <-done)
+			assert.Empty(t, ingested)
+			assert.DirExists(t, filepath.Join(dir, system.ProcessedFolder))
+			assert.DirExists(t, filepath.Join(dir, system.FailedFolder))
+		})
+	}
+}
+
+func TestWatchDirectory_waitsUntilFileSettles(t *testing.T) {
+	dir := t.TempDir()
+	ingested := make(chan string, 10)
+	ingestFile := func(_ context.Context, filename string) error {
+		ingested <- filepath.Base(filename)
+		_ = os.Remove(filename)
+		return nil
+	}
+	config := system.WatcherConfig{Dir: dir, PollInterval: 20 * time.Millisecond, SettleTime: time.Hour, Polling: true}
+	watchDirectory := system.MakeWatchDirectory(config, ingestFile)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	dropFile(t, dir, "data.csv", "Id,Date,Amount\n")
+
+	err := watchDirectory(ctx)
+
+	assert.Nil(t, err)
+	assert.Empty(t, ingested)
+}
@@ -0,0 +1,9 @@
+// Package conf holds the configuration files of the service
+package conf
+
+import _ "embed"
+
+// Production is the production configuration, built into the binary and used when no configuration file is given
+//
+//go:embed production.yml
+var Production []byte
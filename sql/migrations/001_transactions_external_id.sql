@@ -0,0 +1,3 @@
+-- External id of the transaction in the bank statement, e.g. the FITID of OFX files
+ALTER TABLE `transactions`
+  ADD COLUMN `external_id` varchar(255) DEFAULT NULL AFTER `type`;
@@ -0,0 +1,11 @@
+-- Files imported from the drop folder, keyed by the SHA-256 of their content so a file is imported only once
+CREATE TABLE `imports` (
+  `checksum` char(64) NOT NULL,
+  `filename` varchar(255) NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `error` text DEFAULT NULL,
+  `transactions` int NOT NULL DEFAULT 0,
+  `started_at` datetime NOT NULL,
+  `finished_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`checksum`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
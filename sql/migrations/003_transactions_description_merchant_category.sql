@@ -0,0 +1,5 @@
+-- Text of the transaction in the statement, the other party and the category assigned at import time
+ALTER TABLE `transactions`
+  ADD COLUMN `description` varchar(255) DEFAULT NULL AFTER `external_id`,
+  ADD COLUMN `merchant` varchar(255) DEFAULT NULL AFTER `description`,
+  ADD COLUMN `category` varchar(64) DEFAULT NULL AFTER `merchant`;
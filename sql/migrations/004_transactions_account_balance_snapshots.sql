@@ -0,0 +1,12 @@
+-- Account of every transaction, so balances can be computed per account
+ALTER TABLE `transactions`
+  ADD COLUMN `account` varchar(64) NOT NULL DEFAULT 'default' AFTER `external_id`,
+  ADD KEY `account_date` (`account`, `date`);
+
+-- Balance of each account at the end of every date with transactions, recomputed after each import
+CREATE TABLE `balance_snapshots` (
+  `account` varchar(64) NOT NULL,
+  `date` date NOT NULL,
+  `balance` double NOT NULL,
+  PRIMARY KEY (`account`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
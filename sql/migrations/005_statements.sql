@@ -0,0 +1,13 @@
+-- Statements issued to each account, stored as sent so they never change once issued
+CREATE TABLE `statements` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `account` varchar(64) NOT NULL,
+  `period_start` date NOT NULL,
+  `period_end` date NOT NULL,
+  `opening_balance` double NOT NULL,
+  `closing_balance` double NOT NULL,
+  `summary` json NOT NULL,
+  `issued_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `account_period` (`account`, `period_start`, `period_end`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
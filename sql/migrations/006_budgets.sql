@@ -0,0 +1,22 @@
+-- Spending limits of each account per category and period
+CREATE TABLE `budgets` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `account` varchar(64) NOT NULL,
+  `category` varchar(64) NOT NULL,
+  `period` varchar(16) NOT NULL,
+  `amount` double NOT NULL,
+  `rollover` tinyint(1) NOT NULL DEFAULT 0,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `account_category_period` (`account`, `category`, `period`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Alerts already sent for each budget period, so every alert is sent once
+CREATE TABLE `budget_alerts` (
+  `budget_id` bigint NOT NULL,
+  `period_key` varchar(16) NOT NULL,
+  `alert` double NOT NULL,
+  `sent_at` datetime NOT NULL,
+  PRIMARY KEY (`budget_id`, `period_key`, `alert`),
+  CONSTRAINT `budget_alerts_budget` FOREIGN KEY (`budget_id`) REFERENCES `budgets` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
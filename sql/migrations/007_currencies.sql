@@ -0,0 +1,12 @@
+-- ISO 4217 currency of each transaction, NULL for the ones imported before currencies were recorded
+ALTER TABLE `transactions`
+  ADD COLUMN `currency` char(3) DEFAULT NULL AFTER `category`;
+
+-- Exchange rates effective from their date until the next rate of the same pair
+CREATE TABLE `exchange_rates` (
+  `date` date NOT NULL,
+  `from_currency` char(3) NOT NULL,
+  `to_currency` char(3) NOT NULL,
+  `rate` double NOT NULL,
+  PRIMARY KEY (`from_currency`, `to_currency`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,7 @@
+-- The database generates the id of the transactions. Imports are deduplicated by the id the statement gives each
+-- transaction in its account, e.g. the FITID of OFX files or the Id column of CSV files
+UPDATE `transactions` SET `external_id` = CAST(`id` AS CHAR) WHERE `external_id` IS NULL;
+ALTER TABLE `transactions`
+  DROP INDEX `id_UNIQUE`,
+  MODIFY COLUMN `id` bigint NOT NULL AUTO_INCREMENT,
+  ADD UNIQUE KEY `account_external_id` (`account`, `external_id`);
@@ -0,0 +1,48 @@
+// Package sql holds the database schema: the initial dump and the migrations applied after it
+package sql
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+const (
+	dumpFile          string = "stori_transactions.sql"
+	migrationsPattern string = "migrations/*.sql"
+)
+
+//go:embed stori_transactions.sql migrations/*.sql
+var files embed.FS
+
+// Files returns the names of the initial dump and the migrations, in the order they have to be applied
+func Files() ([]string, error) {
+	migrations, err := fs.Glob(files, migrationsPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{dumpFile}, migrations...), nil
+}
+
+// WriteSchema writes the initial dump followed by the migrations, in the order they have to be applied, so it can be
+// piped to the mysql client
+func WriteSchema(w io.Writer) error {
+	filenames, err := Files()
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range filenames {
+		content, err := files.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "-- %s\n%s\n", filename, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package sql_test
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rromero96/stori/sql"
+)
+
+func TestFiles_listsTheDumpBeforeTheMigrationsInOrder(t *testing.T) {
+	got, err := sql.Files()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "stori_transactions.sql", got[0])
+	assert.Equal(t, "migrations/001_transactions_external_id.sql", got[1])
+	assert.IsIncreasing(t, got[1:])
+}
+
+func TestWriteSchema_success(t *testing.T) {
+	var got strings.Builder
+
+	err := sql.WriteSchema(&got)
+
+	assert.Nil(t, err)
+	assert.Contains(t, got.String(), "CREATE TABLE `transactions`")
+	assert.Less(t, strings.Index(got.String(), "-- migrations/001_"), strings.Index(got.String(), "-- migrations/007_"))
+}
+
+var (
+	fileLine    = regexp.MustCompile("^-- ((?:migrations/)?[^ ]+\\.sql)$")
+	createTable = regexp.MustCompile("^CREATE TABLE (?:IF NOT EXISTS )?`([^`]+)`")
+	alterTable  = regexp.MustCompile("^ALTER TABLE `([^`]+)`")
+	columnLine  = regexp.MustCompile("^\\s+`([^`]+)` ")
+	addColumn   = regexp.MustCompile("ADD COLUMN `([^`]+)`")
+)
+
+func TestWriteSchema_definesEveryColumnOnce(t *testing.T) {
+	var schema strings.Builder
+	assert.Nil(t, sql.WriteSchema(&schema))
+
+	// defined maps every table column to the file that defines it
+	defined := map[string]string{}
+	define := func(file string, table string, column string) {
+		key := table + "." + column
+		if first, ok := defined[key]; ok {
+			t.Errorf("%s defines %s, already defined by %s", file, key, first)
+		}
+		defined[key] = file
+	}
+
+	var file, table string
+	var creating bool
+	scanner := bufio.NewScanner(strings.NewReader(schema.String()))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case fileLine.MatchString(line):
+			file = fileLine.FindStringSubmatch(line)[1]
+		case createTable.MatchString(line):
+			table, creating = createTable.FindStringSubmatch(line)[1], true
+		case alterTable.MatchString(line):
+			table, creating = alterTable.FindStringSubmatch(line)[1], false
+		case creating && strings.HasPrefix(line, ")"):
+			creating = false
+		case creating && columnLine.MatchString(line):
+			define(file, table, columnLine.FindStringSubmatch(line)[1])
+		case addColumn.MatchString(line):
+			define(file, table, addColumn.FindStringSubmatch(line)[1])
+		}
+	}
+
+	assert.Contains(t, defined, "transactions.external_id")
+}